@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+)
+
+// exporter holds the running configuration and prober, both of which are
+// replaced when a new configuration is loaded, and the metrics they feed
+type exporter struct {
+	metrics *metrics.Metrics
+	standby atomic.Bool
+
+	// load reads the configuration again for a reload, returning nil if
+	// it has not changed, and reloads queues it for the probe loop
+	load    func(context.Context) (*config.Config, error)
+	reloads chan *config.Config
+
+	mu     sync.RWMutex
+	cfg    *config.Config
+	prober *prober.Prober
+}
+
+// newExporter creates the exporter state for an initial config and prober,
+// reloading the configuration with load
+func newExporter(cfg *config.Config, p *prober.Prober, m *metrics.Metrics, load func(context.Context) (*config.Config, error)) *exporter {
+	return &exporter{metrics: m, load: load, reloads: make(chan *config.Config, 1), cfg: cfg, prober: p}
+}
+
+// Config returns the currently applied configuration
+func (e *exporter) Config() *config.Config {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.cfg
+}
+
+// Prober returns the currently running prober
+func (e *exporter) Prober() *prober.Prober {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.prober
+}
+
+// swap installs a new configuration and prober, returning the old prober
+func (e *exporter) swap(cfg *config.Config, p *prober.Prober) *prober.Prober {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.prober
+	e.cfg = cfg
+	e.prober = p
+	return old
+}
+
+// Reload loads the configuration again and queues it for the probe loop,
+// which applies it once the running cycle is done. If the configuration
+// cannot be loaded, the running one stays in place and the error is
+// returned.
+func (e *exporter) Reload(ctx context.Context) error {
+	cfg, err := e.load(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		logging.Infof("Configuration unchanged, not reloading")
+		return nil
+	}
+	queueReload(e.reloads, cfg)
+	return nil
+}
+
+// queueReload queues cfg for the probe loop, replacing any reload that has
+// not been picked up yet
+func queueReload(reloads chan *config.Config, cfg *config.Config) {
+	select {
+	case <-reloads:
+	default:
+	}
+	reloads <- cfg
+}
+
+// setLeader starts probing when elected leader and stands by otherwise.
+// A cycle in progress finishes before standing by.
+func (e *exporter) setLeader(leader bool) {
+	e.standby.Store(!leader)
+	e.metrics.SetLeader(leader)
+	if leader {
+		logging.Infof("Elected leader, probing")
+	} else {
+		logging.Infof("Standing by for the leader")
+	}
+}
+
+// togglePause pauses probing if it is running and resumes it if paused
+func (e *exporter) togglePause() {
+	p := e.Prober()
+	if p.Paused() {
+		p.Resume()
+		logging.Infof("Probing resumed")
+	} else {
+		p.Pause()
+		logging.Infof("Probing paused")
+	}
+}
+
+// probeLoop runs probe cycles until ctx is cancelled, replacing the prober
+// whenever a reloaded configuration arrives. The new prober takes over the
+// resolvers of unchanged targets. Listener settings and the site label are
+// not affected by a reload.
+func (e *exporter) probeLoop(ctx context.Context) {
+	defer func() { e.Prober().Close() }()
+
+	for {
+		p := e.Prober()
+		if !e.standby.Load() {
+			p.Run(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case cfg := <-e.reloads:
+			if err := applyConfig(cfg); err != nil {
+				logging.Errorf("Failed to apply reloaded configuration: %v", err)
+				continue
+			}
+			if site := e.Config().Site; cfg.Site != site {
+				logging.Warnf("Site changed to %s; metrics keep site %s until restart", cfg.Site, site)
+			}
+			np, err := newProber(cfg, e.metrics)
+			if err != nil {
+				logging.Errorf("Failed to apply reloaded configuration: %v", err)
+				continue
+			}
+			// Keep drain and pause state across reloads
+			for _, target := range p.Drained() {
+				_ = np.Drain(target)
+			}
+			if p.Paused() {
+				np.Pause()
+			}
+			reused := np.Reuse(p)
+			logChanges(diffConfigs(e.Config(), cfg))
+			e.swap(cfg, np).Close()
+			logging.Infof("Configuration reloaded, %d unchanged targets kept their resolvers", reused)
+		case <-time.After(p.Interval()):
+		}
+	}
+}
+
+// watchRemote periodically re-fetches a remote config and queues changed
+// documents for the probe loop
+func watchRemote(ctx context.Context, src *config.RemoteSource, interval time.Duration, reloads chan *config.Config) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cfg, err := src.Fetch(ctx)
+		if err != nil {
+			logging.Errorf("Failed to refresh remote configuration: %v", err)
+			continue
+		}
+		if cfg == nil {
+			continue
+		}
+
+		queueReload(reloads, cfg)
+	}
+}
+
+// domainsFileCheck is how often the domains file is checked for changes
+const domainsFileCheck = 10 * time.Second
+
+// watchDomainsFile checks the domains file of the current configuration
+// every interval and reloads the local config file when the domains file
+// was modified
+func watchDomainsFile(ctx context.Context, e *exporter, filename string, interval time.Duration, reloads chan *config.Config) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var path string
+	var modified time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := e.Config().DomainsFile
+		if current == "" {
+			path = ""
+			continue
+		}
+		info, err := os.Stat(current)
+		if err != nil {
+			logging.Errorf("Failed to check domains file: %v", err)
+			continue
+		}
+		if current != path {
+			// First check, or the file was changed by a reload
+			path, modified = current, info.ModTime()
+			continue
+		}
+		if info.ModTime().Equal(modified) {
+			continue
+		}
+		modified = info.ModTime()
+
+		cfg, err := config.Load(filename)
+		if err != nil {
+			logging.Errorf("Failed to reload configuration after domains file change: %v", err)
+			continue
+		}
+		logging.Infof("Domains file %s changed, reloading configuration", path)
+		queueReload(reloads, cfg)
+	}
+}
+
+// changes lists the targets and domains a reload adds and removes
+type changes struct {
+	addedTargets, removedTargets []string
+	addedDomains, removedDomains []string
+}
+
+// diffConfigs compares the enabled targets, by their key, and the enabled
+// domains, by their name, of two configurations
+func diffConfigs(old, cfg *config.Config) changes {
+	var c changes
+	c.addedTargets, c.removedTargets = diffKeys(targetKeys(old), targetKeys(cfg))
+	c.addedDomains, c.removedDomains = diffKeys(domainNames(old), domainNames(cfg))
+	return c
+}
+
+// targetKeys returns the keys of the enabled targets of cfg
+func targetKeys(cfg *config.Config) []string {
+	var keys []string
+	for _, server := range cfg.DNSServers {
+		if server.IsEnabled() {
+			keys = append(keys, server.Key())
+		}
+	}
+	return keys
+}
+
+// domainNames returns the names of the enabled domains of cfg
+func domainNames(cfg *config.Config) []string {
+	var names []string
+	for _, domain := range cfg.Domains {
+		if domain.IsEnabled() {
+			names = append(names, domain.Name)
+		}
+	}
+	return names
+}
+
+// diffKeys returns the keys only in after and those only in before, in
+// order
+func diffKeys(before, after []string) (added, removed []string) {
+	for _, key := range after {
+		if !slices.Contains(before, key) {
+			added = append(added, key)
+		}
+	}
+	for _, key := range before {
+		if !slices.Contains(after, key) {
+			removed = append(removed, key)
+		}
+	}
+	return added, removed
+}
+
+// logChanges logs every target and domain a reload adds or removes
+func logChanges(c changes) {
+	for _, key := range c.addedTargets {
+		logging.Infof("Reload adds target %s", key)
+	}
+	for _, key := range c.removedTargets {
+		logging.Infof("Reload removes target %s", key)
+	}
+	for _, name := range c.addedDomains {
+		logging.Infof("Reload adds domain %s", name)
+	}
+	for _, name := range c.removedDomains {
+		logging.Infof("Reload removes domain %s", name)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+func TestReload(t *testing.T) {
+	var next *config.Config
+	var loadErr error
+	e := newExporter(&config.Config{}, nil, nil, func(ctx context.Context) (*config.Config, error) {
+		return next, loadErr
+	})
+
+	first, second := &config.Config{Site: "first"}, &config.Config{Site: "second"}
+	for _, cfg := range []*config.Config{first, second, nil} {
+		next = cfg
+		if err := e.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+	}
+	select {
+	case cfg := <-e.reloads:
+		if cfg != second {
+			t.Errorf("Expected the latest configuration to be queued, got site %s", cfg.Site)
+		}
+	default:
+		t.Fatal("Expected a queued configuration")
+	}
+
+	loadErr = errors.New("invalid configuration")
+	if err := e.Reload(context.Background()); err == nil {
+		t.Error("Expected the load error")
+	}
+	if len(e.reloads) != 0 {
+		t.Error("Expected nothing queued after a failed load")
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	disabled := false
+	old := &config.Config{
+		Domains: []config.Domain{{Name: "example.com"}, {Name: "example.org"}},
+		DNSServers: []config.DNSServer{
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	}
+	cfg := &config.Config{
+		Domains: []config.Domain{{Name: "example.com"}, {Name: "example.net"}},
+		DNSServers: []config.DNSServer{
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP, Enabled: &disabled},
+			{Address: "1.1.1.1", Port: "853", Protocol: config.ProtocolDoT},
+		},
+	}
+
+	c := diffConfigs(old, cfg)
+	if !slices.Equal(c.addedTargets, []string{"1.1.1.1:853:dot"}) {
+		t.Errorf("Unexpected added targets: %v", c.addedTargets)
+	}
+	if !slices.Equal(c.removedTargets, []string{"8.8.8.8:53:do53-udp"}) {
+		t.Errorf("Unexpected removed targets: %v", c.removedTargets)
+	}
+	if !slices.Equal(c.addedDomains, []string{"example.net"}) || !slices.Equal(c.removedDomains, []string{"example.org"}) {
+		t.Errorf("Unexpected domain changes: +%v -%v", c.addedDomains, c.removedDomains)
+	}
+}
@@ -8,16 +8,26 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	_ "github.com/prometheus/client_golang/prometheus/promhttp/zstd" // zstd for /metrics
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
-	"dnspulse_exporter/internal/config"
-	"dnspulse_exporter/internal/prober"
+	"github.com/farrokhi/dnspulse_exporter/internal/api"
+	"github.com/farrokhi/dnspulse_exporter/internal/federation"
+	"github.com/farrokhi/dnspulse_exporter/internal/leader"
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/internal/openresolver"
+	"github.com/farrokhi/dnspulse_exporter/internal/server"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
 )
 
 var (
@@ -26,7 +36,15 @@ var (
 	buildTime = "unknown"
 )
 
-var configFile string
+var (
+	configFile       string
+	configAuthHeader string
+	configRefresh    time.Duration
+	overrides        config.Overrides
+	dumpConfig       bool
+	dryRun           bool
+	seed             uint64
+)
 
 func main() {
 	rootCmd := &cobra.Command{
@@ -36,7 +54,21 @@ func main() {
 	}
 
 	rootCmd.Version = fmt.Sprintf("%s (commit: %s, built: %s)", version, gitCommit, buildTime)
-	rootCmd.Flags().StringVarP(&configFile, "config", "f", "/etc/dnspulse.yml", "path to config file")
+	rootCmd.Flags().StringVarP(&configFile, "config", "f", "/etc/dnspulse.yml", "path or http(s) URL of config file")
+	rootCmd.Flags().StringVar(&configAuthHeader, "config-auth-header", "", "header sent when fetching a remote config (e.g. \"Authorization: Bearer xyz\")")
+	rootCmd.Flags().DurationVar(&configRefresh, "config-refresh", 5*time.Minute, "refresh interval for a remote config")
+	rootCmd.Flags().StringVar(&overrides.ListenAddress, "listen-address", "", "address to bind the metrics server (overrides listen_addr)")
+	rootCmd.Flags().StringVar(&overrides.ListenPort, "listen-port", "", "port of the metrics server (overrides listen_port)")
+	rootCmd.Flags().DurationVar(&overrides.Interval, "interval", 0, "time between probe cycles (overrides interval)")
+	rootCmd.Flags().DurationVar(&overrides.Timeout, "timeout", 0, "query timeout for every server (overrides timeout, including per-server values)")
+	rootCmd.Flags().StringVar(&overrides.LogLevel, "log-level", "", "log level: debug, info, warn or error (overrides log_level)")
+	rootCmd.Flags().BoolVar(&dumpConfig, "dump-config", false, "print the loaded configuration as YAML (secrets redacted) and exit")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the probes the configuration makes without sending any queries and exit")
+	rootCmd.Flags().Uint64Var(&seed, "seed", 0, "seed for query names, injected faults and name server order, to reproduce a run (0 for unpredictable)")
+
+	addServiceCommand(rootCmd)
+	addScanCommand(rootCmd)
+	addVersionCommand(rootCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -44,65 +76,211 @@ func main() {
 }
 
 func run(cmd *cobra.Command, args []string) {
-	cfg, err := config.Load(configFile)
+	shutdown, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serve(shutdown)
+}
+
+// serve runs the exporter until shutdown is done
+func serve(shutdown context.Context) {
+	var remote *config.RemoteSource
+	var cfg *config.Config
+	var err error
+
+	if config.IsRemote(configFile) {
+		remote, err = config.NewRemoteSource(configFile, configAuthHeader)
+		if err == nil {
+			cfg, err = remote.Fetch(context.Background())
+		}
+	} else {
+		cfg, err = config.Load(configFile)
+	}
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := applyConfig(cfg); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
-	p, err := prober.New(cfg)
+	if dumpConfig {
+		out, err := yaml.Marshal(cfg.Redacted())
+		if err != nil {
+			log.Fatalf("Failed to encode configuration: %v", err)
+		}
+		fmt.Print(string(out))
+		return
+	}
+	if dryRun {
+		if err := printPlan(os.Stdout, cfg); err != nil {
+			log.Fatalf("Failed to print probe plan: %v", err)
+		}
+		return
+	}
+
+	if cfg.Syslog.Enabled && !logging.HasSink() {
+		facility, _ := logging.ParseFacility(cfg.Syslog.Facility)
+		s, err := logging.NewSyslog(cfg.Syslog.Address, facility, cfg.Syslog.Tag)
+		if err != nil {
+			log.Fatalf("Failed to set up syslog: %v", err)
+		}
+		logging.SetSink(s)
+		defer s.Close()
+	}
+	if cfg.LogFile != "" && !logging.HasSink() {
+		file := openLogFile(cfg)
+		defer file.Close()
+	}
+
+	registry := prometheus.NewRegistry()
+	siteRegistry := prometheus.WrapRegistererWith(prometheus.Labels{"site": cfg.Site}, registry)
+	siteRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m := metrics.New(siteRegistry)
+
+	p, err := newProber(cfg, m)
 	if err != nil {
 		log.Fatalf("Failed to create prober: %v", err)
 	}
-	defer p.Close()
+	exp := newExporter(cfg, p, m, func(ctx context.Context) (*config.Config, error) {
+		if remote != nil {
+			return remote.Fetch(ctx)
+		}
+		return config.Load(configFile)
+	})
+	m.SetLeader(!cfg.LeaderElection.Enabled())
+	exp.standby.Store(cfg.LeaderElection.Enabled())
+
+	http.Handle("/metrics", metrics.ShardHandler(registry, func() int { return exp.Config().Metrics.Shards }))
+	api.New(exp).Register(http.DefaultServeMux)
+
+	handler := server.AllowClients(server.Compress(http.DefaultServeMux), func() []netip.Prefix { return exp.Config().ClientPrefixes() })
+	handler = server.AccessLog(handler, func() bool { return exp.Config().AccessLog })
+	srv := server.New(cfg.ListenAddresses(), handler)
+	if err := srv.Start(); err != nil {
+		log.Fatalf("HTTP server error: %v", err)
+	}
+	// Nothing may probe before the privileges are dropped, so that no
+	// query is sent as root or races the chroot
+	if err := dropPrivileges(cfg); err != nil {
+		log.Fatalf("Failed to drop privileges: %v", err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	pauseChan := make(chan os.Signal, 1)
+	notifyPause(pauseChan)
+	go func() {
+		for range pauseChan {
+			exp.togglePause()
+		}
+	}()
 
+	reloadChan := make(chan os.Signal, 1)
+	notifyReload(reloadChan)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				p.Run(ctx)
-				time.Sleep(30 * time.Second)
+		for range reloadChan {
+			logging.Infof("Reloading configuration")
+			if err := exp.Reload(ctx); err != nil {
+				logging.Errorf("Failed to reload configuration: %v", err)
 			}
 		}
 	}()
 
-	listenAddr := cfg.ListenAddress
-	if listenAddr == "*" {
-		listenAddr = ""
+	loopDone := make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		exp.probeLoop(ctx)
+	}()
+
+	if remote != nil {
+		go watchRemote(ctx, remote, configRefresh, exp.reloads)
+	} else {
+		go watchDomainsFile(ctx, exp, configFile, domainsFileCheck, exp.reloads)
 	}
-	serverAddr := fmt.Sprintf("%s:%s", listenAddr, cfg.ListenPort)
+	go federation.New(exp, m).Run(ctx)
+	go openresolver.New(exp, m).Run(ctx)
+	electionDone := make(chan struct{})
+	if le := cfg.LeaderElection; le.Enabled() {
+		go func() {
+			defer close(electionDone)
+			leader.New(le.LockFile, le.Identity, time.Duration(le.Lease)).Run(ctx, exp.setLeader)
+		}()
+	} else {
+		close(electionDone)
+	}
+
+	<-shutdown.Done()
+	logging.Infof("Shutting down...")
 
-	http.Handle("/metrics", promhttp.Handler())
+	// Probes observe the cancellation, but one stuck in a resolver must not
+	// hold the process past the grace period
+	grace := time.Duration(exp.Config().ShutdownTimeout)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), grace)
+	defer shutdownCancel()
+	cancel()
+	select {
+	case <-loopDone:
+	case <-shutdownCtx.Done():
+		logging.Warnf("Probes still running after %s, abandoning them", grace)
+	}
+	select {
+	case <-electionDone:
+	case <-shutdownCtx.Done():
+	}
 
-	server := &http.Server{
-		Addr:         serverAddr,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logging.Errorf("HTTP server shutdown error: %v", err)
 	}
+}
+
+// openLogFile sends the standard logger to the configured log file, and
+// reopens the file on the reopen signal
+func openLogFile(cfg *config.Config) *logging.File {
+	rotation := cfg.LogRotation
+	file := logging.NewFile(cfg.LogFile, logging.Rotation{
+		MaxSize:    rotation.MaxSize,
+		MaxAge:     time.Duration(rotation.MaxAge),
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+	})
+	log.SetOutput(file)
 
+	reopen := make(chan os.Signal, 1)
+	notifyReopen(reopen)
 	go func() {
-		log.Printf("Starting Prometheus metrics server on %s", serverAddr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTP server error: %v", err)
+		for range reopen {
+			if err := file.Reopen(); err != nil {
+				logging.Errorf("Failed to reopen log file: %v", err)
+			}
 		}
 	}()
+	return file
+}
 
-	<-sigChan
-	log.Println("Shutting down...")
-
-	cancel()
-
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server shutdown error: %v", err)
+// applyConfig applies command-line overrides to a freshly loaded config,
+// checks the settings they may have replaced and sets the log level
+// and sampling it selects
+func applyConfig(cfg *config.Config) error {
+	cfg.ApplyOverrides(overrides)
+	if err := cfg.ValidateOverrides(); err != nil {
+		return err
+	}
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	limits := make(map[logging.Level]logging.Limit, len(cfg.LogSampling))
+	for name, limit := range cfg.LogSampling {
+		l, err := logging.ParseLevel(name)
+		if err != nil {
+			return err
+		}
+		limits[l] = logging.Limit{Every: limit.Every, PerMinute: limit.PerMinute}
 	}
+	logging.SetLevel(level)
+	logging.SetLimits(limits)
+	return nil
 }
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// printPlan writes a line for every enabled domain and server the prober
+// queries, in the order of a cycle, with what and how often it asks
+func printPlan(w io.Writer, cfg *config.Config) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tDOMAIN\tQTYPE\tPROBES\tINTERVAL\tTIMEOUT\tRETRIES")
+	targets := make(map[string]bool)
+	domains, queries := 0, 0
+	for _, domain := range cfg.Domains {
+		if !domain.IsEnabled() {
+			continue
+		}
+		domains++
+		for _, server := range cfg.DNSServers {
+			if !server.IsEnabled() {
+				continue
+			}
+			interval := time.Duration(cfg.Interval).String()
+			if server.Schedule != "" {
+				interval = server.Schedule
+			}
+			timeout := time.Duration(server.Timeout) * time.Millisecond
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%d\n", server.Key(), domain.Name,
+				dns.TypeToString[domain.QueryType()], domain.ProbeCount(), interval, timeout, server.RetryCount())
+			targets[server.Key()] = true
+			queries += domain.ProbeCount()
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d probes per cycle of %d domains on %d targets\n", queries, domains, len(targets))
+	return err
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+func TestPrintPlan(t *testing.T) {
+	content := `
+interval: 1m
+domains:
+  - name: example.com
+    probes: 2
+  - name: example.org
+    qtype: AAAA
+  - name: parked.example
+    enabled: false
+dns_servers:
+  - address: 1.1.1.1
+    protocols: [do53-udp, dot]
+    timeout: 1500
+  - address: 9.9.9.9
+    schedule: "@hourly"
+    retries: 1
+  - address: 8.8.8.8
+    enabled: false
+`
+	cfg, err := config.Parse([]byte(content), ".")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out strings.Builder
+	if err := printPlan(&out, cfg); err != nil {
+		t.Fatalf("printPlan failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := [][]string{
+		{"TARGET", "DOMAIN", "QTYPE", "PROBES", "INTERVAL", "TIMEOUT", "RETRIES"},
+		{"1.1.1.1:53:do53-udp", "example.com", "A", "2", "1m0s", "1.5s", "0"},
+		{"1.1.1.1:853:dot", "example.com", "A", "2", "1m0s", "1.5s", "0"},
+		{"9.9.9.9:53:do53-udp", "example.com", "A", "2", "@hourly", "2s", "1"},
+		{"1.1.1.1:53:do53-udp", "example.org", "AAAA", "1", "1m0s", "1.5s", "0"},
+		{"1.1.1.1:853:dot", "example.org", "AAAA", "1", "1m0s", "1.5s", "0"},
+		{"9.9.9.9:53:do53-udp", "example.org", "AAAA", "1", "@hourly", "2s", "1"},
+	}
+	if len(lines) != len(want)+2 {
+		t.Fatalf("Expected %d lines, got:\n%s", len(want)+2, out.String())
+	}
+	for i, fields := range want {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(fields, " ") {
+			t.Errorf("Line %d: expected %v, got %v", i, fields, got)
+		}
+	}
+	if summary := lines[len(lines)-1]; summary != "9 probes per cycle of 2 domains on 3 targets" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+}
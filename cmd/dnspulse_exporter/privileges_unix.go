@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !windows
+
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// dropPrivileges confines the process to the configured chroot and switches
+// to the configured user and group. It runs once initialization that may
+// need root, such as binding low ports, is done. Without a group, the
+// user's primary group is used.
+func dropPrivileges(cfg *config.Config) error {
+	if cfg.User == "" && cfg.Group == "" && cfg.Chroot == "" {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return errors.New("dropping privileges requires starting as root")
+	}
+
+	// Look up the IDs before the chroot hides the user database
+	uid, gid := -1, -1
+	if cfg.User != "" {
+		u, err := user.Lookup(cfg.User)
+		if err != nil {
+			return err
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+	if cfg.Group != "" {
+		g, err := user.LookupGroup(cfg.Group)
+		if err != nil {
+			return err
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	if cfg.Chroot != "" {
+		// Load the CA roots while they are reachable; TLS resolvers are
+		// created lazily, after the chroot hides /etc/ssl
+		if _, err := x509.SystemCertPool(); err != nil {
+			return fmt.Errorf("failed to load system CA roots: %w", err)
+		}
+		if err := syscall.Chroot(cfg.Chroot); err != nil {
+			return fmt.Errorf("failed to chroot to %s: %w", cfg.Chroot, err)
+		}
+		if err := os.Chdir("/"); err != nil {
+			return err
+		}
+	}
+	if gid >= 0 {
+		if err := syscall.Setgroups([]int{gid}); err != nil {
+			return fmt.Errorf("failed to set groups: %w", err)
+		}
+		if err := syscall.Setgid(gid); err != nil {
+			return fmt.Errorf("failed to set group: %w", err)
+		}
+	}
+	if uid >= 0 {
+		if err := syscall.Setuid(uid); err != nil {
+			return fmt.Errorf("failed to set user: %w", err)
+		}
+	}
+
+	logging.Infof("Dropped privileges to uid %d, gid %d", os.Getuid(), os.Getgid())
+	return nil
+}
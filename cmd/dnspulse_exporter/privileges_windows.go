@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"errors"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// dropPrivileges rejects user, group and chroot settings, which have no
+// equivalent on Windows; configure the service account instead
+func dropPrivileges(cfg *config.Config) error {
+	if cfg.User != "" || cfg.Group != "" || cfg.Chroot != "" {
+		return errors.New("user, group and chroot are not supported on Windows")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/geoip"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// newProber creates a prober for cfg that records its results in m
+func newProber(cfg *config.Config, m *metrics.Metrics) (*prober.Prober, error) {
+	opts := []prober.Option{
+		prober.WithMetrics(m),
+		prober.WithResultCallback(func(res prober.Result) {
+			recordResult(m, res, cfg.FailureLatency, cfg.FirstProbe)
+		}),
+		prober.WithFilteringCallback(func(res prober.FilteringResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetFiltering(server, res.Protocol, res.Server.Labels, res.Category, res.Active())
+		}),
+		prober.WithDivergenceCallback(func(res prober.DivergenceResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.RecordDivergence(res.Domain.Name, server, res.Protocol, res.Server.Labels, res.Diverged)
+		}),
+		prober.WithComparisonCallback(func(res prober.TransportComparison) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			delta, _ := res.Delta()
+			m.SetTransportComparison(res.Domain.Name, server, res.Protocol, res.Server.Labels,
+				res.UDP.Err == nil, res.TCP.Err == nil, delta.Seconds())
+		}),
+		prober.WithDowngradeCallback(func(res prober.DowngradeResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetDowngrade(server, res.Protocol, res.Server.Labels, res.Downgraded(), res.Plaintext.Err == nil)
+		}),
+		prober.WithFingerprintCallback(func(res prober.FingerprintResult) {
+			if res.Err != nil {
+				return
+			}
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			fp := res.Fingerprint
+			m.SetFingerprint(server, res.Protocol, res.Server.Labels, fp.Software, fp.Version, fp.NSID)
+		}),
+		prober.WithCatchmentCallback(func(res prober.CatchmentResult) {
+			if res.Err != nil {
+				return
+			}
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetAnycastInstance(server, res.Protocol, res.Server.Labels, res.Instance, res.Changed)
+		}),
+		prober.WithQUICCallback(func(res prober.QUICResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetQUICBlocked(server, res.Protocol, res.Server.Labels, res.Blocked)
+		}),
+		prober.WithLargeResponseCallback(func(res prober.LargeResponseResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetLargeResponse(server, res.Protocol, res.Server.Labels, res.Success(), res.Size(), res.Truncated())
+		}),
+		prober.WithStatsCallback(func(res prober.StatsResult) {
+			server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+			m.SetServerStats(server, res.Protocol, res.Server.Labels, res.Server.Stats.Type, res.Stats, res.Err == nil)
+		}),
+	}
+	if cfg.GeoIP.Enabled() {
+		db, err := geoip.Open(cfg.GeoIP.CountryDatabase, cfg.GeoIP.ASNDatabase)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, prober.WithResultCallback(func(res prober.Result) {
+			recordGeo(m, db, res)
+		}))
+	}
+	if seed != 0 {
+		opts = append(opts, prober.WithSeed(seed))
+	}
+
+	m.Configure(cfg.LabelNames(), metrics.Options{
+		Disabled:  cfg.Metrics.Disable,
+		Summaries: cfg.Metrics.DurationType == config.DurationTypeSummary,
+		MaxSeries: cfg.Metrics.MaxSeries,
+	})
+	return prober.New(cfg, opts...)
+}
+
+// recordResult writes a probe result to the query and attempt metrics,
+// handling failure durations according to the failure latency policy and
+// the first of several probes according to the first probe policy
+func recordResult(m *metrics.Metrics, res prober.Result, failureLatency, firstProbe string) {
+	domain := res.Domain.Name
+	server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+	labels := res.Server.Labels
+
+	for _, attempt := range res.Attempts {
+		m.RecordAttempt(domain, server, res.Protocol, labels, attempt.Duration.Seconds(), attempt.Err == nil)
+		for _, step := range attempt.Steps {
+			m.ObserveStep(domain, server, res.Protocol, labels, step.Zone, step.Duration.Seconds())
+		}
+		if attempt.FastOpen != nil {
+			m.RecordFastOpen(server, res.Protocol, labels, *attempt.FastOpen)
+		}
+		var doqErr *resolver.DoQError
+		if errors.As(attempt.Err, &doqErr) {
+			kind := "connection_close"
+			if doqErr.StreamReset {
+				kind = "stream_reset"
+			}
+			m.RecordDoQError(server, res.Protocol, labels, kind, resolver.DoQErrorName(doqErr.Code))
+		}
+		if attempt.Conn != nil {
+			m.RecordConnection(server, res.Protocol, labels, connectionKind(attempt.Conn), attempt.Duration.Seconds(), attempt.Err == nil)
+		}
+		if attempt.ALPN != "" {
+			m.SetDoQALPN(server, res.Protocol, labels, attempt.ALPN)
+		}
+	}
+	m.RecordQuery(domain, server, res.Protocol, labels, res.Success())
+	m.RecordResultTime(domain, server, res.Protocol, labels)
+	if last := res.Last(); len(res.Server.CaptureHeaders) > 0 && (last.Headers != nil || last.Err == nil) {
+		m.SetResponseHeaders(server, res.Protocol, labels, last.Headers)
+	}
+	if res.Server.AltSvc != "" && res.Success() {
+		_, advertised := resolver.HTTP3Port(res.Last().AltSvc)
+		m.SetHTTP3Advertised(server, res.Protocol, labels, advertised)
+	}
+	if res.DNSSECChecked() {
+		m.RecordDNSSEC(domain, server, res.Protocol, labels, res.DNSSECMismatch())
+	}
+	if res.BlockChecked() {
+		m.RecordBlock(domain, server, res.Protocol, labels, res.BlockBypassed())
+	}
+	if res.SLAChecked() {
+		warning, critical := res.SLABreaches()
+		m.RecordSLA(domain, server, res.Protocol, labels, warning, critical)
+	}
+
+	switch {
+	case res.Success():
+		if res.First() && firstProbe == config.FirstProbeSeparate {
+			m.ObserveFirstDuration(domain, server, res.Protocol, labels, res.Duration.Seconds())
+		} else {
+			m.ObserveDuration(domain, server, res.Protocol, labels, res.Duration.Seconds())
+		}
+		m.SetLastDuration(domain, server, res.Protocol, labels, res.Duration.Seconds())
+		if res.Upstream != "" {
+			m.ObserveUpstreamDuration(domain, server, res.Protocol, labels, res.Upstream, res.Duration.Seconds())
+		}
+		if res.Timeout > 0 {
+			m.RecordTimeoutRatio(domain, server, res.Protocol, labels, res.Last().Duration.Seconds()/res.Timeout.Seconds())
+		}
+	case failureLatency == config.FailureLatencyTimeout:
+		// Every attempt of a failed probe may have used its full timeout
+		budget := res.Timeout * time.Duration(len(res.Attempts))
+		m.ObserveDuration(domain, server, res.Protocol, labels, budget.Seconds())
+	case failureLatency == config.FailureLatencyOmit:
+		// Failures are only counted
+	default:
+		m.ObserveFailedDuration(domain, server, res.Protocol, labels, res.Duration.Seconds())
+	}
+}
+
+// connectionKind names how the connection of a query was set up
+func connectionKind(conn *resolver.ConnState) string {
+	switch {
+	case conn.Reused:
+		return "reused"
+	case conn.Resumed:
+		return "resumed"
+	}
+	return "new"
+}
+
+// locator looks up the location of an address
+type locator interface {
+	Lookup(ip net.IP) geoip.Location
+}
+
+// recordGeo exports the distinct locations of the addresses in a successful
+// answer. Failed probes keep the previous locations.
+func recordGeo(m *metrics.Metrics, db locator, res prober.Result) {
+	if !res.Success() || res.Last().Response == nil {
+		return
+	}
+
+	seen := make(map[metrics.AnswerLocation]bool)
+	var locations []metrics.AnswerLocation
+	for _, rr := range res.Last().Response.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		default:
+			continue
+		}
+		loc := metrics.AnswerLocation(db.Lookup(ip))
+		if !seen[loc] {
+			seen[loc] = true
+			locations = append(locations, loc)
+		}
+	}
+
+	server := fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port)
+	m.SetAnswerGeo(res.Domain.Name, server, res.Protocol, res.Server.Labels, locations)
+}
@@ -0,0 +1,393 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/geoip"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// histogram returns the current state of a histogram series
+func histogram(t *testing.T, vec prometheus.ObserverVec, values ...string) *dto.Histogram {
+	t.Helper()
+	var m dto.Metric
+	if err := vec.WithLabelValues(values...).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return m.Histogram
+}
+
+// newResult builds a probe result for domain from the given attempts
+func newResult(domain string, timeout time.Duration, attempts ...resolver.QueryResult) prober.Result {
+	res := prober.Result{
+		Domain:   config.Domain{Name: domain},
+		Server:   config.DNSServer{Address: "192.0.2.1", Port: "53"},
+		Protocol: "do53-udp",
+		Attempts: attempts,
+		Timeout:  timeout,
+	}
+	for _, a := range attempts {
+		res.Duration += a.Duration
+		res.Err = a.Err
+	}
+	return res
+}
+
+func TestRecordResultTimeoutRatio(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	recordResult(m, newResult("ratio.example", 100*time.Millisecond,
+		resolver.QueryResult{Duration: 80 * time.Millisecond}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	h := histogram(t, m.QueryTimeoutRatio, "ratio.example", "192.0.2.1:53", "do53-udp")
+	if h.GetSampleCount() != 1 {
+		t.Fatalf("Expected 1 observation, got %d", h.GetSampleCount())
+	}
+	if sum := h.GetSampleSum(); sum < 0.79 || sum > 0.81 {
+		t.Errorf("Expected ratio 0.8, got %v", sum)
+	}
+}
+
+func TestRecordResultLastDuration(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	values := []string{"last.example", "192.0.2.1:53", "do53-udp"}
+
+	recordResult(m, newResult("last.example", time.Second,
+		resolver.QueryResult{Duration: 40 * time.Millisecond}), config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("last.example", time.Second,
+		resolver.QueryResult{Duration: 900 * time.Millisecond, Err: context.DeadlineExceeded}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	if got := testutil.ToFloat64(m.LastQueryDuration.WithLabelValues(values...)); got != 0.04 {
+		t.Errorf("Expected last duration 0.04 after a failure, got %v", got)
+	}
+}
+
+func TestRecordResultFastOpen(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	accepted, rejected := true, false
+
+	recordResult(m, newResult("tfo.example", time.Second,
+		resolver.QueryResult{Duration: 40 * time.Millisecond, FastOpen: &rejected},
+		resolver.QueryResult{Duration: 20 * time.Millisecond, FastOpen: &accepted}), config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("tfo.example", time.Second,
+		resolver.QueryResult{Duration: 20 * time.Millisecond}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	values := []string{"192.0.2.1:53", "do53-udp"}
+	if got := testutil.ToFloat64(m.FastOpenAttempts.WithLabelValues(values...)); got != 2 {
+		t.Errorf("Expected 2 fast open attempts, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.FastOpenAccepted.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 accepted fast open, got %v", got)
+	}
+}
+
+func TestRecordResultFailureLatency(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	tests := []struct {
+		policy         string
+		durationSum    float64
+		failedDuration uint64
+	}{
+		{policy: config.FailureLatencySeparate, durationSum: 0, failedDuration: 1},
+		{policy: config.FailureLatencyTimeout, durationSum: 0.5, failedDuration: 0},
+		{policy: config.FailureLatencyOmit, durationSum: 0, failedDuration: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			domain := "failure-" + tt.policy + ".example"
+			recordResult(m, newResult(domain, 500*time.Millisecond,
+				resolver.QueryResult{Duration: 200 * time.Millisecond, Err: context.DeadlineExceeded}), tt.policy, config.FirstProbeInclude)
+
+			values := []string{domain, "192.0.2.1:53", "do53-udp"}
+			if sum := histogram(t, m.QueryDuration, values...).GetSampleSum(); sum != tt.durationSum {
+				t.Errorf("Expected query duration sum %v, got %v", tt.durationSum, sum)
+			}
+			if count := histogram(t, m.FailedQueryDuration, values...).GetSampleCount(); count != tt.failedDuration {
+				t.Errorf("Expected %d failed duration observations, got %d", tt.failedDuration, count)
+			}
+			if got := testutil.ToFloat64(m.QueryFailures.WithLabelValues(values...)); got != 1 {
+				t.Errorf("Expected 1 failed query, got %v", got)
+			}
+		})
+	}
+}
+
+func TestRecordResultTimeoutRetries(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	failed := resolver.QueryResult{Duration: 200 * time.Millisecond, Err: context.DeadlineExceeded}
+	recordResult(m, newResult("retries.example", 500*time.Millisecond, failed, failed, failed),
+		config.FailureLatencyTimeout, config.FirstProbeInclude)
+
+	h := histogram(t, m.QueryDuration, "retries.example", "192.0.2.1:53", "do53-udp")
+	if sum := h.GetSampleSum(); sum != 1.5 {
+		t.Errorf("Expected the timeout of all 3 attempts, got %v", sum)
+	}
+}
+
+func TestRecordResultFirstProbe(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	values := []string{"first.example", "192.0.2.1:53", "do53-udp"}
+	probes := 3
+	for i := range 3 {
+		res := newResult("first.example", time.Second, resolver.QueryResult{Duration: 100 * time.Millisecond})
+		res.Domain.Probes = &probes
+		res.Index = i
+		recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeSeparate)
+	}
+	if count := histogram(t, m.FirstQueryDuration, values...).GetSampleCount(); count != 1 {
+		t.Errorf("Expected 1 first query observation, got %d", count)
+	}
+	if count := histogram(t, m.QueryDuration, values...).GetSampleCount(); count != 2 {
+		t.Errorf("Expected 2 query observations, got %d", count)
+	}
+
+	// A single probe per cycle is never set apart
+	res := newResult("single.example", time.Second, resolver.QueryResult{Duration: 100 * time.Millisecond})
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeSeparate)
+	if count := histogram(t, m.QueryDuration, "single.example", "192.0.2.1:53", "do53-udp").GetSampleCount(); count != 1 {
+		t.Errorf("Expected the only probe in the query duration, got %d observations", count)
+	}
+}
+
+func TestRecordResultAttempts(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	recordResult(m, newResult("retry.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Err: context.DeadlineExceeded},
+		resolver.QueryResult{Duration: 20 * time.Millisecond}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	values := []string{"retry.example", "192.0.2.1:53", "do53-udp"}
+	if got := testutil.ToFloat64(m.AttemptFailures.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 failed attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.AttemptSuccess.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 successful attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.QuerySuccess.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 successful probe, got %v", got)
+	}
+	if sum := histogram(t, m.QueryDuration, values...).GetSampleSum(); sum < 0.029 || sum > 0.031 {
+		t.Errorf("Expected probe duration to sum both attempts (0.03), got %v", sum)
+	}
+}
+
+func TestRecordResultDoQErrors(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	reset := fmt.Errorf("failed to read response: %w", &resolver.DoQError{Code: resolver.DoQExcessiveLoad, StreamReset: true})
+	closed := &resolver.DoQError{Code: 0x42}
+	recordResult(m, newResult("doq.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Err: reset},
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Err: closed}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	if got := testutil.ToFloat64(m.DoQErrors.WithLabelValues("192.0.2.1:53", "do53-udp", "stream_reset", "DOQ_EXCESSIVE_LOAD")); got != 1 {
+		t.Errorf("Expected 1 stream reset, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.DoQErrors.WithLabelValues("192.0.2.1:53", "do53-udp", "connection_close", "0x42")); got != 1 {
+		t.Errorf("Expected 1 connection close, got %v", got)
+	}
+}
+
+func TestRecordResultDoQALPN(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	recordResult(m, newResult("alpn.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, ALPN: "doq-i02"}), config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("alpn.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, ALPN: "doq"}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	if n := testutil.CollectAndCount(m.DoQALPN); n != 1 {
+		t.Fatalf("Expected 1 ALPN series, got %d", n)
+	}
+	if got := testutil.ToFloat64(m.DoQALPN.WithLabelValues("192.0.2.1:53", "do53-udp", "doq")); got != 1 {
+		t.Errorf("Expected the latest ALPN to be doq, got %v", got)
+	}
+}
+
+func TestRecordResultResponseHeaders(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	res := newResult("headers.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Headers: map[string]string{"Server": "cloudflare", "Cf-Ray": "1-FRA"}})
+	res.Server.CaptureHeaders = config.StringList{"Server", "CF-Ray"}
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+	res.Attempts[0].Headers = map[string]string{"Server": "cloudflare", "Cf-Ray": "2-AMS"}
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	if n := testutil.CollectAndCount(m.ResponseHeader); n != 2 {
+		t.Fatalf("Expected 2 header series, got %d", n)
+	}
+	if got := testutil.ToFloat64(m.ResponseHeader.WithLabelValues("192.0.2.1:53", "do53-udp", "cf-ray", "2-AMS")); got != 1 {
+		t.Errorf("Expected the latest cf-ray value, got %v", got)
+	}
+}
+
+func TestRecordResultConnections(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	recordResult(m, newResult("conn.example", time.Second,
+		resolver.QueryResult{Duration: 90 * time.Millisecond, Conn: &resolver.ConnState{}}), config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("conn.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Conn: &resolver.ConnState{Reused: true}}), config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("conn.example", time.Second,
+		resolver.QueryResult{Duration: 50 * time.Millisecond, Conn: &resolver.ConnState{Resumed: true}, Err: context.DeadlineExceeded}),
+		config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	for _, tt := range []struct {
+		connection string
+		count      float64
+		duration   float64
+	}{{"new", 1, 0.09}, {"reused", 1, 0.01}, {"resumed", 1, 0}} {
+		values := []string{"192.0.2.1:53", "do53-udp", tt.connection}
+		if got := testutil.ToFloat64(m.Connections.WithLabelValues(values...)); got != tt.count {
+			t.Errorf("Expected %v %s connections, got %v", tt.count, tt.connection, got)
+		}
+		if got := testutil.ToFloat64(m.ConnectionLastDuration.WithLabelValues(values...)); got != tt.duration {
+			t.Errorf("Expected last %s duration %v, got %v", tt.connection, tt.duration, got)
+		}
+	}
+}
+
+func TestRecordResultDNSSEC(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	res := newResult("signed.example", time.Second,
+		resolver.QueryResult{Duration: 10 * time.Millisecond, Response: new(dns.Msg)})
+	res.Domain.DNSSEC = config.DNSSECSecure
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	values := []string{"signed.example", "192.0.2.1:53", "do53-udp"}
+	if got := testutil.ToFloat64(m.DNSSECChecks.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 DNSSEC check, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.DNSSECMismatches.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 DNSSEC mismatch for an unauthenticated answer, got %v", got)
+	}
+}
+
+func TestRecordResultBlock(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	resp := new(dns.Msg)
+	resp.Rcode = dns.RcodeNameError
+	res := newResult("malware.example", time.Second, resolver.QueryResult{Duration: 10 * time.Millisecond, Response: resp})
+	res.Domain.Blocked = &config.Blocked{Rcode: "NXDOMAIN"}
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	resp = new(dns.Msg)
+	resp.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "malware.example.", Rrtype: dns.TypeA, Class: dns.ClassINET}, A: net.ParseIP("192.0.2.66")}}
+	res.Attempts = []resolver.QueryResult{{Duration: 10 * time.Millisecond, Response: resp}}
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	values := []string{"malware.example", "192.0.2.1:53", "do53-udp"}
+	if got := testutil.ToFloat64(m.BlockChecks.WithLabelValues(values...)); got != 2 {
+		t.Errorf("Expected 2 block checks, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.BlockBypasses.WithLabelValues(values...)); got != 1 {
+		t.Errorf("Expected 1 bypassed block, got %v", got)
+	}
+}
+
+func TestRecordResultSLA(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	sla := &config.SLA{Warning: config.Duration(50 * time.Millisecond), Critical: config.Duration(200 * time.Millisecond)}
+	for _, d := range []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, 300 * time.Millisecond} {
+		res := newResult("sla.example", time.Second, resolver.QueryResult{Duration: d})
+		res.Server.SLA = sla
+		recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+	}
+
+	values := []string{"sla.example", "192.0.2.1:53", "do53-udp"}
+	if got := testutil.ToFloat64(m.SLABreaches.WithLabelValues(append(values, "warning")...)); got != 2 {
+		t.Errorf("Expected 2 warning breaches, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.SLABreaches.WithLabelValues(append(values, "critical")...)); got != 1 {
+		t.Errorf("Expected 1 critical breach, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.SLAState.WithLabelValues(values...)); got != 2 {
+		t.Errorf("Expected critical state, got %v", got)
+	}
+}
+
+func TestRecordResultUpstream(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	res := newResult("upstream.example", time.Second, resolver.QueryResult{Duration: 30 * time.Millisecond})
+	res.Upstream = "192.0.2.10"
+	recordResult(m, res, config.FailureLatencySeparate, config.FirstProbeInclude)
+	recordResult(m, newResult("upstream.example", time.Second,
+		resolver.QueryResult{Duration: 30 * time.Millisecond}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	if got := testutil.CollectAndCount(m.UpstreamQueryDuration.(prometheus.Collector)); got != 1 {
+		t.Fatalf("Expected 1 upstream series, got %d", got)
+	}
+	h := histogram(t, m.UpstreamQueryDuration, "upstream.example", "192.0.2.1:53", "do53-udp", "192.0.2.10")
+	if h.GetSampleCount() != 1 {
+		t.Errorf("Expected 1 observation, got %d", h.GetSampleCount())
+	}
+}
+
+func TestRecordResultSteps(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	recordResult(m, newResult("iterative.example", time.Second, resolver.QueryResult{
+		Duration: 60 * time.Millisecond,
+		Steps: []resolver.Step{
+			{Zone: ".", Duration: 20 * time.Millisecond},
+			{Zone: "example.", Duration: 40 * time.Millisecond},
+		},
+	}), config.FailureLatencySeparate, config.FirstProbeInclude)
+
+	for zone, want := range map[string]float64{".": 0.02, "example.": 0.04} {
+		h := histogram(t, m.IterationStepDuration, "iterative.example", "192.0.2.1:53", "do53-udp", zone)
+		if h.GetSampleCount() != 1 || h.GetSampleSum() != want {
+			t.Errorf("Expected one %v s step for zone %s, got %d with sum %v", want, zone, h.GetSampleCount(), h.GetSampleSum())
+		}
+	}
+}
+
+// fakeLocator maps addresses to fixed locations
+type fakeLocator map[string]geoip.Location
+
+func (f fakeLocator) Lookup(ip net.IP) geoip.Location {
+	return f[ip.String()]
+}
+
+func TestRecordGeo(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	db := fakeLocator{
+		"192.0.2.10":  {Country: "DE", ASN: "64500"},
+		"192.0.2.11":  {Country: "DE", ASN: "64500"},
+		"2001:db8::1": {Country: "NL", ASN: "64501"},
+	}
+	answer := func(addrs ...string) resolver.QueryResult {
+		msg := new(dns.Msg)
+		for _, addr := range addrs {
+			rr, _ := dns.NewRR("geo.example. 300 IN A " + addr)
+			if net.ParseIP(addr).To4() == nil {
+				rr, _ = dns.NewRR("geo.example. 300 IN AAAA " + addr)
+			}
+			msg.Answer = append(msg.Answer, rr)
+		}
+		return resolver.QueryResult{Response: msg}
+	}
+
+	recordGeo(m, db, newResult("geo.example", time.Second, answer("192.0.2.10", "192.0.2.11", "2001:db8::1")))
+	if got := testutil.CollectAndCount(m.AnswerGeo); got != 2 {
+		t.Errorf("Expected 2 distinct locations, got %d", got)
+	}
+
+	recordGeo(m, db, newResult("geo.example", time.Second, answer("2001:db8::1")))
+	if got := testutil.CollectAndCount(m.AnswerGeo); got != 1 {
+		t.Errorf("Expected the latest answer to replace previous locations, got %d series", got)
+	}
+	if got := testutil.ToFloat64(m.AnswerGeo.WithLabelValues("geo.example", "192.0.2.1:53", "do53-udp", "NL", "64501")); got != 1 {
+		t.Errorf("Expected NL/64501 info series, got %v", got)
+	}
+}
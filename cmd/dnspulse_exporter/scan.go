@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/signal"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/openresolver"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// addScanCommand adds the subcommand checking addresses for open recursion
+func addScanCommand(root *cobra.Command) {
+	var domain string
+	var timeout time.Duration
+	scanCmd := &cobra.Command{
+		Use:   "scan [address|CIDR]...",
+		Short: "Check addresses for open recursion and exit",
+		Long: "Send a recursive query to every address and report whether it resolves it.\n" +
+			"Without arguments, the open_resolver_scan targets of the config file are\n" +
+			"scanned, or the addresses of its dns_servers if there are none.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addresses, err := config.ExpandTargets(args)
+			if len(args) == 0 {
+				addresses, err = configuredAddresses()
+			}
+			if err != nil {
+				return err
+			}
+			if _, ok := dns.IsDomainName(domain); !ok {
+				return fmt.Errorf("invalid domain name '%s'", domain)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return printFindings(cmd.OutOrStdout(), openresolver.Scan(ctx, addresses, domain, timeout))
+		},
+	}
+	scanCmd.Flags().StringVarP(&configFile, "config", "f", "/etc/dnspulse.yml", "path or http(s) URL of config file, read without arguments")
+	scanCmd.Flags().StringVar(&domain, "domain", "example.com", "name to resolve, which the servers must not be authoritative for")
+	scanCmd.Flags().DurationVar(&timeout, "timeout", 2*time.Second, "query timeout for each address")
+	root.AddCommand(scanCmd)
+}
+
+// configuredAddresses returns the addresses of the scan targets of the
+// config file, or those of its servers
+func configuredAddresses() ([]string, error) {
+	var cfg *config.Config
+	var err error
+	if config.IsRemote(configFile) {
+		var remote *config.RemoteSource
+		if remote, err = config.NewRemoteSource(configFile, configAuthHeader); err == nil {
+			cfg, err = remote.Fetch(context.Background())
+		}
+	} else {
+		cfg, err = config.Load(configFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.OpenResolverScan.Enabled() {
+		return config.ExpandTargets(cfg.OpenResolverScan.Targets)
+	}
+	var addresses []string
+	for _, server := range cfg.DNSServers {
+		if !slices.Contains(addresses, server.Address) {
+			addresses = append(addresses, server.Address)
+		}
+	}
+	return addresses, nil
+}
+
+// printFindings writes a line per address with its status and the response
+// code or error
+func printFindings(w io.Writer, findings []openresolver.Finding) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tSTATUS\tDETAIL")
+	for _, f := range findings {
+		detail := dns.RcodeToString[f.Rcode]
+		if f.Err != nil {
+			detail = f.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Address, f.Status(), detail)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !windows
+
+package main
+
+import "github.com/spf13/cobra"
+
+// addServiceCommand adds nothing, as service management is only needed on
+// Windows; other systems use their own service managers (see systemd/)
+func addServiceCommand(root *cobra.Command) {}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// serviceName names the Windows service and its Event Log source
+const serviceName = "dnspulse_exporter"
+
+// defaultServiceConfig is the config file used by an installed service
+// unless another is given
+var defaultServiceConfig = filepath.Join(os.Getenv("ProgramData"), "dnspulse", "dnspulse.yml")
+
+// addServiceCommand adds the subcommands managing the Windows service
+func addServiceCommand(root *cobra.Command) {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the Windows service",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the exporter as an automatically started service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return installService()
+		},
+	}
+	installCmd.Flags().StringVarP(&configFile, "config", "f", defaultServiceConfig, "path of the config file used by the service")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the service and its Event Log source",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return uninstallService()
+		},
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run as a service; started by the service manager",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runService()
+		},
+	}
+	runCmd.Flags().StringVarP(&configFile, "config", "f", defaultServiceConfig, "path of config file")
+
+	serviceCmd.AddCommand(installCmd, uninstallCmd, runCmd)
+	root.AddCommand(serviceCmd)
+}
+
+// installService registers the service to run this executable with the
+// given config file, and an Event Log source for its messages
+func installService() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	config, err := filepath.Abs(configFile)
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", serviceName)
+	}
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "DNS Pulse Exporter",
+		Description: "Prometheus exporter for DNS query metrics",
+		StartType:   mgr.StartAutomatic,
+	}, "service", "run", "--config", config)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = s.Delete()
+		return fmt.Errorf("failed to create Event Log source: %w", err)
+	}
+	fmt.Printf("Service %s installed with config %s\n", serviceName, config)
+	return nil
+}
+
+// uninstallService removes the service and its Event Log source
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", serviceName)
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+	if err := eventlog.Remove(serviceName); err != nil {
+		return fmt.Errorf("failed to remove Event Log source: %w", err)
+	}
+	fmt.Printf("Service %s removed\n", serviceName)
+	return nil
+}
+
+// runService runs the exporter under the service manager, logging to the
+// Event Log
+func runService() error {
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to open Event Log: %w", err)
+	}
+	defer elog.Close()
+
+	logging.SetSink(eventLogSink{elog})
+	log.SetFlags(0)
+	log.SetOutput(eventLogWriter{elog})
+
+	if err := svc.Run(serviceName, windowsService{}); err != nil {
+		return fmt.Errorf("service failed: %w", err)
+	}
+	return nil
+}
+
+// windowsService runs the exporter until the service manager stops it
+type windowsService struct{}
+
+// Execute implements svc.Handler
+func (windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	shutdown, stop := context.WithCancel(context.Background())
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(shutdown)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				stop()
+				<-done
+				return false, 0
+			}
+		case <-done:
+			// The exporter stopped on its own
+			return false, 1
+		}
+	}
+}
+
+// eventLogSink writes log messages to the Event Log with their severity.
+// Event ID 1 is used for every message.
+type eventLogSink struct {
+	elog *eventlog.Log
+}
+
+// Write implements logging.Sink
+func (s eventLogSink) Write(level logging.Level, message string) {
+	switch level {
+	case logging.LevelError:
+		_ = s.elog.Error(1, message)
+	case logging.LevelWarn:
+		_ = s.elog.Warning(1, message)
+	default:
+		_ = s.elog.Info(1, message)
+	}
+}
+
+// eventLogWriter writes standard logger output, such as fatal startup
+// errors, to the Event Log as errors
+type eventLogWriter struct {
+	elog *eventlog.Log
+}
+
+// Write implements io.Writer
+func (w eventLogWriter) Write(p []byte) (int, error) {
+	if err := w.elog.Error(1, strings.TrimSpace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import "os"
+
+// notifyPause does nothing, as Windows has no signal for pausing; the
+// pause API is available instead
+func notifyPause(c chan<- os.Signal) {}
+
+// notifyReopen does nothing, as Windows has no signal for reopening the log
+// file; it is rotated by size and age only
+func notifyReopen(c chan<- os.Signal) {}
+
+// notifyReload does nothing, as Windows has no signal for reloading; the
+// reload endpoint is available instead
+func notifyReload(c chan<- os.Signal) {}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// buildInfo describes the build, for fleet automation to tell what each
+// binary supports
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+
+	// Tags are the build tags the binary was compiled with
+	Tags []string `json:"tags"`
+
+	// Protocols are the probe protocols compiled in
+	Protocols []string `json:"protocols"`
+
+	// Features are the optional capabilities compiled in
+	Features []string `json:"features"`
+}
+
+// currentBuild returns the description of the running binary
+func currentBuild() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    gitCommit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Tags:      []string{},
+		Protocols: resolver.Protocols(),
+		Features:  resolver.Features(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			if setting.Key == "-tags" && setting.Value != "" {
+				info.Tags = strings.Split(setting.Value, ",")
+			}
+		}
+	}
+	if runtime.GOOS == "windows" {
+		info.Features = append(info.Features, "windows_service")
+	} else {
+		info.Features = append(info.Features, "drop_privileges", "signals")
+	}
+	sort.Strings(info.Features)
+	return info
+}
+
+// addVersionCommand adds the subcommand describing the build
+func addVersionCommand(root *cobra.Command) {
+	var asJSON bool
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, supported protocols and features and exit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printVersion(cmd.OutOrStdout(), currentBuild(), asJSON)
+		},
+	}
+	versionCmd.Flags().BoolVar(&asJSON, "json", false, "print as JSON")
+	root.AddCommand(versionCmd)
+}
+
+// printVersion writes the build description to w, as text or as JSON
+func printVersion(w io.Writer, info buildInfo, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	_, err := fmt.Fprintf(w, "dnspulse_exporter %s (commit: %s, built: %s)\n"+
+		"go: %s %s/%s\n"+
+		"protocols: %s\n"+
+		"features: %s\n",
+		info.Version, info.Commit, info.BuildTime,
+		info.GoVersion, info.OS, info.Arch,
+		strings.Join(info.Protocols, ", "),
+		strings.Join(info.Features, ", "))
+	return err
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPrintVersion(t *testing.T) {
+	info := currentBuild()
+	if !slices.Contains(info.Protocols, "do53-udp") {
+		t.Errorf("Expected do53-udp among the protocols, got %v", info.Protocols)
+	}
+	if !slices.IsSorted(info.Features) {
+		t.Errorf("Expected sorted features, got %v", info.Features)
+	}
+
+	var out strings.Builder
+	if err := printVersion(&out, info, true); err != nil {
+		t.Fatalf("printVersion failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("Expected JSON, got %v:\n%s", err, out.String())
+	}
+	for _, key := range []string{"version", "commit", "build_time", "go_version", "os", "arch", "tags", "protocols", "features"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected %q in the JSON output, got:\n%s", key, out.String())
+		}
+	}
+	if tags, ok := decoded["tags"].([]any); !ok {
+		t.Errorf("Expected tags to be a list even without tags, got %v", decoded["tags"])
+	} else if len(tags) != len(info.Tags) {
+		t.Errorf("Expected %d tags, got %v", len(info.Tags), tags)
+	}
+
+	out.Reset()
+	if err := printVersion(&out, info, false); err != nil {
+		t.Fatalf("printVersion failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "protocols: "+strings.Join(info.Protocols, ", ")) {
+		t.Errorf("Expected the protocols in the text output, got:\n%s", out.String())
+	}
+}
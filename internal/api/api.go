@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/internal/probe"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+)
+
+// healthcheckTimeout bounds the health checks run for /api/v1/targets
+const healthcheckTimeout = 10 * time.Second
+
+// Backend provides the exporter state served by the API
+type Backend interface {
+	// Config returns the currently loaded configuration
+	Config() *config.Config
+
+	// Prober returns the currently running prober
+	Prober() *prober.Prober
+
+	// Reload loads the configuration again and queues it to replace the
+	// running one, returning an error if it cannot be loaded
+	Reload(ctx context.Context) error
+}
+
+// API serves the exporter's management endpoints under /api/v1/, and
+// /-/reload
+type API struct {
+	backend Backend
+	limiter limiter
+}
+
+// New creates an API backed by the given exporter state
+func New(backend Backend) *API {
+	return &API{backend: backend}
+}
+
+// Register adds the API endpoints to mux. The endpoints changing probing
+// require the API token if one is configured, and those sending queries
+// are rate limited.
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/config", a.handleConfig)
+	mux.HandleFunc("GET /api/v1/targets", a.limit(a.handleTargets))
+	mux.HandleFunc("GET /api/v1/results", a.handleResults)
+	mux.HandleFunc("GET /api/v1/divergences", a.handleDivergences)
+	mux.HandleFunc("GET /api/v1/errors", a.handleErrors)
+	mux.HandleFunc("GET /api/v1/debug/response", a.handleResponse)
+	mux.HandleFunc("POST /api/v1/probe", a.authorize(a.limit(a.handleProbe)))
+	mux.HandleFunc("GET /api/v1/drain", a.handleDrained)
+	mux.HandleFunc("POST /api/v1/drain", a.authorize(a.handleDrain))
+	mux.HandleFunc("DELETE /api/v1/drain", a.authorize(a.handleUndrain))
+	mux.HandleFunc("GET /api/v1/pause", a.handlePauseState)
+	mux.HandleFunc("POST /api/v1/pause", a.authorize(a.handlePause))
+	mux.HandleFunc("POST /api/v1/resume", a.authorize(a.handleResume))
+	mux.HandleFunc("POST /-/reload", a.authorize(a.handleReload))
+	mux.HandleFunc("GET /probe", a.limit(probe.Handler(a.backend.Config)))
+}
+
+// authorize wraps a handler changing probing to require the configured API
+// token as bearer token. Without a token every request passes.
+func (a *API) authorize(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := a.backend.Config().API.Token
+		if token == "" {
+			h(w, r)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dnspulse"`)
+			writeError(w, http.StatusUnauthorized, "invalid or missing API token")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handleConfig serves the loaded configuration with secrets redacted
+func (a *API) handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.backend.Config().Redacted())
+}
+
+// handleTargets lists the probed targets with their capabilities and
+// health check results
+func (a *API) handleTargets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthcheckTimeout)
+	defer cancel()
+	writeJSON(w, http.StatusOK, map[string][]prober.Target{"targets": a.backend.Prober().Targets(ctx)})
+}
+
+// handleResults lists the latest probe result of every target and domain
+func (a *API) handleResults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]prober.LatestResult{"results": a.backend.Prober().LatestResults()})
+}
+
+// handleDivergences lists the latest answers that differed from a domain's
+// reference answer
+func (a *API) handleDivergences(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]prober.Divergence{"divergences": a.backend.Prober().Divergences()})
+}
+
+// handleErrors lists the recent failures of the target given by the target
+// query parameter, or of every target without it
+func (a *API) handleErrors(w http.ResponseWriter, r *http.Request) {
+	errs, err := a.backend.Prober().Errors(r.URL.Query().Get("target"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]prober.ProbeError{"errors": errs})
+}
+
+// handleResponse serves the most recent response of the target given by the
+// target query parameter
+func (a *API) handleResponse(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "missing target parameter")
+		return
+	}
+	resp, err := a.backend.Prober().LastResponse(target)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleProbe probes the target given by the target query parameter right
+// away and returns the results once done
+func (a *API) handleProbe(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "missing target parameter")
+		return
+	}
+	logging.Infof("Probing %s via API", target)
+	// Besides an unknown target, probing only fails when the client went
+	// away, so nobody reads the status
+	results, err := a.backend.Prober().ProbeNow(r.Context(), target)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]prober.LatestResult{"results": results})
+}
+
+// handleDrained lists the drained targets
+func (a *API) handleDrained(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{"drained": a.backend.Prober().Drained()})
+}
+
+// handleDrain stops probing the target given by the target query parameter
+func (a *API) handleDrain(w http.ResponseWriter, r *http.Request) {
+	a.changeDrain(w, r, a.backend.Prober().Drain)
+}
+
+// handleUndrain resumes probing the target given by the target query parameter
+func (a *API) handleUndrain(w http.ResponseWriter, r *http.Request) {
+	a.changeDrain(w, r, a.backend.Prober().Undrain)
+}
+
+// changeDrain applies a drain state change to the requested target
+func (a *API) changeDrain(w http.ResponseWriter, r *http.Request, change func(string) error) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "missing target parameter")
+		return
+	}
+	if err := change(target); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	a.handleDrained(w, r)
+}
+
+// handlePauseState reports whether probing is paused
+func (a *API) handlePauseState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": a.backend.Prober().Paused()})
+}
+
+// handlePause suspends probing while keeping the metrics endpoint up
+func (a *API) handlePause(w http.ResponseWriter, r *http.Request) {
+	a.backend.Prober().Pause()
+	logging.Infof("Probing paused via API")
+	a.handlePauseState(w, r)
+}
+
+// handleResume resumes probing after a pause
+func (a *API) handleResume(w http.ResponseWriter, r *http.Request) {
+	a.backend.Prober().Resume()
+	logging.Infof("Probing resumed via API")
+	a.handlePauseState(w, r)
+}
+
+// handleReload loads the configuration again. It responds once the new
+// configuration is loaded and queued; the probe loop applies it after the
+// running cycle.
+func (a *API) handleReload(w http.ResponseWriter, r *http.Request) {
+	logging.Infof("Reloading configuration via API")
+	if err := a.backend.Reload(r.Context()); err != nil {
+		logging.Errorf("Failed to reload configuration: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to reload configuration: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]bool{"reloading": true})
+}
+
+// writeError sends a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeJSON encodes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logging.Warnf("Failed to write API response: %v", err)
+	}
+}
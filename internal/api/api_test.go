@@ -0,0 +1,362 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+)
+
+// fakeBackend serves a fixed configuration and prober, and counts reloads
+type fakeBackend struct {
+	cfg       *config.Config
+	prober    *prober.Prober
+	reloads   int
+	reloadErr error
+}
+
+func (b *fakeBackend) Config() *config.Config { return b.cfg }
+
+func (b *fakeBackend) Prober() *prober.Prober { return b.prober }
+
+func (b *fakeBackend) Reload(ctx context.Context) error {
+	if b.reloadErr != nil {
+		return b.reloadErr
+	}
+	b.reloads++
+	return nil
+}
+
+// newFakeBackend creates a backend with a prober for cfg
+func newFakeBackend(t *testing.T, cfg *config.Config) *fakeBackend {
+	t.Helper()
+	p, err := prober.New(cfg)
+	if err != nil {
+		t.Fatalf("prober.New failed: %v", err)
+	}
+	t.Cleanup(p.Close)
+	return &fakeBackend{cfg: cfg, prober: p}
+}
+
+// newTestMux creates a mux with the API registered against backend
+func newTestMux(backend Backend) *http.ServeMux {
+	mux := http.NewServeMux()
+	New(backend).Register(mux)
+	return mux
+}
+
+func TestHandleConfig(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		ListenPort: "9953",
+		Interval:   config.Duration(30 * time.Second),
+		DNSServers: []config.DNSServer{
+			{Address: "9.9.9.9", Port: "853", Protocol: config.ProtocolDoT, TLS: &config.TLSConfig{ServerName: "dns.quad9.net"}},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	newTestMux(backend).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if got["interval"] != "30s" {
+		t.Errorf("Expected interval '30s', got %v", got["interval"])
+	}
+	servers, ok := got["dns_servers"].([]interface{})
+	if !ok || len(servers) != 1 {
+		t.Fatalf("Expected 1 DNS server, got %v", got["dns_servers"])
+	}
+	if servers[0].(map[string]interface{})["address"] != "9.9.9.9" {
+		t.Errorf("Expected server address '9.9.9.9', got %v", servers[0])
+	}
+}
+
+func TestDrain(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		method  string
+		url     string
+		status  int
+		drained int
+	}{
+		{http.MethodPost, "/api/v1/drain?target=8.8.8.8:53:do53-udp", http.StatusOK, 1},
+		{http.MethodGet, "/api/v1/drain", http.StatusOK, 1},
+		{http.MethodPost, "/api/v1/drain?target=1.1.1.1:53:do53-udp", http.StatusNotFound, 1},
+		{http.MethodPost, "/api/v1/drain", http.StatusBadRequest, 1},
+		{http.MethodDelete, "/api/v1/drain?target=8.8.8.8:53:do53-udp", http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.url, tt.status, rec.Code)
+		}
+		if got := len(backend.prober.Drained()); got != tt.drained {
+			t.Errorf("%s %s: expected %d drained targets, got %d", tt.method, tt.url, tt.drained, got)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		method string
+		url    string
+		paused bool
+	}{
+		{http.MethodPost, "/api/v1/pause", true},
+		{http.MethodGet, "/api/v1/pause", true},
+		{http.MethodPost, "/api/v1/resume", false},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status 200, got %d", tt.method, tt.url, rec.Code)
+		}
+		var got map[string]bool
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		if got["paused"] != tt.paused || backend.prober.Paused() != tt.paused {
+			t.Errorf("%s %s: expected paused=%v, got %v", tt.method, tt.url, tt.paused, got["paused"])
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "192.0.2.1", Port: "853", Protocol: config.ProtocolDoT, Timeout: 100},
+			{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP, Timeout: 100},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	newTestMux(backend).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/targets", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var got map[string][]prober.Target
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	targets := got["targets"]
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].Key != "192.0.2.1:853:dot" || !targets[0].Capabilities.Encrypted {
+		t.Errorf("Expected encrypted DoT target first, got %+v", targets[0])
+	}
+	for _, target := range targets {
+		if target.Ready || target.Error == "" {
+			t.Errorf("Expected unreachable target %s to fail its health check", target.Key)
+		}
+	}
+}
+
+func TestDivergences(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{})
+
+	rec := httptest.NewRecorder()
+	newTestMux(backend).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/divergences", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var got map[string][]prober.Divergence
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if divergences, ok := got["divergences"]; !ok || len(divergences) != 0 {
+		t.Errorf("Expected an empty divergences list, got %v", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	})
+	mux := newTestMux(backend)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/errors?target=8.8.8.8:53:do53-udp", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var got map[string][]prober.ProbeError
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if errs, ok := got["errors"]; !ok || len(errs) != 0 {
+		t.Errorf("Expected an empty errors list, got %v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/errors?target=1.1.1.1:53:do53-udp", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown target, got %d", rec.Code)
+	}
+}
+
+func TestDebugResponse(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"/api/v1/debug/response", http.StatusBadRequest},
+		{"/api/v1/debug/response?target=1.1.1.1:53:do53-udp", http.StatusNotFound},
+		{"/api/v1/debug/response?target=8.8.8.8:53:do53-udp", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.url, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"/api/v1/probe", http.StatusBadRequest},
+		{"/api/v1/probe?target=1.1.1.1:53:do53-udp", http.StatusNotFound},
+		{"/api/v1/probe?target=8.8.8.8:53:do53-udp", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("POST %s: expected status %d, got %d", tt.url, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		API: config.API{Token: "s3cret"},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		method string
+		url    string
+		auth   string
+		status int
+	}{
+		{http.MethodPost, "/api/v1/pause", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/pause", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/pause", "Basic czNjcmV0", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/pause", "Bearer s3cret", http.StatusOK},
+		{http.MethodGet, "/api/v1/pause", "", http.StatusOK},
+		{http.MethodPost, "/api/v1/drain?target=8.8.8.8:53:do53-udp", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/drain", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/config", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s with %q: expected status %d, got %d", tt.method, tt.url, tt.auth, tt.status, rec.Code)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s %s: expected WWW-Authenticate header", tt.method, tt.url)
+		}
+	}
+	if !backend.prober.Paused() {
+		t.Error("Expected the authorized request to pause probing")
+	}
+	if len(backend.prober.Drained()) != 0 {
+		t.Error("Expected the unauthorized drain to be rejected")
+	}
+}
+
+func TestReload(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{API: config.API{Token: "s3cret"}})
+	mux := newTestMux(backend)
+
+	reload := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/-/reload", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := reload(""); rec.Code != http.StatusUnauthorized || backend.reloads != 0 {
+		t.Errorf("Expected unauthorized reload to be rejected, got status %d and %d reloads", rec.Code, backend.reloads)
+	}
+	if rec := reload("Bearer s3cret"); rec.Code != http.StatusAccepted || backend.reloads != 1 {
+		t.Errorf("Expected reload to be accepted, got status %d and %d reloads", rec.Code, backend.reloads)
+	}
+
+	backend.reloadErr = errors.New("invalid configuration")
+	rec := reload("Bearer s3cret")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for a failed reload, got %d", rec.Code)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || !strings.Contains(got["error"], "invalid configuration") {
+		t.Errorf("Expected the load error in the response, got %s", rec.Body.String())
+	}
+}
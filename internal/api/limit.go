@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// limiter caps the rate and concurrency of the API requests that send
+// queries, so that a runaway script cannot flood the monitored servers
+// through the exporter. It follows the limits of the current
+// configuration; zero limits are not enforced.
+type limiter struct {
+	mu     sync.Mutex
+	bucket *rate.Limiter
+	active int
+}
+
+// acquire admits a request within the limits of cfg. If it returns true,
+// release must be called once the request is served.
+func (l *limiter) acquire(cfg config.API) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg.MaxConcurrent > 0 && l.active >= cfg.MaxConcurrent {
+		return false
+	}
+	if qps := cfg.RateLimit.QPS; qps > 0 {
+		limit, burst := rate.Limit(qps), max(cfg.RateLimit.Burst, 1)
+		if l.bucket == nil {
+			l.bucket = rate.NewLimiter(limit, burst)
+		} else if l.bucket.Limit() != limit || l.bucket.Burst() != burst {
+			l.bucket.SetLimit(limit)
+			l.bucket.SetBurst(burst)
+		}
+		if !l.bucket.Allow() {
+			return false
+		}
+	}
+	l.active++
+	return true
+}
+
+// release frees the slot of a request admitted by acquire
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+}
+
+// limit wraps a handler sending queries to answer 429 Too Many Requests
+// beyond the configured rate and concurrency
+func (a *API) limit(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.limiter.acquire(a.backend.Config().API) {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+		defer a.limiter.release()
+		h(w, r)
+	}
+}
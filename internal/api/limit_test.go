@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+func TestLimiter(t *testing.T) {
+	var l limiter
+	cfg := config.API{MaxConcurrent: 1}
+	if !l.acquire(cfg) {
+		t.Fatal("Expected the first request to be admitted")
+	}
+	if l.acquire(cfg) {
+		t.Error("Expected a concurrent request to be rejected")
+	}
+	l.release()
+	if !l.acquire(cfg) {
+		t.Error("Expected a request to be admitted after release")
+	}
+	l.release()
+
+	cfg = config.API{RateLimit: config.RateLimit{QPS: 0.001, Burst: 2}}
+	for i := range 2 {
+		if !l.acquire(cfg) {
+			t.Errorf("Expected request %d within the burst to be admitted", i+1)
+		}
+		l.release()
+	}
+	if l.acquire(cfg) {
+		t.Error("Expected a request beyond the burst to be rejected")
+	}
+}
+
+func TestLimit(t *testing.T) {
+	backend := newFakeBackend(t, &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		API: config.API{RateLimit: config.RateLimit{QPS: 0.001, Burst: 1}},
+	})
+	mux := newTestMux(backend)
+
+	tests := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{http.MethodPost, "/api/v1/probe", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/probe", http.StatusTooManyRequests},
+		{http.MethodGet, "/api/v1/targets", http.StatusTooManyRequests},
+		{http.MethodGet, "/api/v1/results", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.url, tt.status, rec.Code)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s %s: expected Retry-After header", tt.method, tt.url)
+		}
+	}
+}
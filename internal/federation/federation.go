@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+)
+
+// fetchTimeout bounds pulling the results of a single peer
+const fetchTimeout = 10 * time.Second
+
+// staleIntervals is how many pull intervals a result stays current
+const staleIntervals = 3
+
+// Backend provides the local configuration and results
+type Backend interface {
+	Config() *config.Config
+	Prober() *prober.Prober
+}
+
+// Aggregator periodically pulls the latest results of peer exporters and
+// exports a cross-site view of every target
+type Aggregator struct {
+	backend Backend
+	metrics *metrics.Metrics
+	client  *http.Client
+}
+
+// New creates an aggregator that reads the federation settings from the
+// backend's current configuration, so that reloads apply
+func New(backend Backend, m *metrics.Metrics) *Aggregator {
+	return &Aggregator{
+		backend: backend,
+		metrics: m,
+		client:  &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+// Run pulls and aggregates results every federation interval until ctx is
+// cancelled. While federation is disabled it only watches for a
+// configuration enabling it.
+func (a *Aggregator) Run(ctx context.Context) {
+	for {
+		cfg := a.backend.Config()
+		interval := time.Duration(cfg.Interval)
+		if cfg.Federation.Enabled() {
+			interval = time.Duration(cfg.Federation.Interval)
+			a.collect(ctx, cfg)
+		} else {
+			a.metrics.SetFederation(nil)
+			a.metrics.SetPeersUp(nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// collect pulls the results of every peer and exports the aggregate
+// together with the local results
+func (a *Aggregator) collect(ctx context.Context, cfg *config.Config) {
+	results := a.backend.Prober().LatestResults()
+	up := make(map[string]bool, len(cfg.Federation.Peers))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, peer := range cfg.Federation.Peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			peerResults, err := a.fetch(ctx, peer)
+			mu.Lock()
+			defer mu.Unlock()
+			up[peerName(peer)] = err == nil
+			if err != nil {
+				logging.Warnf("Failed to pull results from peer %s: %v", peerName(peer), err)
+				return
+			}
+			results = append(results, peerResults...)
+		}(peer)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+	maxAge := staleIntervals * time.Duration(cfg.Federation.Interval)
+	a.metrics.SetPeersUp(up)
+	a.metrics.SetFederation(aggregate(results, time.Now().Add(-maxAge)))
+}
+
+// fetch pulls the latest results of a peer
+func (a *Aggregator) fetch(ctx context.Context, peer string) ([]prober.LatestResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peer, "/")+"/api/v1/results", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Results []prober.LatestResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid results: %w", err)
+	}
+	return body.Results, nil
+}
+
+// peerName returns the peer URL without credentials, for logs and labels
+func peerName(peer string) string {
+	u, err := url.Parse(peer)
+	if err != nil {
+		return peer
+	}
+	return u.Redacted()
+}
+
+// aggregate builds the cross-site view of every target from the results of
+// all sites. Results older than since are ignored, and for each target the
+// newest result of a site counts.
+func aggregate(results []prober.LatestResult, since time.Time) []metrics.FederatedTarget {
+	type targetKey struct{ domain, server, protocol string }
+	bySite := make(map[targetKey]map[string]prober.LatestResult)
+	var keys []targetKey
+	for _, res := range results {
+		if res.Time.Before(since) {
+			continue
+		}
+		key := targetKey{res.Domain, res.Server, res.Protocol}
+		sites, ok := bySite[key]
+		if !ok {
+			sites = make(map[string]prober.LatestResult)
+			bySite[key] = sites
+			keys = append(keys, key)
+		}
+		if prev, ok := sites[res.Site]; !ok || res.Time.After(prev.Time) {
+			sites[res.Site] = res
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b targetKey) int {
+		return strings.Compare(a.domain+"|"+a.server+"|"+a.protocol, b.domain+"|"+b.server+"|"+b.protocol)
+	})
+	targets := make([]metrics.FederatedTarget, 0, len(keys))
+	for _, key := range keys {
+		t := metrics.FederatedTarget{Domain: key.domain, Server: key.server, Protocol: key.protocol}
+		var durations []float64
+		for _, res := range bySite[key] {
+			t.Sites++
+			if res.Success {
+				durations = append(durations, res.Duration)
+			}
+		}
+		t.SuccessRatio = float64(len(durations)) / float64(t.Sites)
+		if len(durations) > 1 {
+			t.LatencySpread = slices.Max(durations) - slices.Min(durations)
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+)
+
+type fakeBackend struct {
+	cfg    *config.Config
+	prober *prober.Prober
+}
+
+func (b *fakeBackend) Config() *config.Config { return b.cfg }
+
+func (b *fakeBackend) Prober() *prober.Prober { return b.prober }
+
+func TestAggregate(t *testing.T) {
+	now := time.Now()
+	result := func(site, server string, success bool, duration float64, age time.Duration) prober.LatestResult {
+		return prober.LatestResult{
+			Site: site, Server: server, Protocol: "do53-udp", Domain: "example.com",
+			Success: success, Duration: duration, Time: now.Add(-age),
+		}
+	}
+
+	targets := aggregate([]prober.LatestResult{
+		result("fra1", "192.0.2.1:53", true, 0.010, 0),
+		result("nyc1", "192.0.2.1:53", true, 0.090, 0),
+		result("sin1", "192.0.2.1:53", false, 2, 0),
+		result("sin1", "192.0.2.1:53", true, 0.5, time.Minute),
+		result("fra1", "192.0.2.2:53", true, 0.020, 0),
+		result("nyc1", "192.0.2.2:53", true, 0.020, time.Hour),
+	}, now.Add(-10*time.Minute))
+
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+	first := targets[0]
+	if first.Server != "192.0.2.1:53" || first.Sites != 3 {
+		t.Errorf("Expected 3 sites for 192.0.2.1:53, got %+v", first)
+	}
+	if first.SuccessRatio < 0.66 || first.SuccessRatio > 0.67 {
+		t.Errorf("Expected success ratio 2/3 from the newest result per site, got %v", first.SuccessRatio)
+	}
+	if first.LatencySpread < 0.079 || first.LatencySpread > 0.081 {
+		t.Errorf("Expected latency spread 0.08, got %v", first.LatencySpread)
+	}
+	if second := targets[1]; second.Sites != 1 || second.LatencySpread != 0 {
+		t.Errorf("Expected the stale result to be ignored, got %+v", second)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/results" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]prober.LatestResult{"results": {{
+			Site: "nyc1", Server: "192.0.2.1:53", Protocol: "do53-udp", Domain: "example.com",
+			Success: true, Duration: 0.05, Time: time.Now(),
+		}}})
+	}))
+	defer peer.Close()
+
+	cfg := &config.Config{Federation: config.Federation{
+		Peers:    config.StringList{peer.URL, peer.URL + "/missing"},
+		Interval: config.Duration(time.Minute),
+	}}
+	p, err := prober.New(cfg)
+	if err != nil {
+		t.Fatalf("prober.New failed: %v", err)
+	}
+	defer p.Close()
+	m := metrics.New(prometheus.NewRegistry())
+
+	New(&fakeBackend{cfg: cfg, prober: p}, m).collect(context.Background(), cfg)
+
+	if got := testutil.ToFloat64(m.FederationSites.WithLabelValues("example.com", "192.0.2.1:53", "do53-udp")); got != 1 {
+		t.Errorf("Expected 1 site, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.FederationPeerUp.WithLabelValues(peer.URL)); got != 1 {
+		t.Errorf("Expected peer to be up, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.FederationPeerUp.WithLabelValues(peer.URL + "/missing")); got != 0 {
+		t.Errorf("Expected failing peer to be down, got %v", got)
+	}
+}
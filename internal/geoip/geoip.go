@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location is the country and autonomous system of an address. Fields are
+// empty when unknown or when the matching database is not configured.
+type Location struct {
+	Country string
+	ASN     string
+}
+
+// DB looks up addresses in local MaxMind DB (MMDB) files, such as
+// GeoLite2-Country and GeoLite2-ASN, held in memory
+type DB struct {
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+}
+
+// countryRecord is the subset of a GeoIP2/GeoLite2 country or city record
+// used for lookups
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// asnRecord is the subset of a GeoLite2-ASN record used for lookups
+type asnRecord struct {
+	Number uint `maxminddb:"autonomous_system_number"`
+}
+
+// Open reads the given databases into memory. Either path may be empty to
+// skip that database. The files are read completely, so a DB needs no
+// closing and is unaffected by later updates of the files.
+func Open(countryPath, asnPath string) (*DB, error) {
+	db := &DB{}
+	var err error
+	if countryPath != "" {
+		if db.country, err = load(countryPath); err != nil {
+			return nil, err
+		}
+	}
+	if asnPath != "" {
+		if db.asn, err = load(asnPath); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// load reads an MMDB file into memory
+func load(path string) (*maxminddb.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MMDB file: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MMDB file %s: %w", path, err)
+	}
+	return reader, nil
+}
+
+// Lookup returns the location of ip. Lookup errors leave the affected
+// field empty.
+func (db *DB) Lookup(ip net.IP) Location {
+	var loc Location
+	if db.country != nil {
+		var record countryRecord
+		if err := db.country.Lookup(ip, &record); err == nil {
+			loc.Country = record.Country.ISOCode
+		}
+	}
+	if db.asn != nil {
+		var record asnRecord
+		if err := db.asn.Lookup(ip, &record); err == nil && record.Number != 0 {
+			loc.ASN = strconv.FormatUint(uint64(record.Number), 10)
+		}
+	}
+	return loc
+}
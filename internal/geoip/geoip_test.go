@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	db, err := Open("", "")
+	if err != nil {
+		t.Fatalf("Open without databases failed: %v", err)
+	}
+	if loc := db.Lookup(net.ParseIP("192.0.2.1")); loc != (Location{}) {
+		t.Errorf("Expected empty location without databases, got %+v", loc)
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"), ""); err == nil {
+		t.Error("Expected error for a missing database")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.mmdb")
+	if err := os.WriteFile(invalid, []byte("not an mmdb file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open("", invalid); err == nil {
+		t.Error("Expected error for an invalid database")
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// lease is the content of the lock file
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// Elector elects one leader among replicas sharing a lock file. The leader
+// renews a lease in the file every third of the lease duration; a standby
+// takes over once the lease has expired. The file is replaced atomically,
+// so it must live on storage all replicas can write, such as a shared
+// volume.
+type Elector struct {
+	path     string
+	identity string
+	duration time.Duration
+	leader   atomic.Bool
+	now      func() time.Time
+}
+
+// New creates an elector for the lock file at path. The identity must be
+// unique among the replicas.
+func New(path, identity string, duration time.Duration) *Elector {
+	return &Elector{path: path, identity: identity, duration: duration, now: time.Now}
+}
+
+// IsLeader returns true while this replica holds the lease
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run keeps acquiring or renewing the lease until ctx is cancelled, calling
+// onChange whenever leadership changes. A held lease is released on return
+// so that a standby can take over immediately.
+func (e *Elector) Run(ctx context.Context, onChange func(leader bool)) {
+	ticker := time.NewTicker(e.duration / 3)
+	defer ticker.Stop()
+
+	for {
+		leader, err := e.try()
+		if err != nil {
+			logging.Errorf("Leader election failed: %v", err)
+		}
+		if leader != e.leader.Swap(leader) {
+			onChange(leader)
+		}
+
+		select {
+		case <-ctx.Done():
+			if e.leader.Load() {
+				e.release()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// try acquires or renews the lease if it is free, expired or already ours.
+// It reads the file back after writing to detect a replica that wrote at
+// the same time.
+func (e *Elector) try() (bool, error) {
+	current, err := e.read()
+	if err != nil {
+		return false, err
+	}
+	now := e.now()
+	if current.Holder != "" && current.Holder != e.identity && now.Before(current.Expires) {
+		return false, nil
+	}
+
+	if err := e.write(lease{Holder: e.identity, Expires: now.Add(e.duration)}); err != nil {
+		return false, err
+	}
+	current, err = e.read()
+	if err != nil {
+		return false, err
+	}
+	return current.Holder == e.identity, nil
+}
+
+// read returns the current lease, or an empty lease if there is no file
+func (e *Elector) read() (lease, error) {
+	var l lease
+	data, err := os.ReadFile(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		// A corrupt lease is treated as free
+		return lease{}, nil
+	}
+	return l, nil
+}
+
+// write atomically replaces the lock file with l
+func (e *Elector) write(l lease) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.path); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// release removes the lock file if this replica still holds it
+func (e *Elector) release() {
+	if current, err := e.read(); err == nil && current.Holder == e.identity {
+		if err := os.Remove(e.path); err != nil {
+			logging.Warnf("Failed to release leader lease: %v", err)
+		}
+	}
+	e.leader.Store(false)
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package leader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnspulse.lock")
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	a := New(path, "a", 15*time.Second)
+	b := New(path, "b", 15*time.Second)
+	a.now, b.now = clock, clock
+
+	if leader, err := a.try(); err != nil || !leader {
+		t.Fatalf("Expected a to acquire the free lease, got %v, %v", leader, err)
+	}
+	if leader, err := b.try(); err != nil || leader {
+		t.Fatalf("Expected b to stand by while the lease is held, got %v, %v", leader, err)
+	}
+	if leader, err := a.try(); err != nil || !leader {
+		t.Fatalf("Expected a to renew its lease, got %v, %v", leader, err)
+	}
+
+	now = now.Add(16 * time.Second)
+	if leader, err := b.try(); err != nil || !leader {
+		t.Fatalf("Expected b to take over the expired lease, got %v, %v", leader, err)
+	}
+	if leader, err := a.try(); err != nil || leader {
+		t.Fatalf("Expected a to stand by after losing the lease, got %v, %v", leader, err)
+	}
+}
+
+func TestRunReleases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnspulse.lock")
+	e := New(path, "a", 3*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan bool, 2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx, func(leader bool) { changes <- leader })
+	}()
+
+	if leader := <-changes; !leader {
+		t.Fatal("Expected to become leader")
+	}
+	cancel()
+	<-done
+
+	if e.IsLeader() {
+		t.Error("Expected leadership to end on return")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package logging
+
+import (
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// File is a log file that is rotated when it grows past a size, keeping a
+// bounded number and age of rotated files
+type File struct {
+	logger *lumberjack.Logger
+}
+
+// Rotation sets when a log file is rotated and which rotated files are kept
+type Rotation struct {
+	// MaxSize is the size in megabytes at which the file is rotated
+	// (0 = 100)
+	MaxSize int
+	// MaxAge removes rotated files older than this, rounded up to whole
+	// days (0 = keep regardless of age)
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept (0 = all)
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// NewFile returns a log file at path; it is opened on the first write
+func NewFile(path string, rotation Rotation) *File {
+	const day = 24 * time.Hour
+	return &File{logger: &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotation.MaxSize,
+		MaxAge:     int((rotation.MaxAge + day - 1) / day),
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+		LocalTime:  true,
+	}}
+}
+
+// Write implements io.Writer, rotating the file first if needed
+func (f *File) Write(p []byte) (int, error) {
+	return f.logger.Write(p)
+}
+
+// Reopen closes the file so that the next write opens path again, e.g.
+// after an external tool such as logrotate moved it away
+func (f *File) Reopen() error {
+	return f.logger.Close()
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.logger.Close()
+}
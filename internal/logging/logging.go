@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the minimum severity of messages that are written
+type Level int32
+
+// Supported log levels
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[string]Level{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"warn":  LevelWarn,
+	"error": LevelError,
+}
+
+var current atomic.Int32
+
+// Sink receives the messages that pass the level filter in place of the
+// standard logger, e.g. to forward them to a system log with severities
+type Sink interface {
+	Write(level Level, message string)
+}
+
+var sink atomic.Pointer[Sink]
+
+// Limit restricts how many messages of a level are written
+type Limit struct {
+	// Every writes one in this many sampled messages (0 or 1 writes all);
+	// messages not logged through Samplef are never sampled out
+	Every int
+	// PerMinute is the maximum number of messages written per minute,
+	// sampled or not (0 = unlimited)
+	PerMinute int
+}
+
+// limiter applies a Limit and counts what it drops in the current minute
+type limiter struct {
+	mu      sync.Mutex
+	limit   Limit
+	seen    int
+	window  time.Time
+	written int
+	dropped int
+}
+
+var limiters atomic.Pointer[map[Level]*limiter]
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a Level
+func ParseLevel(name string) (Level, error) {
+	level, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return LevelInfo, fmt.Errorf("invalid log level '%s'", name)
+	}
+	return level, nil
+}
+
+// SetLevel sets the minimum level of messages that are written
+func SetLevel(level Level) {
+	current.Store(int32(level))
+}
+
+// SetSink sends messages to s instead of the standard logger; nil restores
+// the standard logger
+func SetSink(s Sink) {
+	if s == nil {
+		sink.Store(nil)
+		return
+	}
+	sink.Store(&s)
+}
+
+// SetLimits replaces the sampling and rate limits of each level; levels
+// without an entry are not limited
+func SetLimits(limits map[Level]Limit) {
+	m := make(map[Level]*limiter, len(limits))
+	for level, limit := range limits {
+		m[level] = &limiter{limit: limit}
+	}
+	limiters.Store(&m)
+}
+
+// HasSink returns true if messages are sent to a sink rather than the
+// standard logger
+func HasSink() bool {
+	return sink.Load() != nil
+}
+
+// Enabled returns true if messages at the given level are written
+func Enabled(level Level) bool {
+	return level >= Level(current.Load())
+}
+
+// Debugf logs a message at debug level
+func Debugf(format string, args ...interface{}) {
+	output(LevelDebug, false, format, args...)
+}
+
+// Infof logs a message at info level
+func Infof(format string, args ...interface{}) {
+	output(LevelInfo, false, format, args...)
+}
+
+// Warnf logs a message at warn level
+func Warnf(format string, args ...interface{}) {
+	output(LevelWarn, false, format, args...)
+}
+
+// Errorf logs a message at error level
+func Errorf(format string, args ...interface{}) {
+	output(LevelError, false, format, args...)
+}
+
+// Samplef logs a routine message, such as a successful query, at level.
+// Unlike the other functions it is subject to the level's sampling, so
+// failures should be logged with those to always be written.
+func Samplef(level Level, format string, args ...interface{}) {
+	output(level, true, format, args...)
+}
+
+// output writes a message at level if the level is enabled and its limits
+// allow it
+func output(level Level, sampled bool, format string, args ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+	if m := limiters.Load(); m != nil {
+		if l := (*m)[level]; l != nil {
+			ok, dropped := l.allow(sampled, time.Now())
+			if dropped > 0 {
+				write(level, fmt.Sprintf("Suppressed %d %s messages in the last minute", dropped, levelName(level)))
+			}
+			if !ok {
+				return
+			}
+		}
+	}
+	write(level, fmt.Sprintf(format, args...))
+}
+
+// write sends a message to the sink or the standard logger
+func write(level Level, message string) {
+	if s := sink.Load(); s != nil {
+		(*s).Write(level, message)
+		return
+	}
+	log.Print(message)
+}
+
+// allow reports whether a message is written, and the number of messages
+// dropped by the cap in the previous minute once a new one starts
+func (l *limiter) allow(sampled bool, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if sampled && l.limit.Every > 1 {
+		l.seen++
+		if l.seen%l.limit.Every != 1 {
+			return false, 0
+		}
+	}
+	if l.limit.PerMinute <= 0 {
+		return true, 0
+	}
+
+	var dropped int
+	if now.Sub(l.window) >= time.Minute {
+		dropped = l.dropped
+		l.window, l.written, l.dropped = now, 0, 0
+	}
+	if l.written >= l.limit.PerMinute {
+		l.dropped++
+		return false, dropped
+	}
+	l.written++
+	return true, dropped
+}
+
+// levelName returns the name of a level as accepted by ParseLevel
+func levelName(level Level) string {
+	for name, l := range levelNames {
+		if l == level {
+			return name
+		}
+	}
+	return fmt.Sprintf("level %d", level)
+}
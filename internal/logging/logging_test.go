@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package logging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type captureSink struct {
+	messages []string
+}
+
+func (c *captureSink) Write(level Level, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func TestSampling(t *testing.T) {
+	capture := &captureSink{}
+	SetSink(capture)
+	SetLevel(LevelDebug)
+	SetLimits(map[Level]Limit{LevelDebug: {Every: 3}})
+	defer func() {
+		SetSink(nil)
+		SetLevel(LevelInfo)
+		SetLimits(nil)
+	}()
+
+	for i := 0; i < 6; i++ {
+		Samplef(LevelDebug, "success %d", i)
+		Debugf("failure %d", i)
+	}
+	want := []string{"success 0", "failure 0", "failure 1", "failure 2", "success 3", "failure 3", "failure 4", "failure 5"}
+	if strings.Join(capture.messages, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, capture.messages)
+	}
+}
+
+func TestPerMinuteCap(t *testing.T) {
+	l := &limiter{limit: Limit{PerMinute: 2}}
+	now := time.Now()
+
+	for i, want := range []bool{true, true, false, false} {
+		if ok, _ := l.allow(false, now); ok != want {
+			t.Errorf("Message %d: expected allowed=%v, got %v", i, want, ok)
+		}
+	}
+	ok, dropped := l.allow(false, now.Add(time.Minute))
+	if !ok || dropped != 2 {
+		t.Errorf("Expected the next minute to allow and report 2 dropped, got %v and %d", ok, dropped)
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package logging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Facility is a syslog facility code
+type Facility int
+
+var facilityNames = map[string]Facility{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3,
+	"auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// localSyslogPaths are the sockets of the local syslog daemon on Linux,
+// macOS and the BSDs
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// ParseFacility converts a facility name such as daemon or local0 to a
+// Facility
+func ParseFacility(name string) (Facility, error) {
+	facility, ok := facilityNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid syslog facility '%s'", name)
+	}
+	return facility, nil
+}
+
+// Syslog is a Sink that sends messages to a syslog daemon in RFC 5424
+// format, over the local socket or to a remote server over UDP or TCP
+type Syslog struct {
+	network  string
+	address  string
+	facility Facility
+	tag      string
+	hostname string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	dialed string
+}
+
+// NewSyslog connects to the syslog daemon at address: "udp://host:port",
+// "tcp://host:port", the path of a local socket, or "" for the local
+// daemon. Messages carry the given facility and tag (the RFC 5424
+// APP-NAME).
+func NewSyslog(address string, facility Facility, tag string) (*Syslog, error) {
+	s := &Syslog{facility: facility, tag: tag}
+	switch {
+	case strings.HasPrefix(address, "udp://"):
+		s.network, s.address = "udp", strings.TrimPrefix(address, "udp://")
+	case strings.HasPrefix(address, "tcp://"):
+		s.network, s.address = "tcp", strings.TrimPrefix(address, "tcp://")
+	default:
+		s.network, s.address = "unixgram", address
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect dials the daemon; the caller must hold mu
+func (s *Syslog) connect() error {
+	if s.network != "unixgram" {
+		conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog at %s: %w", s.address, err)
+		}
+		s.conn, s.dialed = conn, s.network
+		return nil
+	}
+
+	paths := localSyslogPaths
+	if s.address != "" {
+		paths = []string{s.address}
+	}
+	var err error
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			if conn, err = net.Dial(network, path); err == nil {
+				s.conn, s.dialed = conn, network
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("failed to connect to the local syslog: %w", err)
+}
+
+// Write implements Sink. A message that cannot be sent after reconnecting
+// once is written to standard error.
+func (s *Syslog) Write(level Level, message string) {
+	line := s.format(level, message, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err := s.connect(); err != nil {
+				break
+			}
+		}
+		if _, err := s.conn.Write(s.frame(line)); err == nil {
+			return
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	fmt.Fprintln(os.Stderr, message)
+}
+
+// format returns message as an RFC 5424 syslog message
+func (s *Syslog) format(level Level, message string, now time.Time) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		int(s.facility)*8+severity(level), now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.tag, os.Getpid(), message)
+}
+
+// frame delimits line for the connection: datagrams need nothing, TCP uses
+// the octet counting of RFC 6587 and local stream sockets a newline
+func (s *Syslog) frame(line string) []byte {
+	switch s.dialed {
+	case "tcp":
+		return []byte(fmt.Sprintf("%d %s", len(line), line))
+	case "unix":
+		return []byte(line + "\n")
+	default:
+		return []byte(line)
+	}
+}
+
+// Close closes the connection to the daemon
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// severity returns the syslog severity of a level
+func severity(level Level) int {
+	switch level {
+	case LevelError:
+		return 3
+	case LevelWarn:
+		return 4
+	case LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package logging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	defer conn.Close()
+
+	facility, err := ParseFacility("local3")
+	if err != nil {
+		t.Fatalf("ParseFacility failed: %v", err)
+	}
+	s, err := NewSyslog("udp://"+conn.LocalAddr().String(), facility, "dnspulse")
+	if err != nil {
+		t.Fatalf("NewSyslog failed: %v", err)
+	}
+	defer s.Close()
+	s.Write(LevelWarn, "query failed")
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("No message received: %v", err)
+	}
+	// local3 (19) * 8 + warning (4) = 156
+	want := regexp.MustCompile(fmt.Sprintf(`^<156>1 \S+ \S+ dnspulse %d - - query failed$`, os.Getpid()))
+	if !want.Match(buf[:n]) {
+		t.Errorf("Unexpected syslog message %q", buf[:n])
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	if _, err := ParseFacility("local8"); err == nil {
+		t.Error("Expected error for local8, got nil")
+	}
+}
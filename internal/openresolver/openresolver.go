@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+// Package openresolver checks addresses for DNS recursion open to the
+// exporter, a common finding of security assessments, with the same query
+// stack the probes use.
+package openresolver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// workers is how many addresses are checked at the same time
+const workers = 64
+
+// Finding is the outcome of checking an address
+type Finding struct {
+	Address string
+
+	// Answered is true if the address sent any response, and Rcode is
+	// its response code
+	Answered bool
+	Rcode    int
+
+	// Open is true if the address resolved a name it is not
+	// authoritative for
+	Open bool
+
+	Err error
+}
+
+// Status describes the finding in a word: open, refused or silent
+func (f Finding) Status() string {
+	switch {
+	case f.Open:
+		return "open"
+	case f.Answered:
+		return "refused"
+	default:
+		return "silent"
+	}
+}
+
+// Check sends a recursive query for domain to the Do53 server at address,
+// port 53 unless given as host:port, and tells whether it resolved it
+func Check(ctx context.Context, address, domain string, timeout time.Duration) Finding {
+	f := Finding{Address: address}
+	opts := resolver.Options{Address: address, Timeouts: resolver.Timeouts{Total: timeout}}
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		opts.Address, opts.Port = host, port
+	}
+	r, err := resolver.New(resolver.ProtocolDo53UDP, opts)
+	if err != nil {
+		f.Err = err
+		return f
+	}
+	defer r.Close()
+
+	result := r.Query(ctx, domain, dns.TypeA)
+	if result.Err != nil {
+		f.Err = result.Err
+		return f
+	}
+	resp := result.Response
+	f.Answered = true
+	f.Rcode = resp.Rcode
+	f.Open = resp.Rcode == dns.RcodeSuccess && resp.RecursionAvailable && !resp.Authoritative && len(resp.Answer) > 0
+	return f
+}
+
+// Scan checks every address, several at a time, and returns the findings
+// in the order of addresses. If ctx is cancelled, the findings stop at the
+// first address left unchecked.
+func Scan(ctx context.Context, addresses []string, domain string, timeout time.Duration) []Finding {
+	findings := make([]Finding, len(addresses))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(addresses)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				findings[i] = Check(ctx, addresses[i], domain, timeout)
+			}
+		}()
+	}
+	sent := 0
+	for sent < len(addresses) && ctx.Err() == nil {
+		select {
+		case next <- sent:
+			sent++
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+	return findings[:sent]
+}
+
+// Backend provides the current configuration
+type Backend interface {
+	Config() *config.Config
+}
+
+// Scanner periodically scans the configured targets and exports the
+// findings
+type Scanner struct {
+	backend Backend
+	metrics *metrics.Metrics
+}
+
+// New creates a scanner that reads its settings from the backend's current
+// configuration, so that reloads apply
+func New(backend Backend, m *metrics.Metrics) *Scanner {
+	return &Scanner{backend: backend, metrics: m}
+}
+
+// Run scans the targets every scan interval until ctx is cancelled. While
+// scanning is disabled it only watches for a configuration enabling it.
+func (s *Scanner) Run(ctx context.Context) {
+	for {
+		cfg := s.backend.Config()
+		interval := time.Duration(cfg.Interval)
+		if scan := cfg.OpenResolverScan; scan.Enabled() {
+			interval = time.Duration(scan.Interval)
+			s.scan(ctx, scan)
+		} else {
+			s.metrics.SetOpenResolvers(nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// scan checks the targets once and exports the addresses that answered
+func (s *Scanner) scan(ctx context.Context, scan config.OpenResolverScan) {
+	addresses, err := config.ExpandTargets(scan.Targets)
+	if err != nil {
+		logging.Errorf("Open resolver scan: %v", err)
+		return
+	}
+	findings := Scan(ctx, addresses, scan.Domain, time.Duration(scan.Timeout))
+	if ctx.Err() != nil {
+		return
+	}
+
+	answered := make(map[string]bool)
+	open := 0
+	for _, f := range findings {
+		if !f.Answered {
+			continue
+		}
+		answered[f.Address] = f.Open
+		if f.Open {
+			open++
+			logging.Warnf("Open resolver scan: %s resolves %s for the exporter", f.Address, scan.Domain)
+		}
+	}
+	logging.Infof("Open resolver scan: %d of %d addresses answered, %d open", len(answered), len(addresses), open)
+	s.metrics.SetOpenResolvers(answered)
+}
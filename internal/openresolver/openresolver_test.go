@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package openresolver
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startServer serves handler over UDP on a free local port until the test
+// ends
+func startServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+	return startServerAt(t, "127.0.0.1:0", handler)
+}
+
+// startServerAt runs a Do53 UDP server on addr and returns its address
+func startServerAt(t *testing.T, addr string, handler dns.HandlerFunc) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: handler}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestScan(t *testing.T) {
+	open := startServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.RecursionAvailable = true
+		resp.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		}}
+		w.WriteMsg(resp)
+	})
+	refusing := startServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Rcode = dns.RcodeRefused
+		w.WriteMsg(resp)
+	})
+	authoritative := startServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Authoritative = true
+		resp.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		}}
+		w.WriteMsg(resp)
+	})
+	silent := startServer(t, func(w dns.ResponseWriter, req *dns.Msg) {})
+
+	addresses := []string{open, refusing, authoritative, silent}
+	findings := Scan(context.Background(), addresses, "example.com", 200*time.Millisecond)
+	want := []string{"open", "refused", "refused", "silent"}
+	for i, f := range findings {
+		if f.Address != addresses[i] {
+			t.Errorf("Expected finding %d for %s, got %s", i, addresses[i], f.Address)
+		}
+		if f.Status() != want[i] {
+			t.Errorf("Expected %s to be %s, got %s (%v)", f.Address, want[i], f.Status(), f.Err)
+		}
+	}
+	if findings[1].Rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED, got %s", dns.RcodeToString[findings[1].Rcode])
+	}
+	if findings[3].Err == nil {
+		t.Error("Expected an error for the silent server")
+	}
+
+	// IPv6 addresses are checked like any other, with or without a port
+	refusing6 := startServerAt(t, "[::1]:0", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Rcode = dns.RcodeRefused
+		w.WriteMsg(resp)
+	})
+	if f := Check(context.Background(), refusing6, "example.com", 200*time.Millisecond); f.Status() != "refused" {
+		t.Errorf("Expected %s to be refused, got %s (%v)", refusing6, f.Status(), f.Err)
+	}
+	if f := Check(context.Background(), "::1", "example.com", 200*time.Millisecond); f.Err != nil && strings.Contains(f.Err.Error(), "too many colons") {
+		t.Errorf("Expected ::1 to be dialed on port 53, got %v", f.Err)
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/prober"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// Handler probes the target named in the request once and serves the
+// probe_* metrics of the outcome:
+//
+//	/probe?target=HOST[:PORT]&module=PROTOCOL&domain=NAME[&qtype=TYPE]
+//
+// The module is the protocol, do53-udp by default, and the port defaults
+// to the protocol's. Like configured probes, the query asks for a random
+// name under the domain. The timeout and retries come from the defaults of
+// the current configuration, and the probe never outlives the scrape. The
+// endpoint only answers if enabled in the configuration, and only for the
+// allowed targets.
+func Handler(current func() *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := current()
+		if !cfg.ProbeEndpoint.Enabled {
+			http.Error(w, "the probe endpoint is disabled (see probe_endpoint.enabled)", http.StatusNotFound)
+			return
+		}
+		domain, server, err := parseRequest(r, cfg.Defaults)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkTarget(server.Address, cfg.ProbeEndpoint.TargetPrefixes()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		timeout, err := Timeout(r, DefaultOffset, serverTimeout(cfg, server.Protocol))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		start := time.Now()
+		res := prober.ProbeOnce(ctx, domain, server, timeout)
+		elapsed := time.Since(start)
+		if res.Err != nil {
+			logging.Debugf("[%s] %s:%s - probe of %s via /probe failed: %v", server.Protocol, server.Address, server.Port, domain.Name, res.Err)
+		}
+
+		result := resolver.QueryResult{Duration: res.Duration, Err: res.Err}
+		if len(res.Attempts) > 0 {
+			result.Response = res.Last().Response
+		}
+		m := NewMetrics()
+		m.Record(result, elapsed)
+		m.Handler().ServeHTTP(w, r)
+	}
+}
+
+// parseRequest reads the domain and server to probe from the query
+// parameters of r
+func parseRequest(r *http.Request, defaults config.Defaults) (config.Domain, config.DNSServer, error) {
+	query := r.URL.Query()
+	target := query.Get("target")
+	if target == "" {
+		return config.Domain{}, config.DNSServer{}, fmt.Errorf("missing target parameter")
+	}
+	protocol := query.Get("module")
+	if protocol == "" {
+		protocol = config.ProtocolDo53UDP
+	}
+	if !resolver.Registered(protocol) {
+		return config.Domain{}, config.DNSServer{}, fmt.Errorf("unknown module '%s' (expected one of %s)", protocol, strings.Join(resolver.Protocols(), ", "))
+	}
+	name := query.Get("domain")
+	if _, ok := dns.IsDomainName(name); !ok || name == "" {
+		return config.Domain{}, config.DNSServer{}, fmt.Errorf("invalid domain parameter '%s'", name)
+	}
+	qtype := query.Get("qtype")
+	if _, ok := dns.StringToType[strings.ToUpper(qtype)]; qtype != "" && !ok {
+		return config.Domain{}, config.DNSServer{}, fmt.Errorf("unknown qtype '%s'", qtype)
+	}
+
+	address, port, err := net.SplitHostPort(target)
+	if err != nil {
+		address, port = strings.Trim(target, "[]"), resolver.DefaultPort(protocol)
+	}
+	server := config.DNSServer{Address: address, Port: port, Protocol: protocol, Retries: &defaults.Retries}
+	if config.IsEncryptedProtocol(protocol) {
+		server.TLS = &config.TLSConfig{ServerName: address}
+	}
+	return config.Domain{Name: name, QType: qtype}, server, nil
+}
+
+// checkTarget returns an error unless the address is within one of the
+// allowed prefixes. Targets must be IP addresses, as names could resolve
+// anywhere, and without prefixes no target is allowed.
+func checkTarget(address string, allowed []netip.Prefix) error {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return fmt.Errorf("target '%s' is not an IP address, as required by probe_endpoint.allowed_targets", address)
+	}
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("target '%s' is not within probe_endpoint.allowed_targets", address)
+}
+
+// serverTimeout returns the timeout configured for servers of the given
+// protocol that do not set their own
+func serverTimeout(cfg *config.Config, protocol string) time.Duration {
+	switch {
+	case cfg.Defaults.Timeout > 0:
+		return time.Duration(cfg.Defaults.Timeout) * time.Millisecond
+	case cfg.Timeout > 0:
+		return time.Duration(cfg.Timeout) * time.Millisecond
+	default:
+		return config.DefaultTimeout(protocol)
+	}
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// startServer runs a Do53 UDP server on a local port and returns its
+// address
+func startServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: handler}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestHandler(t *testing.T) {
+	var qtype atomic.Uint32
+	target := startServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		qtype.Store(uint32(req.Question[0].Qtype))
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = []dns.RR{&dns.AAAA{
+			Hdr:  dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 300},
+			AAAA: net.ParseIP("2001:db8::1"),
+		}}
+		w.WriteMsg(resp)
+	})
+	endpoint := config.ProbeEndpoint{Enabled: true, AllowedTargets: config.StringList{"127.0.0.0/8", "::1"}}
+	cfg := &config.Config{Defaults: config.Defaults{Timeout: 500}, ProbeEndpoint: endpoint}
+	handler := Handler(func() *config.Config { return cfg })
+
+	probe := func(query url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?"+query.Encode(), nil))
+		return rec
+	}
+
+	rec := probe(url.Values{"target": {target}, "domain": {"example.com"}, "qtype": {"AAAA"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"probe_success 1", "probe_dns_answer_rrs 1", "probe_dns_duration_seconds"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, body)
+		}
+	}
+	if got := uint16(qtype.Load()); got != dns.TypeAAAA {
+		t.Errorf("Expected an AAAA query, got %s", dns.TypeToString[got])
+	}
+
+	// IPv6 targets are given in brackets with a port
+	if conn, err := net.ListenPacket("udp", "[::1]:0"); err == nil {
+		server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			w.WriteMsg(resp)
+		})}
+		go server.ActivateAndServe()
+		defer server.Shutdown()
+		rec = probe(url.Values{"target": {conn.LocalAddr().String()}, "domain": {"example.com"}})
+		if body := rec.Body.String(); !strings.Contains(body, "probe_success 1") {
+			t.Errorf("Expected probe_success 1 for %s, got:\n%s", conn.LocalAddr(), body)
+		}
+	}
+
+	// Nothing listens on the port of a closed socket
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	closed := conn.LocalAddr().String()
+	conn.Close()
+	cfg.Defaults.Timeout = 100
+	rec = probe(url.Values{"target": {closed}, "module": {"do53-udp"}, "domain": {"example.com"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for a failed probe, got %d: %s", rec.Code, rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "probe_success 0") {
+		t.Errorf("Expected probe_success 0 for an unreachable target, got:\n%s", body)
+	}
+}
+
+func TestHandlerInvalid(t *testing.T) {
+	cfg := &config.Config{ProbeEndpoint: config.ProbeEndpoint{Enabled: true, AllowedTargets: config.StringList{"192.0.2.0/24"}}}
+	handler := Handler(func() *config.Config { return cfg })
+
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"missing target", url.Values{"domain": {"example.com"}}},
+		{"missing domain", url.Values{"target": {"192.0.2.1"}}},
+		{"invalid domain", url.Values{"target": {"192.0.2.1"}, "domain": {"exa mple..com"}}},
+		{"unknown module", url.Values{"target": {"192.0.2.1"}, "domain": {"example.com"}, "module": {"dns-over-pigeon"}}},
+		{"unknown qtype", url.Values{"target": {"192.0.2.1"}, "domain": {"example.com"}, "qtype": {"BOGUS"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?"+tt.query.Encode(), nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerTargets(t *testing.T) {
+	cfg := &config.Config{}
+	handler := Handler(func() *config.Config { return cfg })
+	probe := func(target string) int {
+		query := url.Values{"target": {target}, "domain": {"example.com"}}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?"+query.Encode(), nil))
+		return rec.Code
+	}
+
+	if code := probe("192.0.2.1"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 while the endpoint is disabled, got %d", code)
+	}
+
+	// Without allowed targets nothing may be probed
+	cfg.ProbeEndpoint = config.ProbeEndpoint{Enabled: true}
+	if code := probe("127.0.0.1:1"); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without allowed targets, got %d", code)
+	}
+
+	cfg.ProbeEndpoint = config.ProbeEndpoint{Enabled: true, AllowedTargets: config.StringList{"127.0.0.0/8", "::1"}}
+	for _, target := range []string{"169.254.169.254:80", "[2001:db8::1]:53", "localhost"} {
+		if code := probe(target); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s, got %d", target, code)
+		}
+	}
+	// A closed port fails the probe, but is allowed
+	for _, target := range []string{"127.0.0.1:1", "[::1]:1"} {
+		if code := probe(target); code != http.StatusOK {
+			t.Errorf("Expected 200 for %s, got %d", target, code)
+		}
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		target, module     string
+		address, port, sni string
+	}{
+		{"192.0.2.1", "", "192.0.2.1", "53", ""},
+		{"192.0.2.1:5353", "", "192.0.2.1", "5353", ""},
+		{"2001:db8::1", "", "2001:db8::1", "53", ""},
+		{"[2001:db8::1]:853", "dot", "2001:db8::1", "853", "2001:db8::1"},
+		{"dns.example.net", "dot", "dns.example.net", "853", "dns.example.net"},
+	}
+	for _, tt := range tests {
+		query := url.Values{"target": {tt.target}, "module": {tt.module}, "domain": {"example.com"}}
+		_, server, err := parseRequest(httptest.NewRequest("GET", "/probe?"+query.Encode(), nil), config.Defaults{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.target, err)
+			continue
+		}
+		if server.Address != tt.address || server.Port != tt.port {
+			t.Errorf("%s: expected %s port %s, got %s port %s", tt.target, tt.address, tt.port, server.Address, server.Port)
+		}
+		sni := ""
+		if server.TLS != nil {
+			sni = server.TLS.ServerName
+		}
+		if sni != tt.sni {
+			t.Errorf("%s: expected server name %q, got %q", tt.target, tt.sni, sni)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package probe
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// Metrics are the probe_* metrics of a single on-demand probe. Every probe
+// registers them on a registry of its own, which only lives for the
+// request, so that ad-hoc targets never add series to the dns_* metrics of
+// the configured targets.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	// Success is 1 if the probe got a response
+	Success prometheus.Gauge
+
+	// Duration is how long the probe took as a whole
+	Duration prometheus.Gauge
+
+	// DNSDuration is how long the query took, including retries
+	DNSDuration prometheus.Gauge
+
+	// DNSAnswerRRs is the number of records in the answer section
+	DNSAnswerRRs prometheus.Gauge
+}
+
+// NewMetrics creates the metrics of a probe on a new registry
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		Success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the probe got a response (1) or failed (0)",
+		}),
+		Duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Duration of the probe as a whole",
+		}),
+		DNSDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_dns_duration_seconds",
+			Help: "Duration of the DNS query, including retries",
+		}),
+		DNSAnswerRRs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_dns_answer_rrs",
+			Help: "Number of records in the answer section of the response",
+		}),
+	}
+	m.registry.MustRegister(m.Success, m.Duration, m.DNSDuration, m.DNSAnswerRRs)
+	return m
+}
+
+// Record sets the metrics from the outcome of the probe's query and the
+// time the whole probe took
+func (m *Metrics) Record(result resolver.QueryResult, elapsed time.Duration) {
+	m.Duration.Set(elapsed.Seconds())
+	m.DNSDuration.Set(result.Duration.Seconds())
+	if result.Err != nil {
+		m.Success.Set(0)
+		return
+	}
+	m.Success.Set(1)
+	if result.Response != nil {
+		m.DNSAnswerRRs.Set(float64(len(result.Response.Answer)))
+	}
+}
+
+// Handler serves the metrics of the probe in the exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package probe
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+func TestMetrics(t *testing.T) {
+	resp := new(dns.Msg)
+	rr, _ := dns.NewRR("example.com. 300 IN A 192.0.2.1")
+	resp.Answer = append(resp.Answer, rr)
+
+	m := NewMetrics()
+	m.Record(resolver.QueryResult{Response: resp, Duration: 20 * time.Millisecond}, 25*time.Millisecond)
+	if got := testutil.ToFloat64(m.Success); got != 1 {
+		t.Errorf("Expected probe_success 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.DNSAnswerRRs); got != 1 {
+		t.Errorf("Expected 1 answer record, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/probe", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "probe_dns_duration_seconds 0.02") {
+		t.Errorf("Expected the query duration in the output, got:\n%s", body)
+	}
+	if strings.Contains(body, "dns_query") || strings.Contains(body, "go_goroutines") {
+		t.Errorf("Expected only probe metrics in the output, got:\n%s", body)
+	}
+
+	failed := NewMetrics()
+	failed.Record(resolver.QueryResult{Err: errors.New("timeout"), Duration: time.Second}, time.Second)
+	if got := testutil.ToFloat64(failed.Success); got != 0 {
+		t.Errorf("Expected probe_success 0, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.Success); got != 1 {
+		t.Errorf("Expected the first probe's metrics to be unaffected, got %v", got)
+	}
+}
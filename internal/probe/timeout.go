@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+// Package probe supports on-demand probes of targets named in the request,
+// in the manner of the blackbox exporter's /probe endpoint, which
+// Prometheus drives through relabeling.
+package probe
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ScrapeTimeoutHeader is the header in which Prometheus announces the
+// scrape timeout, in seconds
+const ScrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// DefaultOffset is left of the scrape timeout to encode and send the
+// response before Prometheus gives up on the scrape
+const DefaultOffset = 500 * time.Millisecond
+
+// Timeout returns how long a probe for the request may take, so that it
+// never outlives its scrape: the scrape timeout announced by Prometheus
+// less offset, capped at max. Without the header, e.g. when requested by
+// hand, it returns max. Scrape timeouts not longer than offset are used as
+// they are.
+func Timeout(r *http.Request, offset, max time.Duration) (time.Duration, error) {
+	header := r.Header.Get(ScrapeTimeoutHeader)
+	if header == "" {
+		return max, nil
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid %s header '%s'", ScrapeTimeoutHeader, header)
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > offset {
+		timeout -= offset
+	}
+	return min(timeout, max), nil
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package probe
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 30 * time.Second, false},
+		{"10", 9500 * time.Millisecond, false},
+		{"2.5", 2 * time.Second, false},
+		{"0.3", 300 * time.Millisecond, false},
+		{"120", 30 * time.Second, false},
+		{"0", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/probe", nil)
+		if tt.header != "" {
+			r.Header.Set(ScrapeTimeoutHeader, tt.header)
+		}
+		got, err := Timeout(r, DefaultOffset, 30*time.Second)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Timeout with header %q: unexpected error %v", tt.header, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Timeout with header %q = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// AccessLog logs every request to h at info level while enabled returns
+// true, as key=value pairs with the client address, the user of any basic
+// authentication, the request, the response status and size, and the
+// duration. Requests over Unix sockets have no client address.
+func AccessLog(h http.Handler, enabled func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled() {
+			h.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		aw := &accessWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(aw, r)
+		logging.Infof("access client=%s user=%s method=%s path=%s status=%d bytes=%d duration=%.3f",
+			clientAddress(r), user(r), r.Method, strconv.Quote(r.URL.RequestURI()), aw.status, aw.bytes, time.Since(start).Seconds())
+	})
+}
+
+// clientAddress returns the IP address of the client, or "-" if unknown
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || host == "" {
+		return "-"
+	}
+	return host
+}
+
+// user returns the user name of the request's basic authentication, or "-"
+func user(r *http.Request) string {
+	if name, _, ok := r.BasicAuth(); ok && name != "" {
+		return strconv.Quote(name)
+	}
+	return "-"
+}
+
+// accessWriter records the status and the number of bytes of a response
+type accessWriter struct {
+	http.ResponseWriter
+	status  int
+	bytes   int
+	written bool
+}
+
+// WriteHeader records the status
+func (w *accessWriter) WriteHeader(status int) {
+	if !w.written {
+		w.status = status
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes of the body
+func (w *accessWriter) Write(b []byte) (int, error) {
+	w.written = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
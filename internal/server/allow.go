@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// AllowClients answers 403 Forbidden to clients outside the prefixes that
+// allowed returns, and passes the requests of the others to h. No prefixes
+// allow every client. Requests over Unix sockets, which have no client
+// address, are always passed on; the permissions of the socket file
+// control them.
+func AllowClients(h http.Handler, allowed func() []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefixes := allowed()
+		if len(prefixes) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || host == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		addr, err := netip.ParseAddr(host)
+		if err == nil {
+			addr = addr.Unmap().WithZone("")
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		http.Error(w, "client not allowed", http.StatusForbidden)
+	})
+}
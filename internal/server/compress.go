@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// Content codings offered, in order of preference
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+)
+
+// Encoders are reused across responses, as a zstd encoder allocates its
+// window and history buffers up front
+var (
+	zstdEncoders sync.Pool // of *zstd.Encoder
+	gzipWriters  sync.Pool // of *gzip.Writer
+)
+
+// newEncoder returns a pooled encoder for the content coding writing to w
+func newEncoder(encoding string, w io.Writer) (io.WriteCloser, error) {
+	if encoding == encodingZstd {
+		if enc, ok := zstdEncoders.Get().(*zstd.Encoder); ok {
+			enc.Reset(w)
+			return enc, nil
+		}
+		// Responses are encoded on the request's goroutine, so the
+		// encoder needs no goroutines of its own
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	if gz, ok := gzipWriters.Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return gz, nil
+	}
+	return gzip.NewWriter(w), nil
+}
+
+// releaseEncoder returns an encoder to its pool, without holding on to the
+// response it wrote
+func releaseEncoder(encoder io.WriteCloser) {
+	switch enc := encoder.(type) {
+	case *zstd.Encoder:
+		enc.Reset(io.Discard)
+		zstdEncoders.Put(enc)
+	case *gzip.Writer:
+		enc.Reset(io.Discard)
+		gzipWriters.Put(enc)
+	}
+}
+
+// Compress compresses the responses of h with zstd or gzip, whichever the
+// client accepts, preferring zstd. Responses that h encodes itself, such
+// as those of promhttp, are passed through.
+func Compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the preferred content coding accepted by an
+// Accept-Encoding header, or "" for none
+func negotiate(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[name] = true
+	}
+	for _, encoding := range []string{encodingZstd, encodingGzip} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter compresses the body of a response once the handler has
+// decided on its headers
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	started bool
+	encoder io.WriteCloser // nil while passing through
+}
+
+// WriteHeader sets up compression unless the response is already encoded
+// or has no body
+func (w *compressWriter) WriteHeader(status int) {
+	if w.started {
+		return
+	}
+	w.started = true
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		encoder, err := newEncoder(w.encoding, w.ResponseWriter)
+		if err != nil {
+			// The response is still served, uncompressed
+			logging.Errorf("Failed to create %s encoder: %v", w.encoding, err)
+		} else {
+			w.encoder = encoder
+			header.Set("Content-Encoding", w.encoding)
+			header.Add("Vary", "Accept-Encoding")
+			header.Del("Content-Length")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write compresses b, writing the headers first if needed
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.encoder.Write(b)
+}
+
+// close flushes the compressed body and releases the encoder. The status
+// has been sent by then, so a failure can only be logged; it usually means
+// the client went away.
+func (w *compressWriter) close() {
+	if w.encoder == nil {
+		return
+	}
+	if err := w.encoder.Close(); err != nil {
+		logging.Debugf("Failed to finish %s response: %v", w.encoding, err)
+	}
+	releaseEncoder(w.encoder)
+	w.encoder = nil
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// Server serves a shared handler on one or more listen addresses
+type Server struct {
+	addresses []string
+	handler   http.Handler
+	servers   []*http.Server
+}
+
+// New creates a server for the given listen addresses. Each address is
+// either host:port for TCP or unix:/path/to/socket for a Unix socket.
+func New(addresses []string, handler http.Handler) *Server {
+	return &Server{
+		addresses: addresses,
+		handler:   handler,
+	}
+}
+
+// ParseAddress splits a listen address into a network and address suitable
+// for net.Listen
+func ParseAddress(address string) (network, addr string) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", address
+}
+
+// Start opens every listener and begins serving. If any listener cannot be
+// opened, those already opened are closed and an error is returned.
+func (s *Server) Start() error {
+	var listeners []net.Listener
+	for _, address := range s.addresses {
+		network, addr := ParseAddress(address)
+		if network == "unix" {
+			removeStaleSocket(addr)
+		}
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	for i, ln := range listeners {
+		srv := &http.Server{
+			Handler:      s.handler,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  120 * time.Second,
+		}
+		s.servers = append(s.servers, srv)
+
+		address := s.addresses[i]
+		go func() {
+			logging.Infof("Starting Prometheus metrics server on %s", address)
+			if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+				logging.Errorf("HTTP server error on %s: %v", address, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Shutdown gracefully stops all servers, waiting until ctx expires
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, srv := range s.servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeStaleSocket deletes a leftover Unix socket file from a previous run
+func removeStaleSocket(path string) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+}
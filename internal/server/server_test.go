@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+	}{
+		{"127.0.0.1:9953", "tcp", "127.0.0.1:9953"},
+		{"[::1]:9953", "tcp", "[::1]:9953"},
+		{":9953", "tcp", ":9953"},
+		{"unix:/run/dnspulse.sock", "unix", "/run/dnspulse.sock"},
+	}
+
+	for _, tt := range tests {
+		network, addr := ParseAddress(tt.address)
+		if network != tt.network || addr != tt.addr {
+			t.Errorf("ParseAddress(%q): expected %s %s, got %s %s", tt.address, tt.network, tt.addr, network, addr)
+		}
+	}
+}
+
+func TestServerMultipleListeners(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "dnspulse.sock")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	s := New([]string{"127.0.0.1:0", "unix:" + socket}, handler)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer func() { _ = s.Shutdown(context.Background()) }()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Get("http://unix/metrics")
+	if err != nil {
+		t.Fatalf("Request over unix socket failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("Expected body 'ok', got %q", body)
+	}
+}
+
+func TestServerStartFailure(t *testing.T) {
+	s := New([]string{"127.0.0.1:0", "256.0.0.1:1"}, http.NotFoundHandler())
+	if err := s.Start(); err == nil {
+		_ = s.Shutdown(context.Background())
+		t.Error("Expected error for invalid listen address, got nil")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"zstd;q=0, gzip;q=0.5", "gzip"},
+		{"GZIP", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.header); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"target":"192.0.2.1:53:do53-udp"}`, 100)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	// Each coding is used twice, the second time with a pooled encoder
+	for _, encoding := range []string{"gzip", "zstd", "gzip", "zstd"} {
+		r := httptest.NewRequest("GET", "/api/v1/results", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if got := rec.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Expected Content-Encoding %s, got %q", encoding, got)
+		}
+		var reader io.Reader
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("Invalid gzip response: %v", err)
+			}
+			reader = gz
+		} else {
+			zr, err := zstd.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("Invalid zstd response: %v", err)
+			}
+			defer zr.Close()
+			reader = zr
+		}
+		decoded, err := io.ReadAll(reader)
+		if err != nil || string(decoded) != body {
+			t.Errorf("Expected the %s body to decode to the original, got %d bytes, error %v", encoding, len(decoded), err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/results", nil))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+		t.Error("Expected an uncompressed response without Accept-Encoding")
+	}
+
+	encoded := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write([]byte("already compressed"))
+	}))
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	encoded.ServeHTTP(rec, r)
+	if rec.Body.String() != "already compressed" {
+		t.Errorf("Expected an encoded response to pass through, got %q", rec.Body.String())
+	}
+}
+
+// captureSink collects log messages
+type captureSink struct {
+	messages []string
+}
+
+func (c *captureSink) Write(level logging.Level, message string) {
+	c.messages = append(c.messages, message)
+}
+
+func TestAccessLog(t *testing.T) {
+	capture := &captureSink{}
+	logging.SetSink(capture)
+	defer logging.SetSink(nil)
+
+	enabled := true
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("forbidden"))
+	}), func() bool { return enabled })
+
+	r := httptest.NewRequest("POST", "/api/v1/pause", nil)
+	r.RemoteAddr = "192.0.2.7:51234"
+	r.SetBasicAuth("ops", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if len(capture.messages) != 1 {
+		t.Fatalf("Expected one access log line, got %v", capture.messages)
+	}
+	line := capture.messages[0]
+	for _, want := range []string{"client=192.0.2.7", `user="ops"`, "method=POST", `path="/api/v1/pause"`, "status=403", "bytes=9", "duration="} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %s in access log line %q", want, line)
+		}
+	}
+	if strings.Contains(line, "secret") {
+		t.Errorf("Expected no password in access log line %q", line)
+	}
+
+	enabled = false
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if len(capture.messages) != 1 {
+		t.Errorf("Expected no access log while disabled, got %v", capture.messages)
+	}
+}
+
+func TestAllowClients(t *testing.T) {
+	prefixes := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24"), netip.MustParsePrefix("2001:db8::/32")}
+	handler := AllowClients(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}), func() []netip.Prefix { return prefixes })
+
+	tests := []struct {
+		remote string
+		want   int
+	}{
+		{"192.0.2.7:51234", http.StatusOK},
+		{"[::ffff:192.0.2.7]:51234", http.StatusOK},
+		{"[2001:db8::1]:51234", http.StatusOK},
+		{"198.51.100.1:51234", http.StatusForbidden},
+		{"[2001:db9::1]:51234", http.StatusForbidden},
+		{"@", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		r.RemoteAddr = tt.remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("Client %s: expected status %d, got %d", tt.remote, tt.want, rec.Code)
+		}
+	}
+
+	prefixes = nil
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.RemoteAddr = "198.51.100.1:51234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected every client to be allowed without prefixes, got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// scrapeBIND reads the server statistics from the JSON statistics channel
+// of BIND at host:port, leaving out the per-view, per-zone, socket and
+// memory statistics of the full document, which grow with the server's
+// configuration. The nested counters are flattened into dotted names, e.g.
+// "nsstats.Requestv4"; values that are not numbers are left out.
+func scrapeBIND(ctx context.Context, address string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/json/v1/server", address), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+
+	var doc map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode statistics: %w", err)
+	}
+	stats := make(map[string]float64)
+	flatten(stats, "", doc)
+	return stats, nil
+}
+
+// flatten adds the numbers in v to stats under their dotted path
+func flatten(stats map[string]float64, prefix string, v any) {
+	switch v := v.(type) {
+	case float64:
+		stats[prefix] = v
+	case map[string]any:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(stats, key, child)
+		}
+	}
+}
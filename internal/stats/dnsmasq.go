@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// dnsmasqCounters are the CHAOS TXT names holding one number each
+var dnsmasqCounters = []string{
+	"cachesize.bind.", "insertions.bind.", "evictions.bind.", "misses.bind.", "hits.bind.", "auth.bind.",
+}
+
+// scrapeDnsmasq asks dnsmasq at host:port for its cache statistics, and
+// for the queries sent to and errors of each upstream server from
+// servers.bind, named e.g. "servers.9.9.9.9#53.queries"
+func scrapeDnsmasq(ctx context.Context, address string) (map[string]float64, error) {
+	client := &dns.Client{Net: "udp"}
+	stats := make(map[string]float64)
+	for _, name := range dnsmasqCounters {
+		txt, err := queryChaos(ctx, client, address, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(txt) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(txt[0], 64); err == nil {
+			stats[strings.TrimSuffix(name, ".bind.")] = v
+		}
+	}
+
+	txt, err := queryChaos(ctx, client, address, "servers.bind.")
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range txt {
+		fields := strings.Fields(server)
+		if len(fields) < 3 {
+			continue
+		}
+		for i, counter := range []string{"queries", "errors"} {
+			if v, err := strconv.ParseFloat(fields[i+1], 64); err == nil {
+				stats[fmt.Sprintf("servers.%s.%s", fields[0], counter)] = v
+			}
+		}
+	}
+	return stats, nil
+}
+
+// queryChaos returns the strings of the TXT records of a CHAOS class name
+func queryChaos(ctx context.Context, client *dns.Client, address, name string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, dns.TypeTXT)
+	msg.Question[0].Qclass = dns.ClassCHAOS
+	resp, _, err := client.ExchangeContext(ctx, msg, address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("%s: %s", name, dns.RcodeToString[resp.Rcode])
+	}
+	var txt []string
+	for _, rr := range resp.Answer {
+		if t, ok := rr.(*dns.TXT); ok {
+			txt = append(txt, t.Txt...)
+		}
+	}
+	return txt, nil
+}
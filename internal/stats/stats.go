@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+// Package stats reads the statistics that DNS server software exports
+// through its own channels: unbound's remote control, BIND's statistics
+// channel and dnsmasq's CHAOS records.
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// Scrape reads the statistics of a server running the given software at
+// address, keyed by the software's own names, e.g. "total.num.queries" for
+// unbound or "nsstats.Requestv4" for BIND
+func Scrape(ctx context.Context, software, address string) (map[string]float64, error) {
+	switch software {
+	case config.StatsUnbound:
+		return scrapeUnbound(ctx, address)
+	case config.StatsBIND:
+		return scrapeBIND(ctx, address)
+	case config.StatsDnsmasq:
+		return scrapeDnsmasq(ctx, address)
+	}
+	return nil, fmt.Errorf("unsupported software: %s", software)
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package stats
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// startUnbound serves one remote control connection on a Unix socket,
+// answering stats_noreset with reply
+func startUnbound(t *testing.T, reply string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "unbound.ctl")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("Cannot listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if line, _ := bufio.NewReader(conn).ReadString('\n'); line == "UBCT1 stats_noreset\n" {
+			_, _ = conn.Write([]byte(reply))
+		}
+	}()
+	return path
+}
+
+func TestScrapeUnbound(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	path := startUnbound(t, "thread0.num.queries=12\ntotal.num.queries=42\ntotal.requestlist.avg=0.5\n")
+	stats, err := Scrape(ctx, config.StatsUnbound, path)
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	if stats["total.num.queries"] != 42 || stats["total.requestlist.avg"] != 0.5 || len(stats) != 3 {
+		t.Errorf("Unexpected statistics: %v", stats)
+	}
+
+	path = startUnbound(t, "error command not allowed\n")
+	if _, err := Scrape(ctx, config.StatsUnbound, path); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected the control error, got %v", err)
+	}
+}
+
+func TestScrapeBIND(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json/v1/server" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"json-stats-version":"1.7","boot-time":"2026-01-01T00:00:00.000Z",
+			"opcodes":{"QUERY":7},"nsstats":{"Requestv4":3},"qtypes":{"A":5}}`))
+	}))
+	defer srv.Close()
+
+	stats, err := Scrape(context.Background(), config.StatsBIND, strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	want := map[string]float64{"opcodes.QUERY": 7, "nsstats.Requestv4": 3, "qtypes.A": 5}
+	if len(stats) != len(want) {
+		t.Errorf("Expected %v, got %v", want, stats)
+	}
+	for name, value := range want {
+		if stats[name] != value {
+			t.Errorf("Expected %s = %v, got %v", name, value, stats[name])
+		}
+	}
+}
+
+func TestScrapeDnsmasq(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS}
+		switch {
+		case q.Qclass != dns.ClassCHAOS:
+			resp.Rcode = dns.RcodeRefused
+		case q.Name == "servers.bind.":
+			resp.Answer = []dns.RR{
+				&dns.TXT{Hdr: hdr, Txt: []string{"9.9.9.9#53 10 1"}},
+				&dns.TXT{Hdr: hdr, Txt: []string{"1.1.1.1#53 5 0"}},
+			}
+		case q.Name == "hits.bind.":
+			resp.Answer = []dns.RR{&dns.TXT{Hdr: hdr, Txt: []string{"123"}}}
+		}
+		_ = w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	stats, err := Scrape(context.Background(), config.StatsDnsmasq, conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	want := map[string]float64{"hits": 123, "servers.9.9.9.9#53.queries": 10, "servers.9.9.9.9#53.errors": 1,
+		"servers.1.1.1.1#53.queries": 5, "servers.1.1.1.1#53.errors": 0}
+	if len(stats) != len(want) {
+		t.Errorf("Expected %v, got %v", want, stats)
+	}
+	for name, value := range want {
+		if v, ok := stats[name]; !ok || v != value {
+			t.Errorf("Expected %s = %v, got %v", name, value, stats[name])
+		}
+	}
+}
+
+func TestScrapeUnsupported(t *testing.T) {
+	if _, err := Scrape(context.Background(), "powerdns", "127.0.0.1:8081"); err == nil {
+		t.Error("Expected an error for unsupported software")
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package stats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// scrapeUnbound runs stats_noreset over unbound's remote control protocol,
+// which answers with one name=value line per statistic. Address is the
+// control-interface: a Unix socket path, or host:port of a TCP interface
+// with control-use-cert: no.
+func scrapeUnbound(ctx context.Context, address string) (map[string]float64, error) {
+	network := "tcp"
+	if filepath.IsAbs(address) {
+		network = "unix"
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if _, err := fmt.Fprint(conn, "UBCT1 stats_noreset\n"); err != nil {
+		return nil, err
+	}
+	stats := make(map[string]float64)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if msg, ok := strings.CutPrefix(line, "error "); ok {
+			return nil, fmt.Errorf("unbound: %s", msg)
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			stats[name] = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,1334 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v2"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// TLSConfig holds TLS-specific configuration for encrypted protocols
+type TLSConfig struct {
+	ServerName         string `yaml:"server_name" json:"server_name"`
+	InsecureSkipVerify *bool  `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+
+	// ALPN lists the application protocols offered by DoQ, "doq" when empty
+	ALPN StringList `yaml:"alpn,omitempty" json:"alpn,omitempty"`
+
+	// MinVersion and MaxVersion bound the negotiated TLS version ("1.0" to
+	// "1.3"); probes of servers outside the range fail in the handshake
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty"`
+	MaxVersion string `yaml:"max_version,omitempty" json:"max_version,omitempty"`
+
+	// SessionResumption lets new connections resume earlier TLS sessions
+	// instead of a full handshake
+	SessionResumption *bool `yaml:"session_resumption,omitempty" json:"session_resumption,omitempty"`
+}
+
+// SkipsVerification returns true if certificates of the server are not
+// verified
+func (t *TLSConfig) SkipsVerification() bool {
+	return t != nil && t.InsecureSkipVerify != nil && *t.InsecureSkipVerify
+}
+
+// ResumesSessions returns true if new connections resume earlier TLS
+// sessions
+func (t *TLSConfig) ResumesSessions() bool {
+	return t != nil && t.SessionResumption != nil && *t.SessionResumption
+}
+
+// tlsVersions maps configured TLS versions to their crypto/tls values
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSVersion returns the crypto/tls value of a configured TLS version, or
+// zero if it is empty or unknown
+func TLSVersion(version string) uint16 {
+	return tlsVersions[version]
+}
+
+// HTTPConfig customizes the requests of DoH and DoH3 servers, e.g. for
+// private endpoints requiring authentication
+type HTTPConfig struct {
+	Headers   map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	UserAgent string            `yaml:"user_agent,omitempty" json:"user_agent,omitempty"`
+}
+
+// StatsConfig selects the native statistics channel of a locally monitored
+// server. Address is the unbound remote control socket (a path or
+// host:port), the host:port of the BIND statistics channel, or for dnsmasq
+// where to send the CHAOS queries, which defaults to the server itself.
+// Include keeps only the statistics whose names start with one of its
+// prefixes.
+type StatsConfig struct {
+	Type    string     `yaml:"type" json:"type"`
+	Address string     `yaml:"address,omitempty" json:"address,omitempty"`
+	Include StringList `yaml:"include,omitempty" json:"include,omitempty"`
+}
+
+// TCPConfig holds socket options for protocols running over TCP. Unset
+// options keep the system defaults.
+type TCPConfig struct {
+	FastOpen          bool     `yaml:"fast_open" json:"fast_open"`
+	NoDelay           *bool    `yaml:"no_delay,omitempty" json:"no_delay,omitempty"`
+	KeepAlive         Duration `yaml:"keepalive,omitempty" json:"keepalive,omitempty"`
+	KeepAliveInterval Duration `yaml:"keepalive_interval,omitempty" json:"keepalive_interval,omitempty"`
+}
+
+// DNSServer represents a single DNS server configuration
+type DNSServer struct {
+	Address        string            `yaml:"address" json:"address"`
+	Port           string            `yaml:"port" json:"port"`
+	Protocol       string            `yaml:"protocol" json:"protocol"`
+	Timeout        int64             `yaml:"timeout" json:"timeout"`
+	ConnectTimeout int64             `yaml:"connect_timeout,omitempty" json:"connect_timeout,omitempty"`
+	QueryTimeout   int64             `yaml:"query_timeout,omitempty" json:"query_timeout,omitempty"`
+	Retries        *int              `yaml:"retries,omitempty" json:"retries,omitempty"`
+	TLS            *TLSConfig        `yaml:"tls,omitempty" json:"tls,omitempty"`
+	TCP            *TCPConfig        `yaml:"tcp,omitempty" json:"tcp,omitempty"`
+	Labels         map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Enabled        *bool             `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	QPS            float64           `yaml:"qps,omitempty" json:"qps,omitempty"`
+	Schedule       string            `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	Recursive      *bool             `yaml:"recursive,omitempty" json:"recursive,omitempty"`
+	Authoritative  bool              `yaml:"authoritative,omitempty" json:"authoritative,omitempty"`
+	Netns          string            `yaml:"netns,omitempty" json:"netns,omitempty"`
+	VRF            string            `yaml:"vrf,omitempty" json:"vrf,omitempty"`
+	ReuseSocket    bool              `yaml:"reuse_socket,omitempty" json:"reuse_socket,omitempty"`
+
+	// SourceAddress is the local address queries are sent from
+	SourceAddress string `yaml:"source_address,omitempty" json:"source_address,omitempty"`
+
+	// SourceAddresses fans the server out into one target per local
+	// address, labeled with SourceLabel
+	SourceAddresses StringList `yaml:"source_addresses,omitempty" json:"source_addresses,omitempty"`
+
+	// Protocols fans the server out into one target per protocol, each on
+	// the protocol's default port
+	Protocols StringList `yaml:"protocols,omitempty" json:"protocols,omitempty"`
+
+	// CompareTransports queries a Do53 server over both UDP and TCP back to
+	// back and exports the latency difference
+	CompareTransports bool `yaml:"compare_transports,omitempty" json:"compare_transports,omitempty"`
+
+	// DowngradeCheck also queries an encrypted server over Do53 to tell a
+	// blocked encrypted transport from a server that is down
+	DowngradeCheck bool `yaml:"downgrade_check,omitempty" json:"downgrade_check,omitempty"`
+
+	// AltSvc watches the Alt-Svc header of DoH responses for HTTP/3
+	// support, AltSvcDetect or AltSvcProbe; empty ignores it
+	AltSvc string `yaml:"alt_svc,omitempty" json:"alt_svc,omitempty"`
+
+	// CaptureHeaders names DoH response headers exported as info metrics,
+	// e.g. to tell which edge location answered
+	CaptureHeaders StringList `yaml:"capture_headers,omitempty" json:"capture_headers,omitempty"`
+
+	// HTTP adds request headers and sets the User-Agent of DoH requests
+	HTTP *HTTPConfig `yaml:"http,omitempty" json:"http,omitempty"`
+
+	// Stats also scrapes the server's own statistics and exports them
+	// alongside the probe metrics
+	Stats *StatsConfig `yaml:"stats,omitempty" json:"stats,omitempty"`
+
+	// Fingerprint identifies the server's software and version with
+	// version.bind, NSID and CHAOS queries
+	Fingerprint bool `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+
+	// Anycast tracks which instance of an anycast server answers, by its
+	// NSID or id.server record
+	Anycast bool `yaml:"anycast,omitempty" json:"anycast,omitempty"`
+
+	// View names the network view the server answers for in a
+	// split-horizon setup, e.g. "internal" or "external"
+	View string `yaml:"view,omitempty" json:"view,omitempty"`
+
+	// Upstreams records probe durations by the address that answered, for
+	// up to this many addresses of a host name target; 0 disables it
+	Upstreams int `yaml:"upstreams,omitempty" json:"upstreams,omitempty"`
+
+	// SLA sets latency thresholds whose breaches are counted
+	SLA *SLA `yaml:"sla,omitempty" json:"sla,omitempty"`
+
+	// Warmup sends an unrecorded query to the server at the start of each
+	// cycle, so that probes see warm caches and open connections
+	Warmup bool `yaml:"warmup,omitempty" json:"warmup,omitempty"`
+
+	// Faults injects latency and failures into the queries to the server,
+	// to test alert rules and dashboards in staging
+	Faults *Faults `yaml:"faults,omitempty" json:"faults,omitempty"`
+
+	location string // position in the config files, for error messages
+}
+
+// SLA holds the latency thresholds of a server. A probe that takes at least
+// a threshold, or fails, breaches it; zero disables a threshold.
+type SLA struct {
+	Warning  Duration `yaml:"warning,omitempty" json:"warning,omitempty"`
+	Critical Duration `yaml:"critical,omitempty" json:"critical,omitempty"`
+}
+
+// Faults selects the faults injected into the queries to a server. Every
+// query is delayed by a random duration of up to Latency, and fails with a
+// timeout or is answered with SERVFAIL with the given probabilities.
+type Faults struct {
+	Latency  Duration `yaml:"latency,omitempty" json:"latency,omitempty"`
+	Timeout  float64  `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Servfail float64  `yaml:"servfail,omitempty" json:"servfail,omitempty"`
+}
+
+// IsEnabled returns false if the server is parked with enabled: false
+func (s DNSServer) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// IsRecursive returns false if the exporter resolves iteratively itself,
+// starting at the server's address, instead of asking it to recurse
+func (s DNSServer) IsRecursive() bool {
+	return s.Recursive == nil || *s.Recursive
+}
+
+// RetryCount returns the number of times a failed query is retried
+func (s DNSServer) RetryCount() int {
+	if s.Retries == nil {
+		return 0
+	}
+	return *s.Retries
+}
+
+// Equal returns true if other is configured the same, wherever it was
+// listed
+func (s DNSServer) Equal(other DNSServer) bool {
+	s.location, other.location = "", ""
+	return reflect.DeepEqual(s, other)
+}
+
+// Key identifies the target: its address, port and protocol, and the
+// namespace, VRF and source address it is reached through, if any
+func (s DNSServer) Key() string {
+	key := fmt.Sprintf("%s:%s:%s", s.Address, s.Port, s.Protocol)
+	if s.Netns != "" || s.VRF != "" || s.SourceAddress != "" {
+		key += fmt.Sprintf(":%s:%s:%s", s.Netns, s.VRF, s.SourceAddress)
+	}
+	return key
+}
+
+// MaxProbes limits the queries per cycle of a domain to each server
+const MaxProbes = 100
+
+// Domain represents a domain to probe
+type Domain struct {
+	Name    string `yaml:"name" json:"name"`
+	Probes  *int   `yaml:"probes,omitempty" json:"probes,omitempty"`
+	Enabled *bool  `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+
+	// QType is the record type probe queries ask for, A when empty
+	QType string `yaml:"qtype,omitempty" json:"qtype,omitempty"`
+
+	// QueryTemplate places the random label of probe queries, e.g.
+	// "{rand}.probe.{name}"; "{rand}.{name}" when empty
+	QueryTemplate string `yaml:"query_template,omitempty" json:"query_template,omitempty"`
+
+	// DNSSEC is the expected validation status of the domain's answers,
+	// DNSSECSecure or DNSSECInsecure; empty disables the check
+	DNSSEC string `yaml:"dnssec,omitempty" json:"dnssec,omitempty"`
+
+	// Reference enables hijack detection against the expected answer
+	Reference *Reference `yaml:"reference,omitempty" json:"reference,omitempty"`
+
+	// Blocked expects servers to block the domain, e.g. with a response
+	// policy zone; nil disables the check
+	Blocked *Blocked `yaml:"blocked,omitempty" json:"blocked,omitempty"`
+
+	location string
+}
+
+// Reference is the expected answer of a domain for hijack detection: either
+// pinned addresses or the answer of a trusted resolver. Addresses are IPv6
+// for domains probed for AAAA records, IPv4 otherwise.
+type Reference struct {
+	Answers  StringList `yaml:"answers,omitempty" json:"answers,omitempty"`
+	Resolver *DNSServer `yaml:"resolver,omitempty" json:"resolver,omitempty"`
+
+	// Views pins the answers of split-horizon servers by their view, as
+	// addresses or a response code such as NXDOMAIN. Servers in other
+	// views, or in none, are held to Answers or Resolver, if set.
+	Views map[string]StringList `yaml:"views,omitempty" json:"views,omitempty"`
+}
+
+// Blocked is how servers answer for a blocked domain: with a response code
+// such as NXDOMAIN, or with the addresses of a walled garden. Without
+// either, any answer withholding routable addresses counts as blocked.
+type Blocked struct {
+	Rcode   string     `yaml:"rcode,omitempty" json:"rcode,omitempty"`
+	Answers StringList `yaml:"answers,omitempty" json:"answers,omitempty"`
+}
+
+// IsEnabled returns false if the domain is parked with enabled: false
+func (d Domain) IsEnabled() bool {
+	return d.Enabled == nil || *d.Enabled
+}
+
+// ProbeCount returns the number of queries per cycle of the domain to each
+// server
+func (d Domain) ProbeCount() int {
+	if d.Probes == nil {
+		return 1
+	}
+	return *d.Probes
+}
+
+// QueryType returns the record type of probe queries
+func (d Domain) QueryType() uint16 {
+	if qtype, ok := dns.StringToType[strings.ToUpper(d.QType)]; ok {
+		return qtype
+	}
+	return dns.TypeA
+}
+
+// ReferenceType returns the record type of reference checks: AAAA for
+// domains probed for AAAA records, A otherwise
+func (d Domain) ReferenceType() uint16 {
+	if d.QueryType() == dns.TypeAAAA {
+		return dns.TypeAAAA
+	}
+	return dns.TypeA
+}
+
+// referenceFamily returns the address family of the domain's reference
+// answers, which must match its reference type
+func (d Domain) referenceFamily() string {
+	if d.ReferenceType() == dns.TypeAAAA {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// isReferenceAnswer returns true if answer is an address of the domain's
+// reference family
+func (d Domain) isReferenceAnswer(answer string) bool {
+	addr, err := netip.ParseAddr(answer)
+	if err != nil {
+		return false
+	}
+	if d.ReferenceType() == dns.TypeAAAA {
+		return addr.Is6() && !addr.Is4In6()
+	}
+	return addr.Unmap().Is4()
+}
+
+// QueryName returns the name probed for the random label rand, following
+// the domain's query template
+func (d Domain) QueryName(rand string) string {
+	if d.QueryTemplate == "" {
+		return rand + "." + d.Name
+	}
+	return strings.NewReplacer("{rand}", rand, "{name}", d.Name).Replace(d.QueryTemplate)
+}
+
+// Defaults holds settings inherited by all servers and domains unless
+// overridden on the individual entry
+type Defaults struct {
+	Protocol string            `yaml:"protocol" json:"protocol"`
+	Timeout  int64             `yaml:"timeout" json:"timeout"`
+	Probes   int               `yaml:"probes" json:"probes"`
+	Retries  int               `yaml:"retries" json:"retries"`
+	TLS      *TLSConfig        `yaml:"tls,omitempty" json:"tls,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	QueryTemplate string `yaml:"query_template,omitempty" json:"query_template,omitempty"`
+
+	SLA *SLA `yaml:"sla,omitempty" json:"sla,omitempty"`
+}
+
+// StringList is a list of strings that may also be written as a single YAML scalar
+type StringList []string
+
+// UnmarshalYAML accepts either a single string or a list of strings
+func (s *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		if single != "" {
+			*s = StringList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// RateLimit caps the rate of queries sent by the prober
+type RateLimit struct {
+	QPS   float64 `yaml:"qps" json:"qps"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// LogLimit samples and caps the messages of one log level
+type LogLimit struct {
+	Every     int `yaml:"every,omitempty" json:"every,omitempty"`
+	PerMinute int `yaml:"per_minute,omitempty" json:"per_minute,omitempty"`
+}
+
+// LogRotation sets when the log file is rotated and which rotated files are
+// kept
+type LogRotation struct {
+	MaxSize    int      `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+	MaxAge     Duration `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+	MaxBackups int      `yaml:"max_backups,omitempty" json:"max_backups,omitempty"`
+	Compress   bool     `yaml:"compress,omitempty" json:"compress,omitempty"`
+}
+
+// Syslog sends logs to a local or remote syslog daemon
+type Syslog struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Address is "udp://host:port", "tcp://host:port" or the path of a
+	// local socket; the local daemon is used when empty
+	Address  string `yaml:"address,omitempty" json:"address,omitempty"`
+	Facility string `yaml:"facility" json:"facility"`
+	Tag      string `yaml:"tag" json:"tag"`
+}
+
+// LogSampling maps log level names to their limits
+type LogSampling map[string]LogLimit
+
+// GeoIP configures enrichment of answers with the country and autonomous
+// system of the returned addresses, from local MMDB files
+type GeoIP struct {
+	CountryDatabase string `yaml:"country_database,omitempty" json:"country_database,omitempty"`
+	ASNDatabase     string `yaml:"asn_database,omitempty" json:"asn_database,omitempty"`
+}
+
+// Enabled returns true if any database is configured
+func (g GeoIP) Enabled() bool {
+	return g.CountryDatabase != "" || g.ASNDatabase != ""
+}
+
+// Filtering configures detection of DNS filtering. Every cycle, each server
+// is asked for the test domains of every category, and a domain counts as
+// blocked when the server withholds an address that the reference
+// resolver returns.
+type Filtering struct {
+	Reference  DNSServer             `yaml:"reference" json:"reference"`
+	Categories map[string]StringList `yaml:"categories,omitempty" json:"categories,omitempty"`
+}
+
+// Enabled returns true if any category is configured
+func (f Filtering) Enabled() bool {
+	return len(f.Categories) > 0
+}
+
+// CategoryNames returns the configured categories in sorted order
+func (f Filtering) CategoryNames() []string {
+	names := make([]string, 0, len(f.Categories))
+	for name := range f.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LargeResponse configures a stress probe that asks every server for a
+// known large response, such as ANY or a big TXT record of a test zone, to
+// test EDNS, fragmentation and the fallback to TCP of truncated answers
+type LargeResponse struct {
+	// Name is the name asked for, and QType its record type
+	Name  string `yaml:"name,omitempty" json:"name,omitempty"`
+	QType string `yaml:"qtype" json:"qtype"`
+
+	// BufferSize is the UDP payload size advertised with EDNS
+	BufferSize int `yaml:"buffer_size" json:"buffer_size"`
+}
+
+// Enabled returns true if a name is configured
+func (l LargeResponse) Enabled() bool {
+	return l.Name != ""
+}
+
+// QueryType returns the record type asked for
+func (l LargeResponse) QueryType() uint16 {
+	return dns.StringToType[strings.ToUpper(l.QType)]
+}
+
+// Federation configures pulling the latest results of peer exporters at
+// other sites to compare each target across sites
+type Federation struct {
+	// Peers are the base URLs of the peer exporters
+	Peers StringList `yaml:"peers,omitempty" json:"peers,omitempty"`
+
+	// Interval is the time between pulls, the probe interval by default
+	Interval Duration `yaml:"interval" json:"interval"`
+}
+
+// Enabled returns true if any peer is configured
+func (f Federation) Enabled() bool {
+	return len(f.Peers) > 0
+}
+
+// OpenResolverScan configures periodically checking addresses for
+// recursion open to the exporter, as seen from its vantage point
+type OpenResolverScan struct {
+	// Targets are IP addresses and CIDR prefixes to check
+	Targets StringList `yaml:"targets,omitempty" json:"targets,omitempty"`
+
+	// Domain is the name asked for, which the checked servers must not be
+	// authoritative for
+	Domain string `yaml:"domain" json:"domain"`
+
+	// Interval is the time between scans, and Timeout bounds the query
+	// to each address
+	Interval Duration `yaml:"interval" json:"interval"`
+	Timeout  Duration `yaml:"timeout" json:"timeout"`
+}
+
+// Enabled returns true if any target is configured
+func (s OpenResolverScan) Enabled() bool {
+	return len(s.Targets) > 0
+}
+
+// MaxScanPrefixBits limits scanned CIDR prefixes to 2^16 addresses
+const MaxScanPrefixBits = 16
+
+// ExpandTargets returns the addresses of IP addresses and CIDR prefixes,
+// in order
+func ExpandTargets(targets []string) ([]string, error) {
+	var addrs []string
+	for _, target := range targets {
+		if addr, err := netip.ParseAddr(target); err == nil {
+			addrs = append(addrs, addr.String())
+			continue
+		}
+		prefix, err := netip.ParsePrefix(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target '%s' (expected IP address or CIDR prefix)", target)
+		}
+		if prefix.Addr().BitLen()-prefix.Bits() > MaxScanPrefixBits {
+			return nil, fmt.Errorf("prefix '%s' too large (at most /%d for IPv4, /%d for IPv6)",
+				target, 32-MaxScanPrefixBits, 128-MaxScanPrefixBits)
+		}
+		prefix = prefix.Masked()
+		for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+			addrs = append(addrs, addr.String())
+		}
+	}
+	return addrs, nil
+}
+
+// ClientPrefixes returns the allowed clients as prefixes, an address
+// standing for a prefix of its own length. Invalid entries, which
+// validation rejects, are skipped.
+func (c *Config) ClientPrefixes() []netip.Prefix {
+	return parsePrefixes(c.AllowedClients)
+}
+
+// parsePrefixes parses IP addresses and CIDR prefixes, skipping invalid
+// entries
+func parsePrefixes(list StringList) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, entry := range list {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+	return prefixes
+}
+
+// LeaderElection lets redundant replicas elect one that probes, while the
+// others stand by, through a lease in a shared lock file
+type LeaderElection struct {
+	LockFile string   `yaml:"lock_file,omitempty" json:"lock_file,omitempty"`
+	Lease    Duration `yaml:"lease" json:"lease"`
+
+	// Identity names this replica in the lock file, host name and process
+	// ID by default
+	Identity string `yaml:"identity,omitempty" json:"identity,omitempty"`
+}
+
+// Enabled returns true if a lock file is configured
+func (l LeaderElection) Enabled() bool {
+	return l.LockFile != ""
+}
+
+// Metrics selects the exported query metrics, which keeps cardinality under
+// control for very large target sets
+type Metrics struct {
+	// Disable lists metric families that are not exported
+	Disable StringList `yaml:"disable,omitempty" json:"disable,omitempty"`
+
+	// DurationType exports durations as histograms or summaries
+	DurationType string `yaml:"duration_type" json:"duration_type"`
+
+	// MaxSeries limits the number of recorded label combinations
+	MaxSeries int `yaml:"max_series" json:"max_series"`
+
+	// Shards splits the series of /metrics by server into this many
+	// shards, scraped separately with ?shard=N; 0 disables sharding
+	Shards int `yaml:"shards,omitempty" json:"shards,omitempty"`
+}
+
+// Config structure for YAML configuration file
+type Config struct {
+	Include         StringList     `yaml:"include" json:"include"`
+	DomainsFile     string         `yaml:"domains_file,omitempty" json:"domains_file,omitempty"`
+	Defaults        Defaults       `yaml:"defaults" json:"defaults"`
+	Domains         []Domain       `yaml:"domains" json:"domains"`
+	DNSServers      []DNSServer    `yaml:"dns_servers" json:"dns_servers"`
+	Listen          StringList     `yaml:"listen" json:"listen"`
+	ListenAddress   string         `yaml:"listen_addr" json:"listen_addr"`
+	ListenPort      string         `yaml:"listen_port" json:"listen_port"`
+	VerboseLogging  bool           `yaml:"verbose_logging" json:"verbose_logging"`
+	LogLevel        string         `yaml:"log_level" json:"log_level"`
+	LogSampling     LogSampling    `yaml:"log_sampling,omitempty" json:"log_sampling,omitempty"`
+	LogFile         string         `yaml:"log_file,omitempty" json:"log_file,omitempty"`
+	LogRotation     LogRotation    `yaml:"log_rotation,omitempty" json:"log_rotation,omitempty"`
+	Syslog          Syslog         `yaml:"syslog" json:"syslog"`
+	Timeout         int64          `yaml:"timeout" json:"timeout"`
+	Interval        Duration       `yaml:"interval" json:"interval"`
+	CycleDeadline   Duration       `yaml:"cycle_deadline" json:"cycle_deadline"`
+	IdleTimeout     Duration       `yaml:"idle_timeout" json:"idle_timeout"`
+	StartupRamp     int            `yaml:"startup_ramp" json:"startup_ramp"`
+	ErrorHistory    int            `yaml:"error_history" json:"error_history"`
+	ShutdownTimeout Duration       `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	RateLimit       RateLimit      `yaml:"rate_limit" json:"rate_limit"`
+	FailureLatency  string         `yaml:"failure_latency" json:"failure_latency"`
+	FirstProbe      string         `yaml:"first_probe" json:"first_probe"`
+	Presets         Presets        `yaml:"presets" json:"presets"`
+	GeoIP           GeoIP          `yaml:"geoip" json:"geoip"`
+	Filtering       Filtering      `yaml:"filtering" json:"filtering"`
+	LargeResponse   LargeResponse  `yaml:"large_response" json:"large_response"`
+	Metrics         Metrics        `yaml:"metrics" json:"metrics"`
+	Site            string         `yaml:"site" json:"site"`
+	Federation      Federation     `yaml:"federation" json:"federation"`
+	LeaderElection  LeaderElection `yaml:"leader_election" json:"leader_election"`
+	User            string         `yaml:"user,omitempty" json:"user,omitempty"`
+	Group           string         `yaml:"group,omitempty" json:"group,omitempty"`
+	Chroot          string         `yaml:"chroot,omitempty" json:"chroot,omitempty"`
+
+	OpenResolverScan OpenResolverScan `yaml:"open_resolver_scan" json:"open_resolver_scan"`
+
+	// AccessLog logs every HTTP request with its client, status, size and
+	// duration
+	AccessLog bool `yaml:"access_log,omitempty" json:"access_log,omitempty"`
+
+	// AllowedClients lists the IP addresses and CIDR prefixes that may use
+	// the HTTP server; empty allows every client
+	AllowedClients StringList `yaml:"allowed_clients,omitempty" json:"allowed_clients,omitempty"`
+
+	// API configures the management API
+	API API `yaml:"api" json:"api"`
+
+	// ProbeEndpoint configures the blackbox-style /probe endpoint
+	ProbeEndpoint ProbeEndpoint `yaml:"probe_endpoint" json:"probe_endpoint"`
+
+	// Duplicates sets how servers and domains listed more than once are
+	// handled: rejected as an error or merged into the first entry
+	Duplicates string `yaml:"duplicates" json:"duplicates"`
+}
+
+// API configures the management API
+type API struct {
+	// Token is the bearer token required by the endpoints that change
+	// probing; empty leaves them open to every allowed client
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+
+	// TokenFile is read for the token instead, e.g. a mounted secret
+	TokenFile string `yaml:"token_file,omitempty" json:"token_file,omitempty"`
+
+	// RateLimit caps the rate of the requests that send queries, and
+	// MaxConcurrent how many of them are served at the same time
+	RateLimit     RateLimit `yaml:"rate_limit" json:"rate_limit"`
+	MaxConcurrent int       `yaml:"max_concurrent" json:"max_concurrent"`
+}
+
+// ProbeEndpoint configures the /probe endpoint, which sends queries to any
+// target named in the request and so is disabled unless enabled here
+type ProbeEndpoint struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// AllowedTargets lists the IP addresses and CIDR prefixes /probe may
+	// send queries to, required when enabled
+	AllowedTargets StringList `yaml:"allowed_targets,omitempty" json:"allowed_targets,omitempty"`
+}
+
+// TargetPrefixes returns the allowed targets as prefixes, an address
+// becoming a prefix of its full length
+func (p ProbeEndpoint) TargetPrefixes() []netip.Prefix {
+	return parsePrefixes(p.AllowedTargets)
+}
+
+// Duration is a time.Duration read from YAML either as a Go duration
+// string ("30s", "5m") or as an integer number of milliseconds
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration string or a millisecond count
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ms int64
+	if err := unmarshal(&ms); err == nil {
+		*d = Duration(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// String formats the duration like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalYAML writes the duration as a Go duration string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON writes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// Overrides holds command-line values that take precedence over the config
+// file. Zero values leave the file's settings untouched.
+type Overrides struct {
+	ListenAddress string
+	ListenPort    string
+	Interval      time.Duration
+	Timeout       time.Duration
+	LogLevel      string
+}
+
+// ApplyOverrides replaces configured values with the non-zero overrides.
+// A timeout override applies to every server, and a listen address or port
+// override replaces the listen list.
+func (c *Config) ApplyOverrides(o Overrides) {
+	if o.ListenAddress != "" || o.ListenPort != "" {
+		c.Listen = nil
+	}
+	if o.ListenAddress != "" {
+		c.ListenAddress = o.ListenAddress
+	}
+	if o.ListenPort != "" {
+		c.ListenPort = o.ListenPort
+	}
+	if o.Interval > 0 {
+		c.Interval = Duration(o.Interval)
+	}
+	if o.Timeout > 0 {
+		c.Timeout = o.Timeout.Milliseconds()
+		for i := range c.DNSServers {
+			c.DNSServers[i].Timeout = c.Timeout
+		}
+	}
+	if o.LogLevel != "" {
+		c.LogLevel = o.LogLevel
+	}
+}
+
+// Supported DNS protocols
+const (
+	ProtocolDo53UDP = resolver.ProtocolDo53UDP
+	ProtocolDo53TCP = resolver.ProtocolDo53TCP
+	ProtocolDoT     = resolver.ProtocolDoT
+	ProtocolDoH     = resolver.ProtocolDoH
+	ProtocolDoH3    = resolver.ProtocolDoH3
+	ProtocolDoQ     = resolver.ProtocolDoQ
+)
+
+// ValidProtocols lists the built-in DNS protocols. Servers may also use any
+// protocol added with resolver.Register. Builds with the noquic tag lack
+// doh3 and doq (see resolver.Unsupported).
+var ValidProtocols = map[string]bool{
+	ProtocolDo53UDP: true,
+	ProtocolDo53TCP: true,
+	ProtocolDoT:     true,
+	ProtocolDoH:     true,
+	ProtocolDoH3:    true,
+	ProtocolDoQ:     true,
+}
+
+// IsEncryptedProtocol returns true if the protocol uses TLS/encryption
+func IsEncryptedProtocol(protocol string) bool {
+	return protocol == ProtocolDoT || protocol == ProtocolDoH ||
+		protocol == ProtocolDoH3 || protocol == ProtocolDoQ
+}
+
+// IsTCPProtocol returns true if the protocol runs over TCP
+func IsTCPProtocol(protocol string) bool {
+	return protocol == ProtocolDo53TCP || protocol == ProtocolDoT || protocol == ProtocolDoH
+}
+
+// IsQUICProtocol returns true if the protocol runs over QUIC, and so over
+// UDP
+func IsQUICProtocol(protocol string) bool {
+	return protocol == ProtocolDoH3 || protocol == ProtocolDoQ
+}
+
+// Metric types for durations
+const (
+	DurationTypeHistogram = "histogram"
+	DurationTypeSummary   = "summary"
+)
+
+// Policies for recording the duration of failed queries
+const (
+	// FailureLatencySeparate records failures in their own histogram
+	FailureLatencySeparate = "separate"
+	// FailureLatencyTimeout records failures at the full timeout of every
+	// attempt in the query duration histogram
+	FailureLatencyTimeout = "timeout"
+	// FailureLatencyOmit records no duration for failures
+	FailureLatencyOmit = "omit"
+)
+
+// Policies for recording the first of several probes of a domain in a cycle
+const (
+	// FirstProbeInclude records the first probe like the others
+	FirstProbeInclude = "include"
+	// FirstProbeSeparate records the first probe in its own histogram, so
+	// that cache misses and connection setup do not skew the others
+	FirstProbeSeparate = "separate"
+)
+
+// Handling of servers and domains listed more than once
+const (
+	// DuplicatesError rejects the configuration
+	DuplicatesError = "error"
+	// DuplicatesMerge keeps the first entry and ignores the others with a
+	// warning
+	DuplicatesMerge = "merge"
+)
+
+// Handling of Alt-Svc advertisements of DoH servers
+const (
+	// AltSvcDetect exports whether the server advertises HTTP/3
+	AltSvcDetect = "detect"
+	// AltSvcProbe also probes the server over DoH3 while it advertises
+	// HTTP/3 on its DoH port
+	AltSvcProbe = "probe"
+)
+
+// Server software whose statistics can be scraped
+const (
+	// StatsUnbound reads unbound's statistics over its remote control
+	StatsUnbound = "unbound"
+	// StatsBIND reads the JSON statistics channel of BIND
+	StatsBIND = "bind"
+	// StatsDnsmasq asks dnsmasq for its cache statistics with CHAOS TXT
+	// queries
+	StatsDnsmasq = "dnsmasq"
+)
+
+// Expected DNSSEC validation status of a domain
+const (
+	// DNSSECSecure expects validating resolvers to set the AD flag
+	DNSSECSecure = "secure"
+	// DNSSECInsecure expects answers without the AD flag
+	DNSSECInsecure = "insecure"
+)
+
+// Load reads YAML configuration from a file
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Dir(filename))
+}
+
+// Parse builds a configuration from YAML data. Relative include patterns
+// are resolved against baseDir.
+func Parse(data []byte, baseDir string) (*Config, error) {
+	return parse(data, baseDir, true)
+}
+
+// parse parses the configuration like Parse. Unless local, the document
+// may not refer to files, which would otherwise be read from the local
+// filesystem on behalf of whoever serves it.
+func parse(data []byte, baseDir string, local bool) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	config.setLocations("", 0, 0)
+	if !local {
+		if err := config.checkFileReferences(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.loadIncludes(baseDir); err != nil {
+		return nil, err
+	}
+	if err := config.loadDomainsFile(baseDir); err != nil {
+		return nil, err
+	}
+	if err := config.loadTokenFile(baseDir); err != nil {
+		return nil, err
+	}
+
+	config.expandPresets()
+	config.expandProtocols()
+	config.expandSourceAddresses()
+	config.applyDefaults()
+	if config.Duplicates == DuplicatesMerge {
+		config.mergeDuplicates()
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// fragment holds the subset of configuration allowed in an included file
+type fragment struct {
+	Domains    []Domain    `yaml:"domains"`
+	DNSServers []DNSServer `yaml:"dns_servers"`
+}
+
+// loadIncludes merges domains and servers from files matching the include
+// patterns. Relative patterns are resolved against baseDir and matching
+// files are merged in lexical order.
+func (c *Config) loadIncludes(baseDir string) error {
+	for _, pattern := range c.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+		for _, file := range matches {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read include %s: %w", file, err)
+			}
+			var frag fragment
+			if err := yaml.UnmarshalStrict(data, &frag); err != nil {
+				return fmt.Errorf("failed to parse include %s: %w", file, err)
+			}
+			domainStart, serverStart := len(c.Domains), len(c.DNSServers)
+			c.Domains = append(c.Domains, frag.Domains...)
+			c.DNSServers = append(c.DNSServers, frag.DNSServers...)
+			c.setLocations(file, domainStart, serverStart)
+		}
+	}
+	return nil
+}
+
+// loadDomainsFile appends the domains listed in the domains file, one per
+// line as "name[,qtype[,probes]]". Blank lines and lines starting with #
+// are skipped. A relative path is resolved against baseDir and replaced
+// with the resolved one, which is watched for changes.
+func (c *Config) loadDomainsFile(baseDir string) error {
+	if c.DomainsFile == "" {
+		return nil
+	}
+	if !filepath.IsAbs(c.DomainsFile) {
+		c.DomainsFile = filepath.Join(baseDir, c.DomainsFile)
+	}
+	data, err := os.ReadFile(c.DomainsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read domains file: %w", err)
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) > 3 {
+			return fmt.Errorf("%s: line %d: expected name[,qtype[,probes]]", c.DomainsFile, i+1)
+		}
+		domain := Domain{
+			Name:     strings.TrimSpace(fields[0]),
+			location: fmt.Sprintf("%s: line %d", c.DomainsFile, i+1),
+		}
+		if len(fields) > 1 {
+			domain.QType = strings.ToUpper(strings.TrimSpace(fields[1]))
+		}
+		if len(fields) > 2 {
+			if probes := strings.TrimSpace(fields[2]); probes != "" {
+				n, err := strconv.Atoi(probes)
+				if err != nil {
+					return fmt.Errorf("%s: line %d: invalid probes '%s'", c.DomainsFile, i+1, probes)
+				}
+				domain.Probes = &n
+			}
+		}
+		c.Domains = append(c.Domains, domain)
+	}
+	return nil
+}
+
+// loadTokenFile reads the API token from the token file, ignoring
+// surrounding whitespace. A relative path is resolved against baseDir.
+func (c *Config) loadTokenFile(baseDir string) error {
+	path := c.API.TokenFile
+	if path == "" {
+		return nil
+	}
+	if c.API.Token != "" {
+		return fmt.Errorf("api.token and api.token_file are mutually exclusive")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	c.API.TokenFile = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read API token: %w", err)
+	}
+	c.API.Token = strings.TrimSpace(string(data))
+	if c.API.Token == "" {
+		return fmt.Errorf("API token file %s is empty", path)
+	}
+	return nil
+}
+
+// setLocations records the YAML path of domains and servers added from
+// file, starting at the given indexes. Indexes in the path are relative to
+// the file the entries came from.
+func (c *Config) setLocations(file string, domainStart, serverStart int) {
+	prefix := ""
+	if file != "" {
+		prefix = file + ": "
+	}
+	for i := domainStart; i < len(c.Domains); i++ {
+		c.Domains[i].location = fmt.Sprintf("%sdomains[%d]", prefix, i-domainStart)
+	}
+	for i := serverStart; i < len(c.DNSServers); i++ {
+		c.DNSServers[i].location = fmt.Sprintf("%sdns_servers[%d]", prefix, i-serverStart)
+	}
+}
+
+// expandProtocols replaces every server listing protocols with one copy
+// per protocol, sharing the rest of its settings. Servers that also set
+// protocol or port are left for validation to reject.
+func (c *Config) expandProtocols() {
+	var servers []DNSServer
+	for i, server := range c.DNSServers {
+		if len(server.Protocols) == 0 || server.Protocol != "" || server.Port != "" {
+			servers = append(servers, server)
+			continue
+		}
+		for j, protocol := range server.Protocols {
+			s := server
+			s.Protocol = protocol
+			s.Protocols = nil
+			s.location = fmt.Sprintf("%s.protocols[%d]", server.path(i), j)
+			s.Labels = maps.Clone(server.Labels)
+			if server.TLS != nil {
+				tls := *server.TLS
+				s.TLS = &tls
+			}
+			if server.TCP != nil {
+				tcp := *server.TCP
+				s.TCP = &tcp
+			}
+			servers = append(servers, s)
+		}
+	}
+	c.DNSServers = servers
+}
+
+// SourceLabel is the label naming the local address of servers with
+// source_addresses
+const SourceLabel = "source"
+
+// expandSourceAddresses replaces every server listing source_addresses with
+// one copy per address, so that each uplink of a multi-homed host is probed
+// and labeled separately
+func (c *Config) expandSourceAddresses() {
+	var servers []DNSServer
+	for _, server := range c.DNSServers {
+		if len(server.SourceAddresses) == 0 {
+			servers = append(servers, server)
+			continue
+		}
+		for _, source := range server.SourceAddresses {
+			s := server
+			s.SourceAddress = source
+			s.SourceAddresses = nil
+			s.Labels = maps.Clone(server.Labels)
+			if s.Labels == nil {
+				s.Labels = make(map[string]string)
+			}
+			s.Labels[SourceLabel] = source
+			servers = append(servers, s)
+		}
+	}
+	c.DNSServers = servers
+}
+
+// applyDefaults sets default values for optional fields. Values from the
+// defaults block take precedence over built-in defaults, and the global
+// timeout applies to servers when neither they nor the defaults set one.
+// Servers without any timeout get their protocol's default.
+func (c *Config) applyDefaults() {
+	if c.Interval == 0 {
+		c.Interval = Duration(30 * time.Second)
+	}
+	if c.Syslog.Facility == "" {
+		c.Syslog.Facility = "daemon"
+	}
+	if c.Syslog.Tag == "" {
+		c.Syslog.Tag = "dnspulse_exporter"
+	}
+	if c.ErrorHistory == 0 {
+		c.ErrorHistory = 20
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = Duration(10 * time.Second)
+	}
+	if c.FailureLatency == "" {
+		c.FailureLatency = FailureLatencySeparate
+	}
+	if c.Duplicates == "" {
+		c.Duplicates = DuplicatesError
+	}
+	if c.FirstProbe == "" {
+		c.FirstProbe = FirstProbeInclude
+	}
+	if c.API.RateLimit.QPS == 0 {
+		c.API.RateLimit.QPS = 1
+	}
+	if c.API.RateLimit.Burst == 0 {
+		c.API.RateLimit.Burst = 5
+	}
+	if c.API.MaxConcurrent == 0 {
+		c.API.MaxConcurrent = 2
+	}
+	if c.Metrics.DurationType == "" {
+		c.Metrics.DurationType = DurationTypeHistogram
+	}
+	if c.Site == "" {
+		c.Site, _ = os.Hostname()
+	}
+	if c.Federation.Enabled() && c.Federation.Interval == 0 {
+		c.Federation.Interval = c.Interval
+	}
+	if c.LargeResponse.Enabled() {
+		if c.LargeResponse.QType == "" {
+			c.LargeResponse.QType = "ANY"
+		}
+		if c.LargeResponse.BufferSize == 0 {
+			c.LargeResponse.BufferSize = 1232
+		}
+	}
+	if c.OpenResolverScan.Enabled() {
+		if c.OpenResolverScan.Domain == "" {
+			c.OpenResolverScan.Domain = "example.com"
+		}
+		if c.OpenResolverScan.Interval == 0 {
+			c.OpenResolverScan.Interval = Duration(time.Hour)
+		}
+		if c.OpenResolverScan.Timeout == 0 {
+			c.OpenResolverScan.Timeout = Duration(2 * time.Second)
+		}
+	}
+	if c.LeaderElection.Enabled() {
+		if c.LeaderElection.Lease == 0 {
+			c.LeaderElection.Lease = Duration(15 * time.Second)
+		}
+		if c.LeaderElection.Identity == "" {
+			hostname, _ := os.Hostname()
+			c.LeaderElection.Identity = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = "info"
+		if c.VerboseLogging {
+			c.LogLevel = "debug"
+		}
+	}
+
+	d := c.Defaults
+	if d.Protocol == "" {
+		d.Protocol = ProtocolDo53UDP
+	}
+	if d.Timeout == 0 {
+		d.Timeout = c.Timeout
+	}
+	if d.Probes == 0 {
+		d.Probes = 1
+	}
+
+	for i := range c.DNSServers {
+		server := &c.DNSServers[i]
+		if !server.IsRecursive() && server.Address == "" {
+			server.Address = resolver.RootHints
+		}
+		if server.Protocol == "" {
+			server.Protocol = d.Protocol
+		}
+		if server.Port == "" {
+			server.Port = resolver.DefaultPort(server.Protocol)
+		}
+		if server.Timeout == 0 {
+			server.Timeout = d.Timeout
+		}
+		if server.Timeout == 0 {
+			server.Timeout = DefaultTimeout(server.Protocol).Milliseconds()
+		}
+		if server.Retries == nil {
+			retries := d.Retries
+			server.Retries = &retries
+		}
+		if d.TLS != nil && IsEncryptedProtocol(server.Protocol) {
+			if server.TLS == nil {
+				server.TLS = &TLSConfig{}
+			}
+			server.TLS.inherit(*d.TLS, server.Protocol)
+		}
+		if server.SLA == nil && d.SLA != nil {
+			sla := *d.SLA
+			server.SLA = &sla
+		}
+		if len(d.Labels) > 0 {
+			labels := make(map[string]string, len(d.Labels)+len(server.Labels))
+			for k, v := range d.Labels {
+				labels[k] = v
+			}
+			for k, v := range server.Labels {
+				labels[k] = v
+			}
+			server.Labels = labels
+		}
+	}
+
+	if c.Filtering.Enabled() {
+		c.Filtering.Reference.applyReferenceDefaults()
+	}
+
+	for i := range c.Domains {
+		if ref := c.Domains[i].Reference; ref != nil && ref.Resolver != nil {
+			ref.Resolver.applyReferenceDefaults()
+		}
+		if c.Domains[i].Probes == nil {
+			probes := d.Probes
+			c.Domains[i].Probes = &probes
+		}
+		if c.Domains[i].QueryTemplate == "" {
+			c.Domains[i].QueryTemplate = d.QueryTemplate
+		}
+	}
+}
+
+// ListenAddresses returns the addresses the metrics server binds to: the
+// listen list if set, otherwise listen_addr and listen_port
+func (c *Config) ListenAddresses() []string {
+	if len(c.Listen) > 0 {
+		return c.Listen
+	}
+	addr := c.ListenAddress
+	if addr == "*" {
+		addr = ""
+	}
+	return []string{net.JoinHostPort(addr, c.ListenPort)}
+}
+
+// inherit fills in the settings the server leaves unset from the default
+// TLS settings d, skipping those its protocol does not accept: ALPN,
+// which only DoQ lets servers choose, and versions below TLS 1.3 for QUIC
+func (t *TLSConfig) inherit(d TLSConfig, protocol string) {
+	if t.ServerName == "" {
+		t.ServerName = d.ServerName
+	}
+	if t.InsecureSkipVerify == nil {
+		t.InsecureSkipVerify = clone(d.InsecureSkipVerify)
+	}
+	if t.SessionResumption == nil {
+		t.SessionResumption = clone(d.SessionResumption)
+	}
+	if len(t.ALPN) == 0 && protocol == ProtocolDoQ {
+		t.ALPN = slices.Clone(d.ALPN)
+	}
+	if t.MinVersion == "" {
+		t.MinVersion = d.MinVersion
+	}
+	if t.MaxVersion == "" && (d.MaxVersion == "1.3" || !IsQUICProtocol(protocol)) {
+		t.MaxVersion = d.MaxVersion
+	}
+}
+
+// applyReferenceDefaults fills in the protocol, port and timeout of a
+// reference resolver, which does not inherit the server defaults
+func (s *DNSServer) applyReferenceDefaults() {
+	if s.Protocol == "" {
+		s.Protocol = ProtocolDo53UDP
+	}
+	if s.Port == "" {
+		s.Port = resolver.DefaultPort(s.Protocol)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultTimeout(s.Protocol).Milliseconds()
+	}
+}
+
+// LabelNames returns the sorted set of custom label names used by any server
+func (c *Config) LabelNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, server := range c.DNSServers {
+		for name := range server.Labels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultTimeout returns the query timeout used for a protocol when no
+// timeout is configured. QUIC-based protocols get more time because cold
+// handshakes are slower, and a single default would bias their failure rates.
+func DefaultTimeout(protocol string) time.Duration {
+	switch protocol {
+	case ProtocolDo53UDP, ProtocolDo53TCP:
+		return 2 * time.Second
+	case ProtocolDoT, ProtocolDoH:
+		return 3 * time.Second
+	case ProtocolDoH3, ProtocolDoQ:
+		return 5 * time.Second
+	default:
+		return 2 * time.Second
+	}
+}
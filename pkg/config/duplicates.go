@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// domainKey identifies a domain by its name, which is all its metrics are
+// labelled with
+func domainKey(d Domain) string {
+	return dns.CanonicalName(d.Name)
+}
+
+// mergeDuplicates keeps the first of the servers with the same key and of
+// the domains with the same name, and drops the others with a warning
+func (c *Config) mergeDuplicates() {
+	seenServers := make(map[string]string)
+	servers := c.DNSServers[:0]
+	for i, server := range c.DNSServers {
+		key := server.Key()
+		if first, ok := seenServers[key]; ok {
+			logging.Warnf("Ignoring %s, a duplicate of %s", server.path(i), first)
+			continue
+		}
+		seenServers[key] = server.path(i)
+		servers = append(servers, server)
+	}
+	c.DNSServers = servers
+
+	seenDomains := make(map[string]string)
+	domains := c.Domains[:0]
+	for i, domain := range c.Domains {
+		key := domainKey(domain)
+		if first, ok := seenDomains[key]; ok {
+			logging.Warnf("Ignoring %s, a duplicate of %s", domain.path(i), first)
+			continue
+		}
+		seenDomains[key] = domain.path(i)
+		domains = append(domains, domain)
+	}
+	c.Domains = domains
+}
+
+// validateDuplicates records a problem for every server and domain that
+// repeats an earlier one. Entries missing their address or name are
+// reported on their own.
+func (c *Config) validateDuplicates(verr *ValidationError) {
+	seenServers := make(map[string]string)
+	for i, server := range c.DNSServers {
+		if server.Address == "" {
+			continue
+		}
+		key := server.Key()
+		if first, ok := seenServers[key]; ok {
+			verr.addf(server.path(i), "duplicate of %s (same address, port and protocol)", first)
+			continue
+		}
+		seenServers[key] = server.path(i)
+	}
+
+	seenDomains := make(map[string]string)
+	for i, domain := range c.Domains {
+		if domain.Name == "" {
+			continue
+		}
+		key := domainKey(domain)
+		if first, ok := seenDomains[key]; ok {
+			verr.addf(domain.path(i), "duplicate of %s (same name)", first)
+			continue
+		}
+		seenDomains[key] = domain.path(i)
+	}
+}
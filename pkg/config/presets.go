@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// Presets enables built-in target sets that expand into servers
+type Presets struct {
+	// RootServers probes all 13 root server identities
+	RootServers bool `yaml:"root_servers" json:"root_servers"`
+
+	// TLDs probes the name servers of the listed top-level domains
+	TLDs StringList `yaml:"tlds,omitempty" json:"tlds,omitempty"`
+}
+
+// NameserverLabel is the label naming the target of preset servers
+const NameserverLabel = "nameserver"
+
+// presetLookupTimeout bounds looking up the name servers of a TLD preset
+const presetLookupTimeout = 10 * time.Second
+
+// lookupNameServers finds the name servers of a TLD; replaced in tests
+var lookupNameServers = func(zone string) ([]resolver.NameServer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), presetLookupTimeout)
+	defer cancel()
+	return resolver.LookupNameServers(ctx, zone, resolver.Timeouts{Total: presetLookupTimeout})
+}
+
+// expandPresets appends the servers of the enabled presets. The name
+// servers of a TLD are looked up at load time; if the lookup fails the TLD
+// is skipped with a warning, so that an outage does not prevent the
+// exporter from starting.
+func (c *Config) expandPresets() {
+	if c.Presets.RootServers {
+		var roots []resolver.NameServer
+		for _, root := range resolver.RootServers {
+			roots = append(roots, resolver.NameServer{Name: root.Name + ".", Address: root.IPv4})
+		}
+		c.DNSServers = append(c.DNSServers, presetServers(roots, "presets.root_servers")...)
+	}
+
+	for i, tld := range c.Presets.TLDs {
+		nameservers, err := lookupNameServers(tld)
+		if err != nil {
+			logging.Warnf("Skipping TLD preset %s: %v", tld, err)
+			continue
+		}
+		c.DNSServers = append(c.DNSServers, presetServers(nameservers, fmt.Sprintf("presets.tlds[%d]", i))...)
+	}
+}
+
+// presetServers returns an authoritative server over UDP and over TCP for
+// every name server, labeled with the name server's name
+func presetServers(nameservers []resolver.NameServer, location string) []DNSServer {
+	var servers []DNSServer
+	for _, ns := range nameservers {
+		for _, protocol := range []string{ProtocolDo53UDP, ProtocolDo53TCP} {
+			servers = append(servers, DNSServer{
+				Address:       ns.Address,
+				Protocol:      protocol,
+				Authoritative: true,
+				Labels:        map[string]string{NameserverLabel: strings.TrimSuffix(ns.Name, ".")},
+				location:      location,
+			})
+		}
+	}
+	return servers
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+func TestRootServersPreset(t *testing.T) {
+	config, err := Parse([]byte("presets:\n  root_servers: true\n"), ".")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(config.DNSServers) != 26 {
+		t.Fatalf("Expected 26 root server targets, got %d", len(config.DNSServers))
+	}
+
+	first := config.DNSServers[0]
+	if first.Address != "198.41.0.4" || first.Protocol != ProtocolDo53UDP || first.Port != "53" {
+		t.Errorf("Unexpected first target %s:%s (%s)", first.Address, first.Port, first.Protocol)
+	}
+	if config.DNSServers[1].Protocol != ProtocolDo53TCP {
+		t.Errorf("Expected each root server over TCP too, got %s", config.DNSServers[1].Protocol)
+	}
+	if !first.Authoritative {
+		t.Error("Expected root server targets to be authoritative")
+	}
+	if first.Labels[NameserverLabel] != "a.root-servers.net" {
+		t.Errorf("Expected nameserver label a.root-servers.net, got '%s'", first.Labels[NameserverLabel])
+	}
+}
+
+func TestTLDPreset(t *testing.T) {
+	orig := lookupNameServers
+	defer func() { lookupNameServers = orig }()
+	lookupNameServers = func(zone string) ([]resolver.NameServer, error) {
+		if zone != "com" {
+			return nil, errors.New("lookup failed")
+		}
+		return []resolver.NameServer{{Name: "a.gtld-servers.net.", Address: "192.5.6.30"}}, nil
+	}
+
+	config, err := Parse([]byte("presets:\n  tlds: [com, broken]\n"), ".")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(config.DNSServers) != 2 {
+		t.Fatalf("Expected 2 targets for com and none for the failed lookup, got %d", len(config.DNSServers))
+	}
+	if config.DNSServers[0].Address != "192.5.6.30" || config.DNSServers[0].Labels[NameserverLabel] != "a.gtld-servers.net" {
+		t.Errorf("Unexpected TLD target %+v", config.DNSServers[0])
+	}
+}
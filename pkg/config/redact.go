@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+)
+
+// redacted replaces secret values
+const redacted = "xxxxx"
+
+// Redacted returns a deep copy of the configuration with secret values
+// replaced, suitable for exposing over the API or printing
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Include = slices.Clone(c.Include)
+	out.Listen = slices.Clone(c.Listen)
+	out.Defaults.TLS = cloneTLS(c.Defaults.TLS)
+	out.Defaults.Labels = maps.Clone(c.Defaults.Labels)
+	out.Domains = slices.Clone(c.Domains)
+	for i, domain := range out.Domains {
+		out.Domains[i].Enabled = clone(domain.Enabled)
+		out.Domains[i].Blocked = clone(domain.Blocked)
+		out.Domains[i].Probes = clone(domain.Probes)
+		if ref := domain.Reference; ref != nil {
+			clone := *ref
+			clone.Answers = slices.Clone(ref.Answers)
+			clone.Views = maps.Clone(ref.Views)
+			if ref.Resolver != nil {
+				resolver := ref.Resolver.redacted()
+				clone.Resolver = &resolver
+			}
+			out.Domains[i].Reference = &clone
+		}
+	}
+
+	out.DNSServers = make([]DNSServer, len(c.DNSServers))
+	for i, server := range c.DNSServers {
+		out.DNSServers[i] = server.redacted()
+	}
+	out.Filtering.Reference = c.Filtering.Reference.redacted()
+	out.Filtering.Categories = maps.Clone(c.Filtering.Categories)
+
+	// Peer URLs may carry basic auth credentials
+	out.Federation.Peers = slices.Clone(c.Federation.Peers)
+	for i, peer := range out.Federation.Peers {
+		if u, err := url.Parse(peer); err == nil {
+			out.Federation.Peers[i] = u.Redacted()
+		}
+	}
+
+	if out.API.Token != "" {
+		out.API.Token = redacted
+	}
+	return &out
+}
+
+// redacted returns a copy of the server that shares nothing with it, with
+// the values of HTTP headers, which usually carry credentials, replaced
+func (s DNSServer) redacted() DNSServer {
+	s.TLS = cloneTLS(s.TLS)
+	if s.TCP != nil {
+		s.TCP = clone(s.TCP)
+		s.TCP.NoDelay = clone(s.TCP.NoDelay)
+	}
+	s.Labels = maps.Clone(s.Labels)
+	s.Enabled = clone(s.Enabled)
+	s.Recursive = clone(s.Recursive)
+	s.Retries = clone(s.Retries)
+	if s.Stats != nil {
+		s.Stats = clone(s.Stats)
+		s.Stats.Include = slices.Clone(s.Stats.Include)
+	}
+	s.SLA = clone(s.SLA)
+	s.Faults = clone(s.Faults)
+	if s.HTTP != nil {
+		http := *s.HTTP
+		http.Headers = make(map[string]string, len(s.HTTP.Headers))
+		for name := range s.HTTP.Headers {
+			http.Headers[name] = redacted
+		}
+		s.HTTP = &http
+	}
+	return s
+}
+
+// cloneTLS copies a TLS config, preserving nil
+func cloneTLS(t *TLSConfig) *TLSConfig {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.ALPN = slices.Clone(t.ALPN)
+	c.InsecureSkipVerify = clone(t.InsecureSkipVerify)
+	c.SessionResumption = clone(t.SessionResumption)
+	return &c
+}
+
+// clone copies the value p points to, preserving nil
+func clone[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRemoteSize limits the size of remote configuration documents, which
+// are read into memory in full
+const maxRemoteSize = 8 << 20
+
+// IsRemote returns true if the config location is an HTTP(S) URL
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// RemoteSource fetches configuration from an HTTP(S) URL. It remembers the
+// last ETag and body so that unchanged documents are not reapplied. It is
+// safe for concurrent use.
+type RemoteSource struct {
+	url         string
+	headerName  string
+	headerValue string
+	client      *http.Client
+
+	mu   sync.Mutex
+	etag string
+	last []byte
+}
+
+// NewRemoteSource creates a remote config source. authHeader is an optional
+// "Name: value" header sent with every request (e.g. "Authorization: Bearer xyz").
+func NewRemoteSource(url, authHeader string) (*RemoteSource, error) {
+	s := &RemoteSource{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if authHeader != "" {
+		name, value, ok := strings.Cut(authHeader, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid auth header: expected 'Name: value'")
+		}
+		s.headerName = strings.TrimSpace(name)
+		s.headerValue = strings.TrimSpace(value)
+	}
+	return s, nil
+}
+
+// Fetch retrieves and parses the remote configuration. It returns a nil
+// Config and nil error when the document has not changed since the last
+// successful fetch.
+func (s *RemoteSource) Fetch(ctx context.Context) (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config request: %w", err)
+	}
+	if s.headerName != "" {
+		req.Header.Set(s.headerName, s.headerValue)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config: HTTP status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(data) > maxRemoteSize {
+		return nil, fmt.Errorf("failed to read config: document exceeds %d bytes", maxRemoteSize)
+	}
+	if s.last != nil && bytes.Equal(data, s.last) {
+		return nil, nil
+	}
+
+	cfg, err := parse(data, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	s.last = data
+	return cfg, nil
+}
+
+// checkFileReferences returns an error if the configuration refers to
+// local files, which remote documents may not
+func (c *Config) checkFileReferences() error {
+	verr := &ValidationError{}
+	if len(c.Include) > 0 {
+		verr.addf("include", "is not allowed in a remote configuration")
+	}
+	if c.DomainsFile != "" {
+		verr.addf("domains_file", "is not allowed in a remote configuration")
+	}
+	if c.API.TokenFile != "" {
+		verr.addf("api.token_file", "is not allowed in a remote configuration")
+	}
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
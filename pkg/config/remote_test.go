@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const remoteConfig = `
+listen_addr: "127.0.0.1"
+listen_port: "9953"
+domains:
+  - name: "example.com"
+    probes: 1
+dns_servers:
+  - address: "8.8.8.8"
+`
+
+func TestIsRemote(t *testing.T) {
+	tests := map[string]bool{
+		"https://configserver/dnspulse.yml": true,
+		"http://configserver/dnspulse.yml":  true,
+		"/etc/dnspulse.yml":                 false,
+		"dnspulse.yml":                      false,
+	}
+	for location, expected := range tests {
+		if IsRemote(location) != expected {
+			t.Errorf("IsRemote(%q): expected %v", location, expected)
+		}
+	}
+}
+
+func TestRemoteSourceFetch(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(remoteConfig))
+	}))
+	defer server.Close()
+
+	t.Run("fetches and caches by etag", func(t *testing.T) {
+		src, err := NewRemoteSource(server.URL, "Authorization: Bearer secret")
+		if err != nil {
+			t.Fatalf("NewRemoteSource failed: %v", err)
+		}
+
+		cfg, err := src.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if cfg == nil || len(cfg.DNSServers) != 1 {
+			t.Fatalf("Expected config with 1 DNS server, got %+v", cfg)
+		}
+
+		cfg, err = src.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("Second fetch failed: %v", err)
+		}
+		if cfg != nil {
+			t.Error("Expected nil config for unchanged document")
+		}
+	})
+
+	t.Run("missing auth", func(t *testing.T) {
+		src, err := NewRemoteSource(server.URL, "")
+		if err != nil {
+			t.Fatalf("NewRemoteSource failed: %v", err)
+		}
+		if _, err := src.Fetch(context.Background()); err == nil {
+			t.Error("Expected error for unauthorized fetch, got nil")
+		}
+	})
+
+	t.Run("invalid auth header", func(t *testing.T) {
+		if _, err := NewRemoteSource(server.URL, "no-colon"); err == nil {
+			t.Error("Expected error for malformed auth header, got nil")
+		}
+	})
+}
+
+func TestRemoteFileReferences(t *testing.T) {
+	for _, reference := range []string{
+		"include: [\"/etc/dnspulse/*.yml\"]",
+		"domains_file: /etc/passwd",
+		"api:\n  enabled: true\n  token_file: /etc/shadow",
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(remoteConfig + reference + "\n"))
+		}))
+		src, err := NewRemoteSource(server.URL, "")
+		if err != nil {
+			t.Fatalf("NewRemoteSource failed: %v", err)
+		}
+		_, err = src.Fetch(context.Background())
+		server.Close()
+		if err == nil || !strings.Contains(err.Error(), "is not allowed in a remote configuration") {
+			t.Errorf("Expected %q to be rejected in a remote configuration, got %v", reference, err)
+		}
+	}
+}
+
+func TestRemoteSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remoteConfig))
+		_, _ = w.Write([]byte("# " + strings.Repeat("x", maxRemoteSize) + "\n"))
+	}))
+	defer server.Close()
+
+	src, err := NewRemoteSource(server.URL, "")
+	if err != nil {
+		t.Fatalf("NewRemoteSource failed: %v", err)
+	}
+	if _, err := src.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected error for an oversized document, got %v", err)
+	}
+}
@@ -0,0 +1,670 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/robfig/cron/v3"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// ValidationError lists every problem found in a configuration, each
+// prefixed with the YAML path of the offending field
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// addf records a problem at the given YAML path
+func (e *ValidationError) addf(path, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// labelNamePattern matches valid Prometheus label names
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// headerNamePattern matches valid HTTP header names (RFC 9110 tokens)
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// reservedHeaders are DoH request headers set by the exporter itself
+var reservedHeaders = map[string]bool{
+	"host":           true,
+	"content-type":   true,
+	"content-length": true,
+	"accept":         true,
+	"user-agent":     true,
+}
+
+// maxInterfaceName is the longest network device name Linux accepts
+const maxInterfaceName = 15
+
+// reservedLabels are label names used by the exporter itself
+var reservedLabels = map[string]bool{
+	"domain":   true,
+	"server":   true,
+	"protocol": true,
+	"zone":     true,
+	"country":  true,
+	"asn":      true,
+	"category": true,
+	"site":     true,
+}
+
+// validate checks the configuration for errors and fills in TLS server
+// names for encrypted protocols. All problems are reported together.
+func (c *Config) validate() error {
+	verr := &ValidationError{}
+
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		verr.addf("log_level", "%v", err)
+	}
+	if c.LogRotation.MaxSize < 0 {
+		verr.addf("log_rotation.max_size", "must not be negative")
+	}
+	if c.LogRotation.MaxAge < 0 {
+		verr.addf("log_rotation.max_age", "must not be negative")
+	}
+	if c.LogRotation.MaxBackups < 0 {
+		verr.addf("log_rotation.max_backups", "must not be negative")
+	}
+	if c.Syslog.Enabled {
+		if runtime.GOOS == "windows" {
+			verr.addf("syslog", "is not supported on Windows")
+		}
+		if c.LogFile != "" {
+			verr.addf("syslog", "cannot be combined with log_file")
+		}
+		if _, err := logging.ParseFacility(c.Syslog.Facility); err != nil {
+			verr.addf("syslog.facility", "%v", err)
+		}
+		if addr := c.Syslog.Address; strings.HasPrefix(addr, "udp://") || strings.HasPrefix(addr, "tcp://") {
+			if _, _, err := net.SplitHostPort(addr[len("udp://"):]); err != nil {
+				verr.addf("syslog.address", "%v", err)
+			}
+		} else if addr != "" && !filepath.IsAbs(addr) {
+			verr.addf("syslog.address", "must be udp://host:port, tcp://host:port or an absolute socket path")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.LogSampling)) {
+		path := "log_sampling." + name
+		if _, err := logging.ParseLevel(name); err != nil {
+			verr.addf(path, "%v", err)
+		}
+		limit := c.LogSampling[name]
+		if limit.Every < 0 {
+			verr.addf(path+".every", "must not be negative")
+		}
+		if limit.PerMinute < 0 {
+			verr.addf(path+".per_minute", "must not be negative")
+		}
+	}
+
+	switch c.FailureLatency {
+	case "", FailureLatencySeparate, FailureLatencyTimeout, FailureLatencyOmit:
+	default:
+		verr.addf("failure_latency", "invalid policy '%s' (expected separate, timeout or omit)", c.FailureLatency)
+	}
+	switch c.FirstProbe {
+	case "", FirstProbeInclude, FirstProbeSeparate:
+	default:
+		verr.addf("first_probe", "invalid policy '%s' (expected include or separate)", c.FirstProbe)
+	}
+	switch c.Duplicates {
+	case "", DuplicatesError, DuplicatesMerge:
+	default:
+		verr.addf("duplicates", "invalid policy '%s' (expected error or merge)", c.Duplicates)
+	}
+
+	switch c.Metrics.DurationType {
+	case "", DurationTypeHistogram, DurationTypeSummary:
+	default:
+		verr.addf("metrics.duration_type", "invalid type '%s' (expected histogram or summary)", c.Metrics.DurationType)
+	}
+	for _, family := range c.Metrics.Disable {
+		if !slices.Contains(metrics.Families, family) {
+			verr.addf("metrics.disable", "unknown metric family '%s'", family)
+		}
+	}
+	if c.Metrics.MaxSeries < 0 {
+		verr.addf("metrics.max_series", "must not be negative")
+	}
+	if c.Metrics.Shards < 0 {
+		verr.addf("metrics.shards", "must not be negative")
+	}
+
+	for i, peer := range c.Federation.Peers {
+		if u, err := url.Parse(peer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.addf(fmt.Sprintf("federation.peers[%d]", i), "invalid peer URL '%s' (expected http or https)", peer)
+		}
+	}
+	if c.Federation.Interval < 0 {
+		verr.addf("federation.interval", "must not be negative")
+	}
+
+	c.validateListen(verr)
+	for i, client := range c.AllowedClients {
+		if _, err := netip.ParseAddr(client); err == nil {
+			continue
+		}
+		if _, err := netip.ParsePrefix(client); err != nil {
+			verr.addf(fmt.Sprintf("allowed_clients[%d]", i), "invalid client '%s' (expected IP address or CIDR prefix)", client)
+		}
+	}
+	if c.ProbeEndpoint.Enabled && len(c.ProbeEndpoint.AllowedTargets) == 0 {
+		verr.addf("probe_endpoint.allowed_targets", "at least one target is required when the probe endpoint is enabled")
+	}
+	for i, target := range c.ProbeEndpoint.AllowedTargets {
+		if _, err := netip.ParseAddr(target); err == nil {
+			continue
+		}
+		if _, err := netip.ParsePrefix(target); err != nil {
+			verr.addf(fmt.Sprintf("probe_endpoint.allowed_targets[%d]", i), "invalid target '%s' (expected IP address or CIDR prefix)", target)
+		}
+	}
+	for i, target := range c.OpenResolverScan.Targets {
+		if _, err := ExpandTargets([]string{target}); err != nil {
+			verr.addf(fmt.Sprintf("open_resolver_scan.targets[%d]", i), "%v", err)
+		}
+	}
+	if _, ok := dns.IsDomainName(c.OpenResolverScan.Domain); c.OpenResolverScan.Domain != "" && !ok {
+		verr.addf("open_resolver_scan.domain", "invalid domain name '%s'", c.OpenResolverScan.Domain)
+	}
+	if c.OpenResolverScan.Interval < 0 {
+		verr.addf("open_resolver_scan.interval", "must not be negative")
+	}
+	if c.OpenResolverScan.Timeout < 0 {
+		verr.addf("open_resolver_scan.timeout", "must not be negative")
+	}
+
+	if c.LeaderElection.Enabled() && c.LeaderElection.Lease < Duration(time.Second) {
+		verr.addf("leader_election.lease", "must be at least 1s")
+	}
+
+	if c.Chroot != "" && !filepath.IsAbs(c.Chroot) {
+		verr.addf("chroot", "must be an absolute path")
+	}
+
+	if c.CycleDeadline < 0 {
+		verr.addf("cycle_deadline", "must not be negative")
+	}
+	if c.IdleTimeout < 0 {
+		verr.addf("idle_timeout", "must not be negative")
+	}
+	if c.StartupRamp < 0 {
+		verr.addf("startup_ramp", "must not be negative")
+	}
+	if c.ErrorHistory < 0 {
+		verr.addf("error_history", "must not be negative")
+	}
+	if c.ShutdownTimeout < 0 {
+		verr.addf("shutdown_timeout", "must not be negative")
+	}
+
+	if c.RateLimit.QPS < 0 {
+		verr.addf("rate_limit.qps", "must not be negative")
+	}
+	if c.RateLimit.Burst < 0 {
+		verr.addf("rate_limit.burst", "must not be negative")
+	}
+	if c.API.RateLimit.QPS < 0 {
+		verr.addf("api.rate_limit.qps", "must not be negative")
+	}
+	if c.API.RateLimit.Burst < 0 {
+		verr.addf("api.rate_limit.burst", "must not be negative")
+	}
+	if c.API.MaxConcurrent < 0 {
+		verr.addf("api.max_concurrent", "must not be negative")
+	}
+
+	for i, domain := range c.Domains {
+		path := domain.path(i)
+		if domain.Name == "" {
+			verr.addf(path+".name", "domain name is required")
+		}
+		if probes := domain.ProbeCount(); probes < 1 || probes > MaxProbes {
+			verr.addf(path+".probes", "must be between 1 and %d, got %d", MaxProbes, probes)
+		}
+		if _, ok := dns.StringToType[strings.ToUpper(domain.QType)]; domain.QType != "" && !ok {
+			verr.addf(path+".qtype", "unknown record type '%s'", domain.QType)
+		}
+		if t := domain.QueryTemplate; t != "" {
+			if !strings.Contains(t, "{rand}") {
+				verr.addf(path+".query_template", "must contain {rand}")
+			} else if name := domain.QueryName("abcde"); strings.ContainsAny(name, "{}") {
+				verr.addf(path+".query_template", "unknown placeholder (expected {rand} and {name})")
+			} else if _, ok := dns.IsDomainName(name); !ok {
+				verr.addf(path+".query_template", "does not produce a valid name")
+			}
+		}
+		switch domain.DNSSEC {
+		case "", DNSSECSecure, DNSSECInsecure:
+		default:
+			verr.addf(path+".dnssec", "invalid status '%s' (expected secure or insecure)", domain.DNSSEC)
+		}
+		if b := domain.Blocked; b != nil {
+			if _, ok := dns.StringToRcode[strings.ToUpper(b.Rcode)]; b.Rcode != "" && !ok {
+				verr.addf(path+".blocked.rcode", "unknown response code '%s'", b.Rcode)
+			}
+			for _, answer := range b.Answers {
+				if net.ParseIP(answer) == nil {
+					verr.addf(path+".blocked.answers", "invalid IP address '%s'", answer)
+				}
+			}
+		}
+		if ref := domain.Reference; ref != nil {
+			switch {
+			case len(ref.Answers) > 0 && ref.Resolver != nil:
+				verr.addf(path+".reference", "answers and resolver are mutually exclusive")
+			case len(ref.Answers) == 0 && ref.Resolver == nil && len(ref.Views) == 0:
+				verr.addf(path+".reference", "answers, resolver or views is required")
+			case ref.Resolver != nil:
+				ref.Resolver.validateReference(path+".reference.resolver", verr)
+			}
+			for _, answer := range ref.Answers {
+				if !domain.isReferenceAnswer(answer) {
+					verr.addf(path+".reference.answers", "invalid %s address '%s'", domain.referenceFamily(), answer)
+				}
+			}
+			for _, view := range slices.Sorted(maps.Keys(ref.Views)) {
+				viewPath := path + ".reference.views." + view
+				if !slices.ContainsFunc(c.DNSServers, func(s DNSServer) bool { return s.View == view }) {
+					verr.addf(viewPath, "no server in view '%s'", view)
+				}
+				if len(ref.Views[view]) == 0 {
+					verr.addf(viewPath, "at least one answer is required")
+				}
+				for _, answer := range ref.Views[view] {
+					_, rcode := dns.StringToRcode[strings.ToUpper(answer)]
+					if !rcode && !domain.isReferenceAnswer(answer) {
+						verr.addf(viewPath, "invalid answer '%s' (expected %s address or response code)", answer, domain.referenceFamily())
+					}
+				}
+			}
+		}
+	}
+
+	if c.Filtering.Enabled() {
+		c.Filtering.Reference.validateReference("filtering.reference", verr)
+		for _, category := range c.Filtering.CategoryNames() {
+			if len(c.Filtering.Categories[category]) == 0 {
+				verr.addf("filtering.categories."+category, "at least one test domain is required")
+			}
+		}
+	}
+
+	if lr := c.LargeResponse; lr.Enabled() {
+		if _, ok := dns.IsDomainName(lr.Name); !ok {
+			verr.addf("large_response.name", "invalid domain name '%s'", lr.Name)
+		}
+		if _, ok := dns.StringToType[strings.ToUpper(lr.QType)]; !ok {
+			verr.addf("large_response.qtype", "unknown record type '%s'", lr.QType)
+		}
+		if lr.BufferSize < dns.MinMsgSize || lr.BufferSize > dns.MaxMsgSize {
+			verr.addf("large_response.buffer_size", "must be between %d and %d", dns.MinMsgSize, dns.MaxMsgSize)
+		}
+	}
+
+	for i, server := range c.DNSServers {
+		path := server.path(i)
+
+		if server.Address == "" {
+			verr.addf(path+".address", "server address is required")
+		}
+		if len(server.Protocols) > 0 {
+			verr.addf(path+".protocols", "cannot be combined with protocol or port")
+		}
+
+		if validProtocol(path, server.Protocol, verr) && !server.IsRecursive() && server.Protocol != ProtocolDo53UDP && server.Protocol != ProtocolDo53TCP {
+			verr.addf(path+".recursive", "iterative resolution requires protocol %s or %s", ProtocolDo53UDP, ProtocolDo53TCP)
+		}
+
+		if server.QPS < 0 {
+			verr.addf(path+".qps", "must not be negative")
+		}
+
+		if server.RetryCount() < 0 {
+			verr.addf(path+".retries", "must not be negative")
+		}
+
+		server.validateTimeouts(path, verr)
+
+		if server.Schedule != "" {
+			if _, err := ParseSchedule(server.Schedule); err != nil {
+				verr.addf(path+".schedule", "invalid schedule '%s': %v", server.Schedule, err)
+			}
+		}
+
+		if server.Netns != "" || server.VRF != "" {
+			if runtime.GOOS != "linux" {
+				verr.addf(path, "netns and vrf are only supported on Linux")
+			}
+			if strings.Contains(server.Netns, "/") && !filepath.IsAbs(server.Netns) {
+				verr.addf(path+".netns", "must be a namespace name or an absolute path")
+			}
+			if len(server.VRF) > maxInterfaceName || strings.ContainsAny(server.VRF, "/ \t") {
+				verr.addf(path+".vrf", "invalid device name '%s'", server.VRF)
+			}
+		}
+
+		if server.ReuseSocket && (server.Protocol != ProtocolDo53UDP || !server.IsRecursive()) {
+			verr.addf(path+".reuse_socket", "requires protocol %s and a recursive server", ProtocolDo53UDP)
+		}
+
+		if server.CompareTransports && server.Protocol != ProtocolDo53UDP && server.Protocol != ProtocolDo53TCP {
+			verr.addf(path+".compare_transports", "requires protocol %s or %s", ProtocolDo53UDP, ProtocolDo53TCP)
+		}
+		if server.DowngradeCheck && (!IsEncryptedProtocol(server.Protocol) || !server.IsRecursive()) {
+			verr.addf(path+".downgrade_check", "requires an encrypted protocol and a recursive server")
+		}
+		if server.Fingerprint && !server.IsRecursive() {
+			verr.addf(path+".fingerprint", "is not supported with recursive: false")
+		}
+		if server.Anycast && !server.IsRecursive() {
+			verr.addf(path+".anycast", "is not supported with recursive: false")
+		}
+		if len(server.CaptureHeaders) > 0 && server.Protocol != ProtocolDoH && server.Protocol != ProtocolDoH3 {
+			verr.addf(path+".capture_headers", "requires protocol %s or %s", ProtocolDoH, ProtocolDoH3)
+		}
+		for j, name := range server.CaptureHeaders {
+			if !headerNamePattern.MatchString(name) {
+				verr.addf(fmt.Sprintf("%s.capture_headers[%d]", path, j), "invalid header name '%s'", name)
+			}
+		}
+		if http := server.HTTP; http != nil {
+			if server.Protocol != ProtocolDoH && server.Protocol != ProtocolDoH3 {
+				verr.addf(path+".http", "requires protocol %s or %s", ProtocolDoH, ProtocolDoH3)
+			}
+			for _, name := range slices.Sorted(maps.Keys(http.Headers)) {
+				switch value := http.Headers[name]; {
+				case !headerNamePattern.MatchString(name):
+					verr.addf(path+".http.headers", "invalid header name '%s'", name)
+				case reservedHeaders[strings.ToLower(name)]:
+					verr.addf(path+".http.headers", "header '%s' is set by the exporter", name)
+				case strings.ContainsAny(value, "\r\n"):
+					verr.addf(path+".http.headers", "value of header '%s' contains a line break", name)
+				}
+			}
+			if strings.ContainsAny(http.UserAgent, "\r\n") {
+				verr.addf(path+".http.user_agent", "contains a line break")
+			}
+		}
+		if stats := server.Stats; stats != nil {
+			switch stats.Type {
+			case StatsUnbound, StatsBIND:
+				if stats.Address == "" {
+					verr.addf(path+".stats.address", "is required for %s", stats.Type)
+				}
+			case StatsDnsmasq:
+			default:
+				verr.addf(path+".stats.type", "invalid type '%s' (expected %s, %s or %s)", stats.Type, StatsUnbound, StatsBIND, StatsDnsmasq)
+			}
+			if stats.Address != "" && !(stats.Type == StatsUnbound && filepath.IsAbs(stats.Address)) {
+				if _, _, err := net.SplitHostPort(stats.Address); err != nil {
+					verr.addf(path+".stats.address", "invalid address '%s': %v", stats.Address, err)
+				}
+			}
+		}
+		switch server.AltSvc {
+		case "":
+		case AltSvcDetect, AltSvcProbe:
+			if server.Protocol != ProtocolDoH {
+				verr.addf(path+".alt_svc", "requires protocol %s", ProtocolDoH)
+			}
+			if reason, ok := resolver.Unsupported(ProtocolDoH3); ok && server.AltSvc == AltSvcProbe {
+				verr.addf(path+".alt_svc", "mode %s is not supported: %s", AltSvcProbe, reason)
+			}
+		default:
+			verr.addf(path+".alt_svc", "invalid mode '%s' (expected %s or %s)", server.AltSvc, AltSvcDetect, AltSvcProbe)
+		}
+
+		if tcp := server.TCP; tcp != nil {
+			if !IsTCPProtocol(server.Protocol) {
+				verr.addf(path+".tcp", "requires protocol %s, %s or %s", ProtocolDo53TCP, ProtocolDoT, ProtocolDoH)
+			}
+			if tcp.FastOpen && runtime.GOOS != "linux" {
+				verr.addf(path+".tcp.fast_open", "is only supported on Linux")
+			}
+			if tcp.KeepAlive < 0 {
+				verr.addf(path+".tcp.keepalive", "must not be negative")
+			}
+			if tcp.KeepAliveInterval < 0 {
+				verr.addf(path+".tcp.keepalive_interval", "must not be negative")
+			}
+		}
+
+		if server.SourceAddress != "" {
+			source := net.ParseIP(server.SourceAddress)
+			if source == nil {
+				verr.addf(path+".source_address", "invalid IP address '%s'", server.SourceAddress)
+			} else if ip := net.ParseIP(server.Address); ip != nil && (ip.To4() == nil) != (source.To4() == nil) {
+				verr.addf(path+".source_address", "address family of '%s' does not match server address", server.SourceAddress)
+			}
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(server.Labels)) {
+			if !labelNamePattern.MatchString(name) || reservedLabels[name] || strings.HasPrefix(name, "__") {
+				verr.addf(path+".labels", "invalid label name '%s'", name)
+			}
+		}
+
+		if server.Upstreams < 0 {
+			verr.addf(path+".upstreams", "must not be negative")
+		}
+
+		if sla := server.SLA; sla != nil {
+			switch {
+			case sla.Warning < 0 || sla.Critical < 0:
+				verr.addf(path+".sla", "thresholds must not be negative")
+			case sla.Warning == 0 && sla.Critical == 0:
+				verr.addf(path+".sla", "warning or critical is required")
+			case sla.Warning > 0 && sla.Critical > 0 && sla.Warning > sla.Critical:
+				verr.addf(path+".sla.warning", "must not be above critical")
+			}
+		}
+
+		if faults := server.Faults; faults != nil {
+			if faults.Latency < 0 {
+				verr.addf(path+".faults.latency", "must not be negative")
+			}
+			probabilities := map[string]float64{"timeout": faults.Timeout, "servfail": faults.Servfail}
+			for _, name := range slices.Sorted(maps.Keys(probabilities)) {
+				if p := probabilities[name]; p < 0 || p > 1 {
+					verr.addf(path+".faults."+name, "must be between 0 and 1, got %g", p)
+				}
+			}
+			if faults.Timeout+faults.Servfail > 1 {
+				verr.addf(path+".faults", "timeout and servfail must not add up to more than 1")
+			}
+		}
+
+		if tls := server.TLS; tls != nil && tls.ResumesSessions() && !IsEncryptedProtocol(server.Protocol) {
+			verr.addf(path+".tls.session_resumption", "requires an encrypted protocol")
+		}
+		if tls := server.TLS; tls != nil && (tls.MinVersion != "" || tls.MaxVersion != "") {
+			if !IsEncryptedProtocol(server.Protocol) {
+				verr.addf(path+".tls", "min_version and max_version require an encrypted protocol")
+			}
+			versions := map[string]string{"min_version": tls.MinVersion, "max_version": tls.MaxVersion}
+			for _, name := range slices.Sorted(maps.Keys(versions)) {
+				if version := versions[name]; version != "" && TLSVersion(version) == 0 {
+					verr.addf(path+".tls."+name, "invalid TLS version '%s' (expected 1.0, 1.1, 1.2 or 1.3)", version)
+				}
+			}
+			if minVersion, maxVersion := TLSVersion(tls.MinVersion), TLSVersion(tls.MaxVersion); minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+				verr.addf(path+".tls.min_version", "must not be above max_version")
+			}
+			if tls.MaxVersion != "" && tls.MaxVersion != "1.3" && (server.Protocol == ProtocolDoH3 || server.Protocol == ProtocolDoQ) {
+				verr.addf(path+".tls.max_version", "QUIC requires TLS 1.3")
+			}
+		}
+
+		if server.TLS != nil && len(server.TLS.ALPN) > 0 {
+			if server.Protocol != ProtocolDoQ {
+				verr.addf(path+".tls.alpn", "requires protocol %s", ProtocolDoQ)
+			}
+			for j, alpn := range server.TLS.ALPN {
+				if alpn == "" || len(alpn) > 255 {
+					verr.addf(fmt.Sprintf("%s.tls.alpn[%d]", path, j), "must be 1 to 255 bytes long")
+				}
+			}
+		}
+
+		if IsEncryptedProtocol(server.Protocol) {
+			if server.TLS == nil {
+				c.DNSServers[i].TLS = &TLSConfig{ServerName: server.Address}
+			} else if server.TLS.ServerName == "" {
+				c.DNSServers[i].TLS.ServerName = server.Address
+			}
+		}
+	}
+
+	c.validateDuplicates(verr)
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// ValidateOverrides checks the settings command-line overrides may have
+// replaced since the configuration was loaded: the addresses the HTTP
+// server listens on and the timeouts of the servers
+func (c *Config) ValidateOverrides() error {
+	verr := &ValidationError{}
+	c.validateListen(verr)
+	for i, server := range c.DNSServers {
+		server.validateTimeouts(server.path(i), verr)
+	}
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateTimeouts records problems with the connect and query timeouts,
+// which must fit within the server's timeout
+func (s DNSServer) validateTimeouts(path string, verr *ValidationError) {
+	if s.ConnectTimeout < 0 {
+		verr.addf(path+".connect_timeout", "must not be negative")
+	} else if s.ConnectTimeout > s.Timeout {
+		verr.addf(path+".connect_timeout", "must not exceed timeout (%d ms)", s.Timeout)
+	}
+	if s.QueryTimeout < 0 {
+		verr.addf(path+".query_timeout", "must not be negative")
+	} else if s.QueryTimeout > s.Timeout {
+		verr.addf(path+".query_timeout", "must not exceed timeout (%d ms)", s.Timeout)
+	}
+}
+
+// validateListen records problems with the listen addresses, so that they
+// are reported at load time rather than by net.Listen once probing started
+func (c *Config) validateListen(verr *ValidationError) {
+	for i, address := range c.Listen {
+		path := fmt.Sprintf("listen[%d]", i)
+		if socket, ok := strings.CutPrefix(address, "unix:"); ok {
+			if socket == "" {
+				verr.addf(path, "missing socket path")
+			}
+			continue
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			verr.addf(path, "invalid address '%s' (expected host:port or unix:/path)", address)
+			continue
+		}
+		if host != "" && !validHost(host) {
+			verr.addf(path, "invalid host '%s' (expected IP address or host name)", host)
+		}
+		if !validPort(port) {
+			verr.addf(path, "invalid port '%s' (expected 0 to 65535)", port)
+		}
+	}
+	if addr := c.ListenAddress; addr != "" && addr != "*" && !validHost(addr) {
+		verr.addf("listen_addr", "invalid address '%s' (expected IP address, host name or *)", addr)
+	}
+	if port := c.ListenPort; port != "" && !validPort(port) {
+		verr.addf("listen_port", "invalid port '%s' (expected 0 to 65535)", port)
+	}
+}
+
+// hostnamePattern matches host names made of letters, digits and hyphens
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.?$`)
+
+// validHost returns true if host is an IP address, possibly with a zone,
+// or a host name
+func validHost(host string) bool {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	return len(host) <= 253 && hostnamePattern.MatchString(host)
+}
+
+// validPort returns true if port is a TCP port number
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
+
+// ParseSchedule parses a standard five-field cron expression
+// (minute hour day-of-month month day-of-week) or a descriptor such as @hourly
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+// validateReference checks a reference resolver configured at path
+func (s DNSServer) validateReference(path string, verr *ValidationError) {
+	if s.Address == "" {
+		verr.addf(path+".address", "reference resolver address is required")
+	}
+	validProtocol(path, s.Protocol, verr)
+}
+
+// validProtocol checks the protocol of the server configured at path,
+// telling unknown protocols from those this build was compiled without
+func validProtocol(path, protocol string, verr *ValidationError) bool {
+	if reason, ok := resolver.Unsupported(protocol); ok {
+		verr.addf(path+".protocol", "protocol '%s' is not supported: %s", protocol, reason)
+		return false
+	}
+	if !resolver.Registered(protocol) {
+		verr.addf(path+".protocol", "invalid protocol '%s'", protocol)
+		return false
+	}
+	return true
+}
+
+// path returns the YAML path of the domain for error messages
+func (d Domain) path(index int) string {
+	if d.location != "" {
+		return d.location
+	}
+	return fmt.Sprintf("domains[%d]", index)
+}
+
+// path returns the YAML path of the server for error messages
+func (s DNSServer) path(index int) string {
+	if s.location != "" {
+		return s.location
+	}
+	return fmt.Sprintf("dns_servers[%d]", index)
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// resultAges exports how long ago the latest result of every target was
+// recorded. The age is computed at scrape time, so it keeps growing while a
+// target is not probed.
+type resultAges struct {
+	desc *prometheus.Desc
+
+	mu    sync.Mutex
+	times map[string]resultTime
+}
+
+// resultTime is when the latest result of a label combination was recorded
+type resultTime struct {
+	values []string
+	at     time.Time
+}
+
+// newResultAges creates the collector for the given label names
+func newResultAges(labelNames []string) *resultAges {
+	return &resultAges{
+		desc: prometheus.NewDesc("dns_probe_result_age_seconds",
+			"Seconds since the latest result of the target was recorded", labelNames, nil),
+		times: make(map[string]resultTime),
+	}
+}
+
+// set records that a result for the label values arrived at
+func (a *resultAges) set(values []string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.times[strings.Join(values, "\xff")] = resultTime{values: values, at: at}
+}
+
+// Describe sends the descriptor of the ages
+func (a *resultAges) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.desc
+}
+
+// Collect sends the current age of every result
+func (a *resultAges) Collect(ch chan<- prometheus.Metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, t := range a.times {
+		ch <- prometheus.MustNewConstMetric(a.desc, prometheus.GaugeValue, time.Since(t.at).Seconds(), t.values...)
+	}
+}
@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package metrics
+
+import (
+	"os"
+	"runtime"
+)
+
+// openFDs counts the open file descriptors of the process. Unlike the
+// process collector, which only does so on Linux, it also works on the BSDs
+// and macOS through /dev/fd; FreeBSD needs fdescfs mounted there.
+func openFDs() (int, error) {
+	dir := "/dev/fd"
+	if runtime.GOOS == "linux" {
+		dir = "/proc/self/fd"
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	// Reading the directory takes a descriptor of its own
+	return len(entries) - 1, nil
+}
@@ -0,0 +1,1581 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package metrics
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+)
+
+// baseLabels are the labels present on every query metric
+var baseLabels = []string{"domain", "server", "protocol"}
+
+// serverLabels are the labels present on every per-server metric
+var serverLabels = []string{"server", "protocol"}
+
+// Query metric families that can be disabled
+const (
+	FamilyQueryDuration       = "query_duration"
+	FamilyFailedQueryDuration = "failed_query_duration"
+	FamilyLastQueryDuration   = "last_query_duration"
+	FamilyTimeoutRatio        = "timeout_ratio"
+	FamilyAttempts            = "attempts"
+	FamilyIterationSteps      = "iteration_steps"
+	FamilyAnswerGeo           = "answer_geo"
+	FamilyFiltering           = "filtering"
+	FamilyDivergence          = "divergence"
+	FamilyDNSSEC              = "dnssec"
+	FamilyFastOpen            = "fast_open"
+	FamilyTransports          = "transports"
+	FamilyDowngrade           = "downgrade"
+	FamilyDoQErrors           = "doq_errors"
+	FamilyDoQALPN             = "doq_alpn"
+	FamilyAltSvc              = "alt_svc"
+	FamilyResponseHeaders     = "response_headers"
+	FamilyConnections         = "connections"
+	FamilyServerStats         = "server_stats"
+	FamilyFingerprint         = "fingerprint"
+	FamilyBlocking            = "blocking"
+	FamilyAnycast             = "anycast"
+	FamilyQUICBlocked         = "quic_blocked"
+	FamilyFirstQueryDuration  = "first_query_duration"
+	FamilyLargeResponse       = "large_response"
+	FamilySLA                 = "sla"
+	FamilyUpstream            = "upstream"
+	FamilyResultAge           = "result_age"
+)
+
+// Families lists the query metric families that can be disabled. Query
+// success and failure counters are always exported.
+var Families = []string{
+	FamilyQueryDuration, FamilyFailedQueryDuration, FamilyLastQueryDuration, FamilyTimeoutRatio,
+	FamilyAttempts, FamilyIterationSteps, FamilyAnswerGeo, FamilyFiltering, FamilyDivergence, FamilyDNSSEC,
+	FamilyFastOpen, FamilyTransports, FamilyDowngrade, FamilyDoQErrors, FamilyDoQALPN,
+	FamilyAltSvc, FamilyResponseHeaders, FamilyConnections, FamilyServerStats,
+	FamilyFingerprint, FamilyBlocking, FamilyAnycast, FamilyQUICBlocked, FamilyFirstQueryDuration,
+	FamilyLargeResponse, FamilySLA, FamilyUpstream, FamilyResultAge,
+}
+
+// Options selects the exported query metrics
+type Options struct {
+	// Disabled lists metric families that record nothing
+	Disabled []string
+
+	// Summaries exports durations as summaries instead of histograms
+	Summaries bool
+
+	// MaxSeries limits the label combinations of domain, server, protocol
+	// and custom labels that are recorded (0 = unlimited)
+	MaxSeries int
+}
+
+// summaryObjectives are the quantiles of duration summaries
+var summaryObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
+// Metrics holds the exporter's Prometheus metrics registered on a single
+// registry. Several instances can coexist on separate registries. A nil
+// *Metrics ignores all operational events.
+type Metrics struct {
+	mu          sync.RWMutex
+	extraLabels []string
+	options     Options
+	disabled    map[string]bool
+
+	// series holds the admitted label combinations; seriesMu guards it as
+	// recording methods only hold mu for reading
+	seriesMu      sync.Mutex
+	series        map[string]bool
+	limitReported bool
+
+	// QueryDuration tracks the duration of DNS queries
+	QueryDuration prometheus.ObserverVec
+
+	// FailedQueryDuration tracks the duration of failed DNS queries
+	FailedQueryDuration prometheus.ObserverVec
+
+	// FirstQueryDuration tracks the duration of the first of several
+	// successful DNS queries of a domain in a cycle, when kept apart
+	FirstQueryDuration prometheus.ObserverVec
+
+	// UpstreamQueryDuration tracks the duration of successful DNS queries
+	// by the address of a host name target that answered
+	UpstreamQueryDuration prometheus.ObserverVec
+
+	// LastQueryDuration is the duration of the latest successful DNS query
+	LastQueryDuration *prometheus.GaugeVec
+
+	// resultAges tells how long ago the latest result of every target was
+	// recorded
+	resultAges *resultAges
+
+	// QuerySuccess counts successful DNS queries
+	QuerySuccess *prometheus.CounterVec
+
+	// QueryFailures counts failed DNS queries
+	QueryFailures *prometheus.CounterVec
+
+	// QueryTimeoutRatio tracks successful query durations as a fraction of
+	// their timeout
+	QueryTimeoutRatio *prometheus.HistogramVec
+
+	// AttemptDuration tracks the duration of every network exchange,
+	// including retries
+	AttemptDuration prometheus.ObserverVec
+
+	// AttemptSuccess counts successful network exchanges
+	AttemptSuccess *prometheus.CounterVec
+
+	// AttemptFailures counts failed network exchanges
+	AttemptFailures *prometheus.CounterVec
+
+	// IterationStepDuration tracks each referral step of iterative
+	// resolution, labeled with the zone whose name servers were asked
+	IterationStepDuration prometheus.ObserverVec
+
+	// AnswerGeo is 1 for each country and ASN combination of the addresses
+	// in a target's latest answer
+	AnswerGeo *prometheus.GaugeVec
+
+	// FilteringActive is 1 while a server blocks test domains of a
+	// filtering category that the reference resolver answers
+	FilteringActive *prometheus.GaugeVec
+
+	// AnswerChecks counts comparisons of answers with a domain's reference
+	AnswerChecks *prometheus.CounterVec
+
+	// AnswerDivergences counts answers that differed from a domain's
+	// reference
+	AnswerDivergences *prometheus.CounterVec
+
+	// DNSSECChecks counts probes whose DNSSEC status was verified
+	DNSSECChecks *prometheus.CounterVec
+
+	// DNSSECMismatches counts probes whose DNSSEC status differed from the
+	// domain's expected status
+	DNSSECMismatches *prometheus.CounterVec
+
+	// BlockChecks counts probes of domains expected to be blocked
+	BlockChecks *prometheus.CounterVec
+
+	// BlockBypasses counts probes whose server did not block a domain
+	// expected to be blocked
+	BlockBypasses *prometheus.CounterVec
+
+	// SLABreaches counts probes that breached a latency threshold of
+	// their server, by threshold
+	SLABreaches *prometheus.CounterVec
+
+	// SLAState is the state of the latest probe against the latency
+	// thresholds: 0 within, 1 warning, 2 critical
+	SLAState *prometheus.GaugeVec
+
+	// FastOpenAttempts counts TCP connections that sent the query with TCP
+	// Fast Open
+	FastOpenAttempts *prometheus.CounterVec
+
+	// FastOpenAccepted counts TCP connections whose server accepted the
+	// query sent in the SYN
+	FastOpenAccepted *prometheus.CounterVec
+
+	// TransportLatencyDelta is how much longer the latest TCP query of a
+	// transport comparison took than the UDP query
+	TransportLatencyDelta *prometheus.GaugeVec
+
+	// TransportUp is 1 if the latest query of a transport comparison over
+	// the transport succeeded
+	TransportUp *prometheus.GaugeVec
+
+	// EncryptedTransportDown is 1 while an encrypted server fails but
+	// answers plaintext DNS
+	EncryptedTransportDown *prometheus.GaugeVec
+
+	// PlaintextUp is 1 if the latest Do53 query of a downgrade check
+	// succeeded
+	PlaintextUp *prometheus.GaugeVec
+
+	// DoQErrors counts DoQ error codes signalled by servers, labeled with
+	// whether they reset the stream or closed the connection
+	DoQErrors *prometheus.CounterVec
+
+	// DoQALPN is 1 for the application protocol negotiated by a target's
+	// latest DoQ connection
+	DoQALPN *prometheus.GaugeVec
+
+	// HTTP3Advertised is 1 if the latest response of a DoH server
+	// advertised HTTP/3 with Alt-Svc
+	HTTP3Advertised *prometheus.GaugeVec
+
+	// ResponseHeader is 1 for each captured header value of a target's
+	// latest DoH response
+	ResponseHeader *prometheus.GaugeVec
+
+	// Connections counts encrypted queries by how their connection was set
+	// up: a full handshake, a resumed TLS session or an existing connection
+	Connections *prometheus.CounterVec
+
+	// ConnectionLastDuration is the duration of the latest successful
+	// attempt of each kind of connection
+	ConnectionLastDuration *prometheus.GaugeVec
+
+	// OpenConnections is the number of connections and sockets held open
+	// by a target's resolver at the end of the latest probe cycle
+	OpenConnections *prometheus.GaugeVec
+
+	// ServerStat holds the native statistics of a server, as last scraped
+	// from its software
+	ServerStat *prometheus.GaugeVec
+
+	// ServerStatsUp is 1 if the latest statistics scrape of a server
+	// succeeded
+	ServerStatsUp *prometheus.GaugeVec
+
+	// ServerFingerprint is 1 for the software, version and NSID of the
+	// latest successful fingerprint of a server
+	ServerFingerprint *prometheus.GaugeVec
+
+	// AnycastInstance is 1 for the instance of an anycast server that
+	// answered the latest check
+	AnycastInstance *prometheus.GaugeVec
+
+	// AnycastInstanceChanges counts changes of the answering instance
+	AnycastInstanceChanges *prometheus.CounterVec
+
+	// QUICBlocked is 1 while QUIC to a DoQ or DoH3 target keeps failing
+	// and TCP to the same address works
+	QUICBlocked *prometheus.GaugeVec
+
+	// LargeResponseUp is 1 if the server sent the complete large response
+	// of the latest stress query, after falling back to TCP if truncated
+	LargeResponseUp *prometheus.GaugeVec
+
+	// LargeResponseSize is the size of the latest complete large response
+	LargeResponseSize *prometheus.GaugeVec
+
+	// LargeResponseTruncated is 1 if the latest large response was first
+	// answered truncated
+	LargeResponseTruncated *prometheus.GaugeVec
+
+	// CycleOverruns counts probe cycles cut short by the cycle deadline
+	CycleOverruns prometheus.Counter
+
+	// ClockJumps counts measurements discarded because the wall clock
+	// jumped while they ran
+	ClockJumps prometheus.Counter
+
+	// ProbingPaused is 1 while probing is paused
+	ProbingPaused prometheus.Gauge
+
+	// SchedulerHeartbeat is the time the scheduler last showed signs of life
+	SchedulerHeartbeat prometheus.Gauge
+
+	// WatchdogCancels counts probes force-cancelled by the stuck-probe watchdog
+	WatchdogCancels *prometheus.CounterVec
+
+	// OverlapSkips counts probes skipped because an earlier probe of the
+	// target was still in flight
+	OverlapSkips *prometheus.CounterVec
+
+	// FederationSites is the number of sites with a current result for a
+	// target
+	FederationSites *prometheus.GaugeVec
+
+	// FederationSuccessRatio is the fraction of sites whose latest probe of
+	// a target succeeded
+	FederationSuccessRatio *prometheus.GaugeVec
+
+	// FederationLatencySpread is the difference between the slowest and the
+	// fastest successful latest probe of a target across sites
+	FederationLatencySpread *prometheus.GaugeVec
+
+	// FederationPeerUp is 1 if the latest pull from a peer succeeded
+	FederationPeerUp *prometheus.GaugeVec
+
+	// ProviderSuccessRatio is the fraction of the latest probes of a
+	// provider's servers that succeeded
+	ProviderSuccessRatio *prometheus.GaugeVec
+
+	// ProviderLatency is the mean duration of the successful latest probes
+	// of a provider's servers
+	ProviderLatency *prometheus.GaugeVec
+
+	// OpenResolver is 1 for scanned addresses that resolved a name they
+	// are not authoritative for, and 0 for those refusing to
+	OpenResolver *prometheus.GaugeVec
+
+	// Leader is 1 while this replica probes and 0 while it stands by
+	Leader prometheus.Gauge
+
+	// ActiveSeries is the number of admitted label combinations
+	ActiveSeries prometheus.Gauge
+
+	// RejectedSeries counts recordings refused by the series limit
+	RejectedSeries prometheus.Counter
+}
+
+// New creates the metrics and registers them on registry
+func New(registry prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		CycleOverruns: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "dnspulse_probe_cycle_overruns_total",
+				Help: "Total probe cycles that did not complete within the cycle deadline",
+			},
+		),
+		ClockJumps: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "dnspulse_clock_jumps_total",
+				Help: "Total measurements discarded because the wall clock jumped while they ran",
+			},
+		),
+		ProbingPaused: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_probing_paused",
+				Help: "Whether probing is currently paused (1) or running (0)",
+			},
+		),
+		SchedulerHeartbeat: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_scheduler_heartbeat_timestamp_seconds",
+				Help: "Unix time of the last probe scheduler activity",
+			},
+		),
+		WatchdogCancels: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dnspulse_probe_watchdog_cancels_total",
+				Help: "Total probes cancelled by the watchdog after blocking far beyond their timeout",
+			},
+			[]string{"server", "protocol"},
+		),
+		OverlapSkips: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "dns_probe_skipped_overlap_total",
+				Help: "Total probes skipped because an earlier probe of the server was still in flight",
+			},
+			[]string{"server", "protocol"},
+		),
+		FederationSites: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_federation_sites",
+				Help: "Number of sites with a current result for the target",
+			},
+			baseLabels,
+		),
+		FederationSuccessRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_federation_success_ratio",
+				Help: "Fraction of sites whose latest probe of the target succeeded",
+			},
+			baseLabels,
+		),
+		FederationLatencySpread: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_federation_latency_spread_seconds",
+				Help: "Difference between the slowest and fastest successful latest probe of the target across sites",
+			},
+			baseLabels,
+		),
+		FederationPeerUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_federation_peer_up",
+				Help: "Whether the latest pull of results from the peer succeeded (1) or not (0)",
+			},
+			[]string{"peer"},
+		),
+		ProviderSuccessRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_provider_success_ratio",
+				Help: "Fraction of the latest probes of the servers labeled with the provider that succeeded",
+			},
+			[]string{"provider", "protocol"},
+		),
+		ProviderLatency: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_provider_latency_seconds",
+				Help: "Mean duration of the successful latest probes of the servers labeled with the provider",
+			},
+			[]string{"provider", "protocol"},
+		),
+		OpenResolver: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "dns_open_resolver",
+				Help: "Whether the address answered a recursive query of the latest open resolver scan (1) or refused it (0)",
+			},
+			[]string{"address"},
+		),
+		Leader: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_leader",
+				Help: "Whether this replica is the elected leader that probes (1) or a standby (0)",
+			},
+		),
+		ActiveSeries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_active_series",
+				Help: "Number of label combinations recorded by the query metrics",
+			},
+		),
+		RejectedSeries: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "dnspulse_series_rejected_total",
+				Help: "Total recordings refused because the series limit was reached",
+			},
+		),
+	}
+	m.Configure(nil, Options{})
+	registry.MustRegister(m.CycleOverruns, m.ClockJumps, m.ProbingPaused, m.SchedulerHeartbeat, m.WatchdogCancels, m.OverlapSkips,
+		m.FederationSites, m.FederationSuccessRatio, m.FederationLatencySpread, m.FederationPeerUp,
+		m.ProviderSuccessRatio, m.ProviderLatency, m.OpenResolver, m.Leader, m.ActiveSeries, m.RejectedSeries, queryCollector{m})
+	if _, err := openFDs(); err == nil {
+		registry.MustRegister(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name: "dnspulse_open_fds",
+				Help: "Number of open file descriptors of the process",
+			},
+			func() float64 {
+				n, _ := openFDs()
+				return float64(n)
+			},
+		))
+	}
+	return m
+}
+
+// queryCollector exposes the query metrics as an unchecked collector. A
+// registry never accepts a metric name again with different labels, so the
+// label set could not change on reload if the metrics were registered
+// directly.
+type queryCollector struct {
+	m *Metrics
+}
+
+// Describe sends no descriptors, which makes the collector unchecked
+func (c queryCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect gathers the current query metrics
+func (c queryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.mu.RLock()
+	defer c.m.mu.RUnlock()
+	for _, collector := range c.m.queryCollectors() {
+		collector.Collect(ch)
+	}
+}
+
+// Configure sets the custom label names added to every query metric and
+// the exported metrics. Changing either recreates the metrics, which resets
+// their series; a new series limit alone applies to the existing series.
+func (m *Metrics) Configure(labelNames []string, opts Options) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.QueryDuration != nil && slices.Equal(labelNames, m.extraLabels) &&
+		slices.Equal(opts.Disabled, m.options.Disabled) && opts.Summaries == m.options.Summaries {
+		m.options.MaxSeries = opts.MaxSeries
+		return
+	}
+
+	m.extraLabels = slices.Clone(labelNames)
+	m.options = opts
+	m.options.Disabled = slices.Clone(opts.Disabled)
+	m.series = make(map[string]bool)
+	m.limitReported = false
+	m.ActiveSeries.Set(0)
+	m.disabled = make(map[string]bool, len(opts.Disabled))
+	for _, family := range opts.Disabled {
+		m.disabled[family] = true
+	}
+	names := append(slices.Clone(baseLabels), m.extraLabels...)
+
+	m.QueryDuration = m.newDurationVec("dns_query_duration_seconds", "Duration of DNS queries", names)
+	m.FailedQueryDuration = m.newDurationVec("dns_failed_query_duration_seconds", "Duration of failed DNS queries", names)
+	m.FirstQueryDuration = m.newDurationVec("dns_first_query_duration_seconds",
+		"Duration of the first of several successful DNS queries of a domain in a probe cycle", names)
+	m.UpstreamQueryDuration = m.newDurationVec("dns_upstream_query_duration_seconds",
+		"Duration of successful DNS queries by the address of the server that answered", append(slices.Clone(names), "upstream"))
+	m.LastQueryDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_last_query_duration_seconds",
+			Help: "Duration of the latest successful DNS query",
+		},
+		names,
+	)
+	m.resultAges = newResultAges(names)
+	m.QuerySuccess = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_query_success_total",
+			Help: "Total successful DNS queries",
+		},
+		names,
+	)
+	m.QueryFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_query_failures_total",
+			Help: "Total failed DNS queries",
+		},
+		names,
+	)
+
+	m.QueryTimeoutRatio = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dns_query_timeout_ratio",
+			Help:    "Duration of successful DNS queries as a fraction of their timeout",
+			Buckets: []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+		},
+		names,
+	)
+
+	m.AttemptDuration = m.newDurationVec("dns_attempt_duration_seconds",
+		"Duration of individual DNS query attempts, including retries", names)
+	m.AttemptSuccess = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_attempt_success_total",
+			Help: "Total successful DNS query attempts",
+		},
+		names,
+	)
+	m.AttemptFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_attempt_failures_total",
+			Help: "Total failed DNS query attempts",
+		},
+		names,
+	)
+
+	m.IterationStepDuration = m.newDurationVec("dns_iteration_step_duration_seconds",
+		"Duration of each step of iterative resolution by the zone asked", append(slices.Clone(names), "zone"))
+
+	m.AnswerGeo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_answer_geo_info",
+			Help: "Country and ASN of the addresses in the latest answer of a target",
+		},
+		append(slices.Clone(names), "country", "asn"),
+	)
+
+	m.FilteringActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_filtering_active",
+			Help: "Whether the server blocks test domains of the category (1) or not (0)",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "category"),
+	)
+
+	m.AnswerChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_answer_checks_total",
+			Help: "Total answers compared with the domain's reference answer",
+		},
+		names,
+	)
+	m.AnswerDivergences = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_answer_divergence_total",
+			Help: "Total answers that differed from the domain's reference answer",
+		},
+		names,
+	)
+
+	m.DNSSECChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_dnssec_checks_total",
+			Help: "Total DNS queries checked against the expected DNSSEC status",
+		},
+		names,
+	)
+	m.DNSSECMismatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_dnssec_mismatches_total",
+			Help: "Total DNS queries whose DNSSEC status did not match the expected status",
+		},
+		names,
+	)
+	m.BlockChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_block_checks_total",
+			Help: "Total DNS queries for domains expected to be blocked",
+		},
+		names,
+	)
+	m.BlockBypasses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_block_bypassed_total",
+			Help: "Total DNS queries for domains expected to be blocked that the server answered without blocking",
+		},
+		names,
+	)
+	m.SLABreaches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_sla_breaches_total",
+			Help: "Total DNS queries that took at least the latency threshold of the server, or failed",
+		},
+		append(slices.Clone(names), "threshold"),
+	)
+	m.SLAState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_sla_state",
+			Help: "State of the latest DNS query against the latency thresholds of the server: 0 within, 1 warning, 2 critical",
+		},
+		names,
+	)
+	m.FastOpenAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_tcp_fast_open_attempts_total",
+			Help: "Total TCP connections that sent the query with TCP Fast Open",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.FastOpenAccepted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_tcp_fast_open_accepted_total",
+			Help: "Total TCP connections whose server accepted the query sent in the SYN",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+
+	m.TransportLatencyDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_transport_latency_delta_seconds",
+			Help: "Duration of the latest TCP query minus that of the UDP query sent back to back",
+		},
+		names,
+	)
+	m.TransportUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_transport_up",
+			Help: "Whether the latest transport comparison query over the transport succeeded (1) or not (0)",
+		},
+		append(slices.Clone(names), "transport"),
+	)
+
+	m.EncryptedTransportDown = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_encrypted_transport_down",
+			Help: "Whether the encrypted server fails while plaintext DNS to it answers (1) or not (0)",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.PlaintextUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_plaintext_up",
+			Help: "Whether the latest plaintext DNS query to the encrypted server's address succeeded (1) or not (0)",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+
+	m.DoQErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_doq_errors_total",
+			Help: "Total DoQ error codes received by stream reset or connection close",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "kind", "code"),
+	)
+	m.DoQALPN = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_doq_alpn_info",
+			Help: "Application protocol negotiated by the latest DoQ connection to the server",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "alpn"),
+	)
+
+	m.HTTP3Advertised = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_doh_http3_advertised",
+			Help: "Whether the latest DoH response advertised HTTP/3 with Alt-Svc (1) or not (0)",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.ResponseHeader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_http_response_header_info",
+			Help: "Captured header values of the latest DoH response of the server",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "header", "value"),
+	)
+
+	m.Connections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_connections_total",
+			Help: "Total encrypted queries by connection: new (full handshake), resumed (TLS session resumption) or reused",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "connection"),
+	)
+	m.ConnectionLastDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_connection_last_duration_seconds",
+			Help: "Duration of the latest successful query attempt on each kind of connection",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "connection"),
+	)
+	m.OpenConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_open_connections",
+			Help: "Number of connections and sockets held open by the resolver of the server, idle or not",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+
+	m.ServerStat = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_server_stat",
+			Help: "Native statistics of the server, by software and statistic name as reported by the software",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "software", "stat"),
+	)
+	m.ServerStatsUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_server_stats_up",
+			Help: "Whether the latest scrape of the server's native statistics succeeded (1) or not (0)",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "software"),
+	)
+	m.ServerFingerprint = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_server_fingerprint_info",
+			Help: "Software, version and NSID of the server as of its latest fingerprint",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "software", "version", "nsid"),
+	)
+	m.AnycastInstance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_anycast_instance_info",
+			Help: "Instance of the anycast server, by NSID or id.server, that answered the latest check",
+		},
+		append(append(slices.Clone(serverLabels), m.extraLabels...), "instance"),
+	)
+	m.AnycastInstanceChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_anycast_instance_changes_total",
+			Help: "Total changes of the instance of the anycast server answering the exporter",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.QUICBlocked = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_quic_blocked",
+			Help: "Whether QUIC to the target keeps failing while TCP to the same address works (1) or not (0), suggesting blocked UDP on the path",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+
+	m.LargeResponseUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_large_response_up",
+			Help: "Whether the server sent the complete large response of the latest stress query (1) or not (0)",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.LargeResponseSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_large_response_size_bytes",
+			Help: "Size of the latest complete large response",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+	m.LargeResponseTruncated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dns_large_response_truncated",
+			Help: "Whether the latest large response was first answered truncated (1) or not (0)",
+		},
+		append(slices.Clone(serverLabels), m.extraLabels...),
+	)
+}
+
+// newDurationVec creates a histogram of durations in seconds, or a summary
+// if summaries are selected
+func (m *Metrics) newDurationVec(name, help string, labelNames []string) prometheus.ObserverVec {
+	if m.options.Summaries {
+		return prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{Name: name, Help: help, Objectives: summaryObjectives},
+			labelNames,
+		)
+	}
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{Name: name, Help: help, Buckets: prometheus.DefBuckets},
+		labelNames,
+	)
+}
+
+// queryCollectors returns the metrics carrying the configurable labels
+func (m *Metrics) queryCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.QueryDuration, m.FailedQueryDuration, m.FirstQueryDuration, m.UpstreamQueryDuration, m.LastQueryDuration, m.resultAges, m.QuerySuccess, m.QueryFailures, m.QueryTimeoutRatio,
+		m.AttemptDuration, m.AttemptSuccess, m.AttemptFailures,
+		m.IterationStepDuration, m.AnswerGeo, m.FilteringActive,
+		m.AnswerChecks, m.AnswerDivergences, m.DNSSECChecks, m.DNSSECMismatches,
+		m.BlockChecks, m.BlockBypasses, m.SLABreaches, m.SLAState,
+		m.FastOpenAttempts, m.FastOpenAccepted, m.TransportLatencyDelta, m.TransportUp,
+		m.EncryptedTransportDown, m.PlaintextUp, m.DoQErrors, m.DoQALPN, m.HTTP3Advertised,
+		m.ResponseHeader, m.Connections, m.ConnectionLastDuration, m.OpenConnections, m.ServerStat, m.ServerStatsUp,
+		m.ServerFingerprint, m.AnycastInstance, m.AnycastInstanceChanges, m.QUICBlocked,
+		m.LargeResponseUp, m.LargeResponseSize, m.LargeResponseTruncated,
+	}
+}
+
+// labelValues builds the label values for a query in configured label order.
+// Labels missing from the server are recorded as empty strings.
+func (m *Metrics) labelValues(domain, server, protocol string, labels map[string]string) []string {
+	return append([]string{domain}, m.serverLabelValues(server, protocol, labels)...)
+}
+
+// serverLabelValues builds the label values of a per-server metric in
+// configured label order
+func (m *Metrics) serverLabelValues(server, protocol string, labels map[string]string) []string {
+	values := make([]string, 0, len(serverLabels)+len(m.extraLabels))
+	values = append(values, server, protocol)
+	for _, name := range m.extraLabels {
+		values = append(values, labels[name])
+	}
+	return values
+}
+
+// admit returns true if the label combination may be recorded. Once the
+// series limit is reached, new combinations are refused and counted, while
+// known ones are still recorded. Metrics with labels beyond the target's
+// admit their full label values, as each value creates a series.
+func (m *Metrics) admit(values []string) bool {
+	key := strings.Join(values, "\xff")
+
+	m.seriesMu.Lock()
+	defer m.seriesMu.Unlock()
+	if m.series[key] {
+		return true
+	}
+	if m.options.MaxSeries > 0 && len(m.series) >= m.options.MaxSeries {
+		m.RejectedSeries.Inc()
+		if !m.limitReported {
+			logging.Errorf("Series limit of %d reached; refusing new series such as %v", m.options.MaxSeries, values)
+			m.limitReported = true
+		}
+		return false
+	}
+	m.series[key] = true
+	m.ActiveSeries.Set(float64(len(m.series)))
+	return true
+}
+
+// RecordQuery counts a DNS query as successful or failed
+func (m *Metrics) RecordQuery(domain, server, protocol string, labels map[string]string, success bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	if success {
+		m.QuerySuccess.WithLabelValues(values...).Inc()
+	} else {
+		m.QueryFailures.WithLabelValues(values...).Inc()
+	}
+}
+
+// RecordResultTime notes that a result of the target arrived now, for the
+// result age exported at scrape time
+func (m *Metrics) RecordResultTime(domain, server, protocol string, labels map[string]string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyResultAge] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.resultAges.set(values, time.Now())
+	}
+}
+
+// RecordAttempt records a single network exchange of a probe
+func (m *Metrics) RecordAttempt(domain, server, protocol string, labels map[string]string, duration float64, success bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyAttempts] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.AttemptDuration.WithLabelValues(values...).Observe(duration)
+	if success {
+		m.AttemptSuccess.WithLabelValues(values...).Inc()
+	} else {
+		m.AttemptFailures.WithLabelValues(values...).Inc()
+	}
+}
+
+// ObserveDuration records a query duration in seconds
+func (m *Metrics) ObserveDuration(domain, server, protocol string, labels map[string]string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyQueryDuration] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.QueryDuration.WithLabelValues(values...).Observe(duration)
+	}
+}
+
+// ObserveFirstDuration records the duration in seconds of the first of
+// several successful queries of a domain in a cycle
+func (m *Metrics) ObserveFirstDuration(domain, server, protocol string, labels map[string]string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyFirstQueryDuration] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.FirstQueryDuration.WithLabelValues(values...).Observe(duration)
+	}
+}
+
+// ObserveUpstreamDuration records the duration in seconds of a successful
+// query by the address that answered it
+func (m *Metrics) ObserveUpstreamDuration(domain, server, protocol string, labels map[string]string, upstream string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyUpstream] {
+		return
+	}
+
+	values := append(m.labelValues(domain, server, protocol, labels), upstream)
+	if m.admit(values) {
+		m.UpstreamQueryDuration.WithLabelValues(values...).Observe(duration)
+	}
+}
+
+// SetLastDuration records the duration in seconds of the latest successful
+// query
+func (m *Metrics) SetLastDuration(domain, server, protocol string, labels map[string]string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyLastQueryDuration] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.LastQueryDuration.WithLabelValues(values...).Set(duration)
+	}
+}
+
+// ObserveFailedDuration records the duration of a failed query in seconds
+func (m *Metrics) ObserveFailedDuration(domain, server, protocol string, labels map[string]string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyFailedQueryDuration] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.FailedQueryDuration.WithLabelValues(values...).Observe(duration)
+	}
+}
+
+// RecordTimeoutRatio records how much of its timeout a successful query used
+func (m *Metrics) RecordTimeoutRatio(domain, server, protocol string, labels map[string]string, ratio float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyTimeoutRatio] {
+		return
+	}
+
+	if values := m.labelValues(domain, server, protocol, labels); m.admit(values) {
+		m.QueryTimeoutRatio.WithLabelValues(values...).Observe(ratio)
+	}
+}
+
+// ObserveStep records the duration in seconds of an iterative resolution
+// step that asked the name servers of zone
+func (m *Metrics) ObserveStep(domain, server, protocol string, labels map[string]string, zone string, duration float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyIterationSteps] {
+		return
+	}
+
+	values := append(m.labelValues(domain, server, protocol, labels), zone)
+	if !m.admit(values) {
+		return
+	}
+	m.IterationStepDuration.WithLabelValues(values...).Observe(duration)
+}
+
+// AnswerLocation is the country and ASN of an answer address
+type AnswerLocation struct {
+	Country string
+	ASN     string
+}
+
+// SetAnswerGeo replaces the locations exported for a target with those of
+// its latest answer
+func (m *Metrics) SetAnswerGeo(domain, server, protocol string, labels map[string]string, locations []AnswerLocation) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyAnswerGeo] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.AnswerGeo.DeletePartialMatch(prometheus.Labels{"domain": domain, "server": server, "protocol": protocol})
+	for _, loc := range locations {
+		if geoValues := append(slices.Clone(values), loc.Country, loc.ASN); m.admit(geoValues) {
+			m.AnswerGeo.WithLabelValues(geoValues...).Set(1)
+		}
+	}
+}
+
+// SetFiltering records whether a server filters a category
+func (m *Metrics) SetFiltering(server, protocol string, labels map[string]string, category string, active bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyFiltering] {
+		return
+	}
+
+	value := 0.0
+	if active {
+		value = 1
+	}
+	values := append(m.serverLabelValues(server, protocol, labels), category)
+	if !m.admit(values) {
+		return
+	}
+	m.FilteringActive.WithLabelValues(values...).Set(value)
+}
+
+// RecordDivergence counts a comparison with a reference answer and whether
+// it diverged
+func (m *Metrics) RecordDivergence(domain, server, protocol string, labels map[string]string, diverged bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyDivergence] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.AnswerChecks.WithLabelValues(values...).Inc()
+	if diverged {
+		m.AnswerDivergences.WithLabelValues(values...).Inc()
+	}
+}
+
+// RecordDNSSEC counts a DNSSEC status check and whether it mismatched
+func (m *Metrics) RecordDNSSEC(domain, server, protocol string, labels map[string]string, mismatch bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyDNSSEC] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.DNSSECChecks.WithLabelValues(values...).Inc()
+	if mismatch {
+		m.DNSSECMismatches.WithLabelValues(values...).Inc()
+	}
+}
+
+// RecordBlock counts a check of a domain expected to be blocked and
+// whether the server bypassed the block
+func (m *Metrics) RecordBlock(domain, server, protocol string, labels map[string]string, bypassed bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyBlocking] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.BlockChecks.WithLabelValues(values...).Inc()
+	if bypassed {
+		m.BlockBypasses.WithLabelValues(values...).Inc()
+	}
+}
+
+// RecordSLA records which latency thresholds a query breached
+func (m *Metrics) RecordSLA(domain, server, protocol string, labels map[string]string, warning, critical bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilySLA] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	state := 0.0
+	if warning {
+		state = 1
+		if breachValues := append(slices.Clone(values), "warning"); m.admit(breachValues) {
+			m.SLABreaches.WithLabelValues(breachValues...).Inc()
+		}
+	}
+	if critical {
+		state = 2
+		if breachValues := append(slices.Clone(values), "critical"); m.admit(breachValues) {
+			m.SLABreaches.WithLabelValues(breachValues...).Inc()
+		}
+	}
+	m.SLAState.WithLabelValues(values...).Set(state)
+}
+
+// RecordFastOpen counts a TCP connection that used TCP Fast Open and
+// whether the server accepted the query sent in the SYN
+func (m *Metrics) RecordFastOpen(server, protocol string, labels map[string]string, accepted bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyFastOpen] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.FastOpenAttempts.WithLabelValues(values...).Inc()
+	if accepted {
+		m.FastOpenAccepted.WithLabelValues(values...).Inc()
+	}
+}
+
+// SetTransportComparison records the outcome of querying a target over UDP
+// and TCP back to back. The latency delta is removed unless both succeeded.
+func (m *Metrics) SetTransportComparison(domain, server, protocol string, labels map[string]string, udp, tcp bool, delta float64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyTransports] {
+		return
+	}
+
+	values := m.labelValues(domain, server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	for transport, up := range map[string]bool{"udp": udp, "tcp": tcp} {
+		value := 0.0
+		if up {
+			value = 1
+		}
+		if transportValues := append(slices.Clone(values), transport); m.admit(transportValues) {
+			m.TransportUp.WithLabelValues(transportValues...).Set(value)
+		}
+	}
+	if udp && tcp {
+		m.TransportLatencyDelta.WithLabelValues(values...).Set(delta)
+	} else {
+		m.TransportLatencyDelta.DeleteLabelValues(values...)
+	}
+}
+
+// SetDowngrade records the outcome of a downgrade check of an encrypted
+// server
+func (m *Metrics) SetDowngrade(server, protocol string, labels map[string]string, downgraded, plaintextUp bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyDowngrade] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	down, up := 0.0, 0.0
+	if downgraded {
+		down = 1
+	}
+	if plaintextUp {
+		up = 1
+	}
+	m.EncryptedTransportDown.WithLabelValues(values...).Set(down)
+	m.PlaintextUp.WithLabelValues(values...).Set(up)
+}
+
+// RecordDoQError counts a DoQ error code a server signalled; kind is
+// "stream_reset" or "connection_close"
+func (m *Metrics) RecordDoQError(server, protocol string, labels map[string]string, kind, code string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyDoQErrors] {
+		return
+	}
+
+	values := append(m.serverLabelValues(server, protocol, labels), kind, code)
+	if !m.admit(values) {
+		return
+	}
+	m.DoQErrors.WithLabelValues(values...).Inc()
+}
+
+// SetDoQALPN replaces the application protocol exported for a target with
+// the one negotiated by its latest DoQ connection
+func (m *Metrics) SetDoQALPN(server, protocol string, labels map[string]string, alpn string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyDoQALPN] {
+		return
+	}
+
+	values := append(m.serverLabelValues(server, protocol, labels), alpn)
+	if !m.admit(values) {
+		return
+	}
+	m.DoQALPN.DeletePartialMatch(prometheus.Labels{"server": server, "protocol": protocol})
+	m.DoQALPN.WithLabelValues(values...).Set(1)
+}
+
+// SetHTTP3Advertised records whether a DoH server advertised HTTP/3
+func (m *Metrics) SetHTTP3Advertised(server, protocol string, labels map[string]string, advertised bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyAltSvc] {
+		return
+	}
+
+	value := 0.0
+	if advertised {
+		value = 1
+	}
+	if values := m.serverLabelValues(server, protocol, labels); m.admit(values) {
+		m.HTTP3Advertised.WithLabelValues(values...).Set(value)
+	}
+}
+
+// SetResponseHeaders replaces the header values exported for a target with
+// those captured from its latest DoH response. Header names are lowercased.
+func (m *Metrics) SetResponseHeaders(server, protocol string, labels map[string]string, headers map[string]string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyResponseHeaders] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.ResponseHeader.DeletePartialMatch(prometheus.Labels{"server": server, "protocol": protocol})
+	for name, value := range headers {
+		if headerValues := append(slices.Clone(values), strings.ToLower(name), value); m.admit(headerValues) {
+			m.ResponseHeader.WithLabelValues(headerValues...).Set(1)
+		}
+	}
+}
+
+// RecordConnection counts a query attempt on a connection of the given kind
+// ("new", "resumed" or "reused") and records its duration if it succeeded
+func (m *Metrics) RecordConnection(server, protocol string, labels map[string]string, connection string, duration float64, success bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyConnections] {
+		return
+	}
+
+	values := append(m.serverLabelValues(server, protocol, labels), connection)
+	if !m.admit(values) {
+		return
+	}
+	m.Connections.WithLabelValues(values...).Inc()
+	if success {
+		m.ConnectionLastDuration.WithLabelValues(values...).Set(duration)
+	}
+}
+
+// SetOpenConnections records the number of connections and sockets held
+// open by the resolver of a server
+func (m *Metrics) SetOpenConnections(server, protocol string, labels map[string]string, open int) {
+	if m == nil {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyConnections] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.OpenConnections.WithLabelValues(values...).Set(float64(open))
+}
+
+// SetServerStats replaces the native statistics of a server with those of
+// the latest scrape, which are dropped if it failed. Every statistic is a
+// series of its own, counted against the series limit.
+func (m *Metrics) SetServerStats(server, protocol string, labels map[string]string, software string, stats map[string]float64, up bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyServerStats] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.ServerStat.DeletePartialMatch(prometheus.Labels{"server": server, "protocol": protocol})
+	for name, value := range stats {
+		statValues := append(slices.Clone(values), software, name)
+		if m.admit(statValues) {
+			m.ServerStat.WithLabelValues(statValues...).Set(value)
+		}
+	}
+	upValue := 0.0
+	if up {
+		upValue = 1
+	}
+	if upValues := append(values, software); m.admit(upValues) {
+		m.ServerStatsUp.WithLabelValues(upValues...).Set(upValue)
+	}
+}
+
+// SetFingerprint replaces the fingerprint of a server
+func (m *Metrics) SetFingerprint(server, protocol string, labels map[string]string, software, version, nsid string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyFingerprint] {
+		return
+	}
+
+	values := append(m.serverLabelValues(server, protocol, labels), software, version, nsid)
+	if !m.admit(values) {
+		return
+	}
+	m.ServerFingerprint.DeletePartialMatch(prometheus.Labels{"server": server, "protocol": protocol})
+	m.ServerFingerprint.WithLabelValues(values...).Set(1)
+}
+
+// SetOpenResolvers replaces the findings of the open resolver scan, keyed
+// by the address of each server that answered
+func (m *Metrics) SetOpenResolvers(findings map[string]bool) {
+	if m == nil {
+		return
+	}
+	m.OpenResolver.Reset()
+	for address, open := range findings {
+		value := 0.0
+		if open {
+			value = 1
+		}
+		m.OpenResolver.WithLabelValues(address).Set(value)
+	}
+}
+
+// SetAnycastInstance replaces the instance answering for an anycast
+// server, and counts a change of instance
+func (m *Metrics) SetAnycastInstance(server, protocol string, labels map[string]string, instance string, changed bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyAnycast] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	m.AnycastInstance.DeletePartialMatch(prometheus.Labels{"server": server, "protocol": protocol})
+	if instanceValues := append(slices.Clone(values), instance); m.admit(instanceValues) {
+		m.AnycastInstance.WithLabelValues(instanceValues...).Set(1)
+	}
+	if changed {
+		m.AnycastInstanceChanges.WithLabelValues(values...).Inc()
+	}
+}
+
+// SetQUICBlocked records whether QUIC to a target appears blocked
+func (m *Metrics) SetQUICBlocked(server, protocol string, labels map[string]string, blocked bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyQUICBlocked] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	value := 0.0
+	if blocked {
+		value = 1
+	}
+	m.QUICBlocked.WithLabelValues(values...).Set(value)
+}
+
+// SetLargeResponse records the outcome of a large response query. The size
+// of failed queries is left unchanged.
+func (m *Metrics) SetLargeResponse(server, protocol string, labels map[string]string, up bool, size int, truncated bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.disabled[FamilyLargeResponse] {
+		return
+	}
+
+	values := m.serverLabelValues(server, protocol, labels)
+	if !m.admit(values) {
+		return
+	}
+	upValue, truncatedValue := 0.0, 0.0
+	if up {
+		upValue = 1
+		m.LargeResponseSize.WithLabelValues(values...).Set(float64(size))
+	}
+	if truncated {
+		truncatedValue = 1
+	}
+	m.LargeResponseUp.WithLabelValues(values...).Set(upValue)
+	m.LargeResponseTruncated.WithLabelValues(values...).Set(truncatedValue)
+}
+
+// Heartbeat records scheduler activity
+func (m *Metrics) Heartbeat() {
+	if m == nil {
+		return
+	}
+	m.SchedulerHeartbeat.SetToCurrentTime()
+}
+
+// CycleOverrun counts a probe cycle cut short by its deadline
+func (m *Metrics) CycleOverrun() {
+	if m == nil {
+		return
+	}
+	m.CycleOverruns.Inc()
+}
+
+// ClockJump counts a measurement discarded after a wall clock jump
+func (m *Metrics) ClockJump() {
+	if m == nil {
+		return
+	}
+	m.ClockJumps.Inc()
+}
+
+// WatchdogCancel counts a probe abandoned by the watchdog
+func (m *Metrics) WatchdogCancel(server, protocol string) {
+	if m == nil {
+		return
+	}
+	m.WatchdogCancels.WithLabelValues(server, protocol).Inc()
+}
+
+// OverlapSkip counts a probe skipped while an earlier one was in flight
+func (m *Metrics) OverlapSkip(server, protocol string) {
+	if m == nil {
+		return
+	}
+	m.OverlapSkips.WithLabelValues(server, protocol).Inc()
+}
+
+// SetPaused reports whether probing is paused
+func (m *Metrics) SetPaused(paused bool) {
+	if m == nil {
+		return
+	}
+	if paused {
+		m.ProbingPaused.Set(1)
+	} else {
+		m.ProbingPaused.Set(0)
+	}
+}
+
+// SetLeader reports whether this replica is the elected leader
+func (m *Metrics) SetLeader(leader bool) {
+	if m == nil {
+		return
+	}
+	if leader {
+		m.Leader.Set(1)
+	} else {
+		m.Leader.Set(0)
+	}
+}
+
+// FederatedTarget is the cross-site view of a target for a domain
+type FederatedTarget struct {
+	Domain   string
+	Server   string
+	Protocol string
+
+	Sites         int
+	SuccessRatio  float64
+	LatencySpread float64
+}
+
+// SetFederation replaces the cross-site view with targets
+func (m *Metrics) SetFederation(targets []FederatedTarget) {
+	if m == nil {
+		return
+	}
+	m.FederationSites.Reset()
+	m.FederationSuccessRatio.Reset()
+	m.FederationLatencySpread.Reset()
+	for _, t := range targets {
+		m.FederationSites.WithLabelValues(t.Domain, t.Server, t.Protocol).Set(float64(t.Sites))
+		m.FederationSuccessRatio.WithLabelValues(t.Domain, t.Server, t.Protocol).Set(t.SuccessRatio)
+		m.FederationLatencySpread.WithLabelValues(t.Domain, t.Server, t.Protocol).Set(t.LatencySpread)
+	}
+}
+
+// ProviderSummary aggregates the latest probes of the servers of a provider
+// over a protocol. Latency is only meaningful if Successes is not zero.
+type ProviderSummary struct {
+	Provider string
+	Protocol string
+
+	Probes    int
+	Successes int
+	Latency   float64
+}
+
+// SetProviders replaces the provider aggregates with providers
+func (m *Metrics) SetProviders(providers []ProviderSummary) {
+	if m == nil {
+		return
+	}
+	m.ProviderSuccessRatio.Reset()
+	m.ProviderLatency.Reset()
+	for _, p := range providers {
+		if p.Probes == 0 {
+			continue
+		}
+		m.ProviderSuccessRatio.WithLabelValues(p.Provider, p.Protocol).Set(float64(p.Successes) / float64(p.Probes))
+		if p.Successes > 0 {
+			m.ProviderLatency.WithLabelValues(p.Provider, p.Protocol).Set(p.Latency)
+		}
+	}
+}
+
+// SetPeersUp replaces the pull status of the peers, keyed by peer
+func (m *Metrics) SetPeersUp(peers map[string]bool) {
+	if m == nil {
+		return
+	}
+	m.FederationPeerUp.Reset()
+	for peer, up := range peers {
+		value := 0.0
+		if up {
+			value = 1
+		}
+		m.FederationPeerUp.WithLabelValues(peer).Set(value)
+	}
+}
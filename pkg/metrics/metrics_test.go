@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMultipleInstances(t *testing.T) {
+	a := New(prometheus.NewRegistry())
+	b := New(prometheus.NewRegistry())
+
+	a.RecordQuery("example.com", "192.0.2.1:53", "do53-udp", nil, true)
+	if got := testutil.ToFloat64(a.QuerySuccess.WithLabelValues("example.com", "192.0.2.1:53", "do53-udp")); got != 1 {
+		t.Errorf("Expected 1 success on first instance, got %v", got)
+	}
+	if got := testutil.CollectAndCount(b.QuerySuccess); got != 0 {
+		t.Errorf("Expected no series on second instance, got %d", got)
+	}
+}
+
+func TestConfigureLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+	m.Configure([]string{"site"}, Options{})
+
+	m.RecordQuery("example.com", "192.0.2.1:53", "do53-udp", map[string]string{"site": "fra1"}, false)
+	if got := testutil.ToFloat64(m.QueryFailures.WithLabelValues("example.com", "192.0.2.1:53", "do53-udp", "fra1")); got != 1 {
+		t.Errorf("Expected 1 failure with site label, got %v", got)
+	}
+
+	m.Configure(nil, Options{})
+	m.RecordQuery("example.com", "192.0.2.1:53", "do53-udp", nil, true)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "dns_query_failures_total" {
+			t.Error("Expected series with the old label set to be dropped")
+		}
+		if family.GetName() == "dns_query_success_total" && len(family.GetMetric()[0].GetLabel()) != 3 {
+			t.Errorf("Expected 3 labels after reconfiguring, got %v", family.GetMetric()[0].GetLabel())
+		}
+	}
+}
+
+func TestConfigureOptions(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+	m.Configure(nil, Options{Disabled: []string{FamilyAttempts}, Summaries: true})
+
+	m.RecordAttempt("example.com", "192.0.2.1:53", "do53-udp", nil, 0.02, true)
+	m.ObserveDuration("example.com", "192.0.2.1:53", "do53-udp", nil, 0.02)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	found := false
+	for _, family := range families {
+		switch family.GetName() {
+		case "dns_attempt_duration_seconds", "dns_attempt_success_total":
+			t.Errorf("Expected disabled family %s not to be exported", family.GetName())
+		case "dns_query_duration_seconds":
+			found = true
+			if family.GetType() != dto.MetricType_SUMMARY {
+				t.Errorf("Expected a summary, got %v", family.GetType())
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected dns_query_duration_seconds to be exported")
+	}
+}
+
+func TestSeriesLimit(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.Configure(nil, Options{MaxSeries: 2})
+
+	m.RecordQuery("a.example", "192.0.2.1:53", "do53-udp", nil, true)
+	m.RecordQuery("b.example", "192.0.2.1:53", "do53-udp", nil, true)
+	m.RecordQuery("c.example", "192.0.2.1:53", "do53-udp", nil, true)
+	m.ObserveDuration("a.example", "192.0.2.1:53", "do53-udp", nil, 0.01)
+
+	if got := testutil.CollectAndCount(m.QuerySuccess); got != 2 {
+		t.Errorf("Expected 2 series within the limit, got %d", got)
+	}
+	if got := testutil.CollectAndCount(m.QueryDuration); got != 1 {
+		t.Errorf("Expected a known combination to be recorded, got %d series", got)
+	}
+	if got := testutil.ToFloat64(m.RejectedSeries); got != 1 {
+		t.Errorf("Expected 1 rejected recording, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ActiveSeries); got != 2 {
+		t.Errorf("Expected 2 active series, got %v", got)
+	}
+}
+
+func TestServerStatsSeriesLimit(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.Configure(nil, Options{MaxSeries: 3})
+
+	stats := map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4}
+	m.SetServerStats("192.0.2.1:53", "do53-udp", nil, "bind", stats, true)
+	if got := testutil.CollectAndCount(m.ServerStat); got != 2 {
+		t.Errorf("Expected 2 statistics within the limit, got %d", got)
+	}
+	// The up series of the software is refused as well
+	if got := testutil.ToFloat64(m.RejectedSeries); got != 3 {
+		t.Errorf("Expected 3 rejected recordings, got %v", got)
+	}
+}
+
+func TestExtraLabelsSeriesLimit(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.Configure(nil, Options{MaxSeries: 2})
+
+	for _, upstream := range []string{"192.0.2.10", "192.0.2.11", "192.0.2.12"} {
+		m.ObserveUpstreamDuration("example.com", "192.0.2.1:53", "do53-udp", nil, upstream, 0.01)
+	}
+	if got := testutil.CollectAndCount(m.UpstreamQueryDuration); got != 2 {
+		t.Errorf("Expected 2 upstream series within the limit, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.RejectedSeries); got != 1 {
+		t.Errorf("Expected 1 rejected upstream, got %v", got)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.Heartbeat()
+	m.CycleOverrun()
+	m.ClockJump()
+	m.WatchdogCancel("192.0.2.1:53", "do53-udp")
+	m.SetPaused(true)
+	m.SetOpenConnections("192.0.2.1:53", "do53-udp", nil, 1)
+}
+
+func TestOpenFDs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("file descriptors are only counted reliably on Linux")
+	}
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := openFDs()
+	if err != nil {
+		t.Fatalf("openFDs failed: %v", err)
+	}
+	_ = f.Close()
+	after, _ := openFDs()
+	if after != before-1 {
+		t.Errorf("Expected %d open file descriptors after closing one, got %d", before-1, after)
+	}
+}
+
+func TestSetProviders(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	m.SetProviders([]ProviderSummary{
+		{Provider: "alpha", Protocol: "doh", Probes: 4, Successes: 3, Latency: 0.02},
+		{Provider: "beta", Protocol: "doh", Probes: 2},
+	})
+	if got := testutil.ToFloat64(m.ProviderSuccessRatio.WithLabelValues("alpha", "doh")); got != 0.75 {
+		t.Errorf("Expected success ratio 0.75, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.ProviderLatency); got != 1 {
+		t.Errorf("Expected latency only for the provider with successes, got %d series", got)
+	}
+
+	m.SetProviders(nil)
+	if got := testutil.CollectAndCount(m.ProviderSuccessRatio); got != 0 {
+		t.Errorf("Expected no series after reset, got %d", got)
+	}
+}
+
+func TestResultAge(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+	m.RecordResultTime("example.com", "192.0.2.1:53", "do53-udp", nil)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() != "dns_probe_result_age_seconds" {
+			continue
+		}
+		found = true
+		if age := family.GetMetric()[0].GetGauge().GetValue(); age < 0 || age > 5 {
+			t.Errorf("Expected a fresh result age, got %v", age)
+		}
+	}
+	if !found {
+		t.Error("Expected the result age to be exported")
+	}
+
+	m.Configure(nil, Options{Disabled: []string{FamilyResultAge}})
+	m.RecordResultTime("example.com", "192.0.2.1:53", "do53-udp", nil)
+	if got := testutil.CollectAndCount(m.resultAges); got != 0 {
+		t.Errorf("Expected no result age with the family disabled, got %d series", got)
+	}
+}
+
+func TestShardHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := New(registry)
+	servers := []string{"192.0.2.1:53", "192.0.2.2:53", "192.0.2.3:53", "192.0.2.4:53"}
+	for _, server := range servers {
+		m.RecordQuery("example.com", server, "do53-udp", nil, true)
+	}
+	handler := ShardHandler(registry, func() int { return 2 })
+
+	seen := 0
+	for shard := range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/metrics?shard=%d", shard), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for shard %d, got %d", shard, rec.Code)
+		}
+		body := rec.Body.String()
+		for _, server := range servers {
+			in := strings.Contains(body, fmt.Sprintf(`dns_query_success_total{domain="example.com",protocol="do53-udp",server="%s"}`, server))
+			if in != (ShardOf(server, 2) == shard) {
+				t.Errorf("Shard %d: expected %s to be served only by shard %d", shard, server, ShardOf(server, 2))
+			}
+			if in {
+				seen++
+			}
+		}
+		if hasActive := strings.Contains(body, "dnspulse_active_series"); hasActive != (shard == 0) {
+			t.Errorf("Shard %d: expected series without a server label only in shard 0", shard)
+		}
+	}
+	if seen != len(servers) {
+		t.Errorf("Expected every server in exactly one shard, saw %d of %d", seen, len(servers))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?shard=2", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a shard out of range, got %d", rec.Code)
+	}
+}
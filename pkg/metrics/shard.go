@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// ShardLabel is the label whose value assigns a series to a shard, so that
+// all series of a server are scraped together
+const ShardLabel = "server"
+
+// ShardOf returns the shard of shards that the series of server belong to
+func ShardOf(server string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(server))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardHandler serves the metrics of g like promhttp. While shards returns
+// more than 0, the shard query parameter selects the series of the servers
+// in one shard; series without a server label, such as the process
+// metrics, are in shard 0. Without the parameter every series is served.
+func ShardHandler(g prometheus.Gatherer, shards func() int) http.Handler {
+	all := promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		param := r.URL.Query().Get("shard")
+		if param == "" {
+			all.ServeHTTP(w, r)
+			return
+		}
+		n := shards()
+		shard, err := strconv.Atoi(param)
+		if err != nil || shard < 0 || shard >= n {
+			http.Error(w, fmt.Sprintf("invalid shard '%s' (metrics.shards is %d)", param, n), http.StatusBadRequest)
+			return
+		}
+		promhttp.HandlerFor(shardGatherer{g, shard, n}, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// shardGatherer gathers the series of one shard
+type shardGatherer struct {
+	gatherer prometheus.Gatherer
+	shard    int
+	shards   int
+}
+
+// Gather implements prometheus.Gatherer, leaving out families without
+// series in the shard
+func (s shardGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := s.gatherer.Gather()
+	var kept []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			if s.contains(metric) {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			kept = append(kept, family)
+		}
+	}
+	return kept, err
+}
+
+// contains returns true if the series belongs to the shard
+func (s shardGatherer) contains(metric *dto.Metric) bool {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == ShardLabel {
+			return ShardOf(label.GetValue(), s.shards) == s.shard
+		}
+	}
+	return s.shard == 0
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// http3Server returns the DoH3 target of a DoH server on the same port
+func http3Server(server config.DNSServer) config.DNSServer {
+	h3 := server
+	h3.Protocol = config.ProtocolDoH3
+	h3.AltSvc = ""
+	h3.TCP = nil
+	return h3
+}
+
+// recordAltSvc remembers whether the latest successful probe of a DoH
+// server advertised HTTP/3 on its own port
+func (p *Prober) recordAltSvc(res Result) {
+	port, ok := resolver.HTTP3Port(res.Last().AltSvc)
+	advertised := ok && port == res.Server.Port
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.advertised == nil {
+		p.advertised = make(map[string]bool)
+	}
+	key := serverKey(res.Server)
+	if advertised != p.advertised[key] {
+		logging.Infof("[%s] %s:%s HTTP/3 advertised: %v", res.Protocol, res.Server.Address, res.Server.Port, advertised)
+	}
+	p.advertised[key] = advertised
+}
+
+// advertisesHTTP3 returns true if the latest successful probe of the
+// target advertised HTTP/3 on its port
+func (p *Prober) advertisesHTTP3(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.advertised[key]
+}
+
+// checkAltSvc probes every due DoH server with alt_svc: probe over DoH3 for
+// each domain, while the server advertises HTTP/3. The results are
+// reported like those of regular probes, with the doh3 protocol.
+func (p *Prober) checkAltSvc(ctx context.Context, due map[string]bool) {
+	for _, domain := range p.config.Domains {
+		if !domain.IsEnabled() {
+			continue
+		}
+		for _, server := range p.config.DNSServers {
+			key := serverKey(server)
+			r, ok := p.http3[key]
+			if !ok || !due[key] || p.isDrained(key) || !p.advertisesHTTP3(key) {
+				continue
+			}
+			if p.Paused() {
+				return
+			}
+			if err := p.wait(ctx, key); err != nil {
+				return
+			}
+			if _, ok := p.probe(ctx, domain, http3Server(server), r, 0); !ok && ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(500 * time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"errors"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// errNoInstance is reported for servers that answered without identifying
+// the instance
+var errNoInstance = errors.New("server does not identify its instance")
+
+// CatchmentResult is the outcome of asking an anycast server which of its
+// instances answers. Changed is true if the instance differs from the one
+// that answered the previous check.
+type CatchmentResult struct {
+	Server   config.DNSServer
+	Protocol string
+	Instance string
+	Previous string
+	Changed  bool
+	Err      error
+}
+
+// WithCatchmentCallback registers fn to receive the result of every
+// catchment check. Like result callbacks, it runs on the probing goroutine.
+func WithCatchmentCallback(fn func(CatchmentResult)) Option {
+	return func(p *Prober) {
+		p.catchmentCallbacks = append(p.catchmentCallbacks, fn)
+	}
+}
+
+// instanceNames are the CHAOS names asked for the instance of servers not
+// sending an NSID, in order
+var instanceNames = []string{"id.server.", "hostname.bind."}
+
+// catchment asks a server for the identifier of the answering instance:
+// its NSID, or else its id.server or hostname.bind record. It returns false
+// if ctx was cancelled.
+func (p *Prober) catchment(ctx context.Context, key string, server config.DNSServer, r resolver.Resolver) (CatchmentResult, bool) {
+	res := CatchmentResult{Server: server, Protocol: r.Protocol()}
+	result, ok := p.exchange(ctx, key, server, r, nsidQuery())
+	if !ok {
+		return res, false
+	}
+	if result.Err != nil {
+		res.Err = result.Err
+		return res, true
+	}
+	if res.Instance = nsidOf(result.Response); res.Instance != "" {
+		return res, true
+	}
+
+	for _, name := range instanceNames {
+		result, ok := p.exchange(ctx, key, server, r, chaosQuery(name))
+		if !ok {
+			return res, false
+		}
+		if res.Instance = txtAnswer(result.Response); result.Err == nil && res.Instance != "" {
+			return res, true
+		}
+	}
+	res.Err = errNoInstance
+	return res, true
+}
+
+// checkCatchments asks every due anycast server which instance answers and
+// logs when it changes, e.g. after a routing change moved the exporter into
+// another instance's catchment
+func (p *Prober) checkCatchments(ctx context.Context, due map[string]bool) {
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || !server.Anycast || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() {
+			return
+		}
+
+		res, ok := p.catchment(ctx, key, server, r)
+		if !ok {
+			return
+		}
+		if res.Err != nil {
+			logging.Debugf("[%s] %s:%s catchment check failed: %v", res.Protocol, server.Address, server.Port, res.Err)
+		} else {
+			res.Previous, res.Changed = p.recordInstance(key, res.Instance)
+			if res.Changed {
+				logging.Infof("[%s] %s:%s answered by instance %s, previously %s",
+					res.Protocol, server.Address, server.Port, res.Instance, res.Previous)
+			}
+		}
+		for _, fn := range p.catchmentCallbacks {
+			fn(res)
+		}
+	}
+}
+
+// recordInstance remembers the instance answering a target and returns the
+// previous one, and true if there was a different one
+func (p *Prober) recordInstance(key, instance string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.instances == nil {
+		p.instances = make(map[string]string)
+	}
+	previous, ok := p.instances[key]
+	p.instances[key] = instance
+	return previous, ok && previous != instance
+}
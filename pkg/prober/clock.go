@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import "time"
+
+// maxClockJump is how far the wall clock may move apart from the monotonic
+// clock during a measurement before the measurement is discarded
+const maxClockJump = time.Second
+
+// clockJump returns how much further the wall clock moved than the
+// monotonic clock between start and now. Durations are measured on the
+// monotonic clock, which is immune to steps of the wall clock but, on
+// Linux, stops while the host is suspended; either shows up as a jump.
+func clockJump(start, now time.Time) time.Duration {
+	return now.Round(0).Sub(start.Round(0)) - now.Sub(start)
+}
+
+// jumped returns true if a clock jump is large enough that a measurement
+// spanning it cannot be trusted, e.g. after an NTP step or a VM resume
+func jumped(jump time.Duration) bool {
+	return jump > maxClockJump || jump < -maxClockJump
+}
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// DivergenceResult is the outcome of comparing a server's answer for a
+// domain with the domain's reference answer. Answers are summarized as
+// their sorted addresses, or as the rcode name for error responses.
+type DivergenceResult struct {
+	Domain   config.Domain
+	Server   config.DNSServer
+	Protocol string
+	Expected []string
+	Got      []string
+	Diverged bool
+}
+
+// Divergence is the latest diverging answer of a server for a domain
+type Divergence struct {
+	Target   string    `json:"target"`
+	View     string    `json:"view,omitempty"`
+	Domain   string    `json:"domain"`
+	Expected []string  `json:"expected"`
+	Got      []string  `json:"got"`
+	Time     time.Time `json:"time"`
+}
+
+// WithDivergenceCallback registers fn to receive the result of every
+// comparison with a reference answer. Like result callbacks, it runs on the
+// probing goroutine.
+func WithDivergenceCallback(fn func(DivergenceResult)) Option {
+	return func(p *Prober) {
+		p.divergenceCallbacks = append(p.divergenceCallbacks, fn)
+	}
+}
+
+// checkDivergence asks every due recursive server for each domain with a
+// reference and compares the answer with the reference answer, or with the
+// answers pinned for the server's view
+func (p *Prober) checkDivergence(ctx context.Context, due map[string]bool) {
+	for _, domain := range p.config.Domains {
+		if !domain.IsEnabled() || domain.Reference == nil {
+			continue
+		}
+		var fallback expectation
+		hasFallback := false
+		if ref := domain.Reference; len(ref.Answers) > 0 || ref.Resolver != nil {
+			fallback, hasFallback = p.expectedAnswer(ctx, domain)
+		}
+
+		for _, server := range p.config.DNSServers {
+			key := serverKey(server)
+			r, ok := p.resolvers[key]
+			if !ok || !due[key] || p.isDrained(key) || server.Authoritative {
+				continue
+			}
+			expected, ok := fallback, hasFallback
+			if answers, inView := domain.Reference.Views[server.View]; server.View != "" && inView {
+				expected, ok = pinned(answers), true
+			}
+			if !ok {
+				continue
+			}
+			if p.Paused() {
+				return
+			}
+			if err := p.wait(ctx, key); err != nil {
+				return
+			}
+			result := p.query(ctx, server, r, resolver.NewQuery(domain.Name, domain.ReferenceType()))
+			if ctx.Err() != nil {
+				return
+			}
+			if result.Err != nil {
+				continue
+			}
+
+			got := answerSummary(result.Response)
+			res := DivergenceResult{
+				Domain:   domain,
+				Server:   server,
+				Protocol: r.Protocol(),
+				Expected: expected.answers,
+				Got:      got,
+				Diverged: expected.diverges(got),
+			}
+			if res.Diverged {
+				logging.Warnf("[%s] %s:%s%s answered %s with %v, expected %v",
+					res.Protocol, server.Address, server.Port, viewSuffix(server.View), domain.Name, got, expected.answers)
+				p.recordDivergence(key, res)
+			}
+			for _, fn := range p.divergenceCallbacks {
+				fn(res)
+			}
+		}
+	}
+}
+
+// expectation is the reference answer of a domain
+type expectation struct {
+	answers []string
+	pinned  bool
+}
+
+// expectedAnswer returns the pinned answer of domain, or asks its reference
+// resolver. It returns false if the reference resolver failed.
+func (p *Prober) expectedAnswer(ctx context.Context, domain config.Domain) (expectation, bool) {
+	ref := domain.Reference
+	if len(ref.Answers) > 0 {
+		return pinned(ref.Answers), true
+	}
+	resp := p.queryReference(ctx, *ref.Resolver, domain.Name, domain.ReferenceType())
+	if resp == nil {
+		return expectation{}, false
+	}
+	return expectation{answers: answerSummary(resp)}, true
+}
+
+// pinned returns the expectation of pinned answers, addresses or response
+// codes, in the form of answerSummary
+func pinned(answers []string) expectation {
+	e := expectation{answers: make([]string, 0, len(answers)), pinned: true}
+	for _, answer := range answers {
+		if ip := net.ParseIP(answer); ip != nil {
+			e.answers = append(e.answers, ip.String())
+		} else {
+			e.answers = append(e.answers, strings.ToUpper(answer))
+		}
+	}
+	sort.Strings(e.answers)
+	return e
+}
+
+// viewSuffix returns " (view name)" for servers in a view, for logs
+func viewSuffix(view string) string {
+	if view == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (view %s)", view)
+}
+
+// diverges returns true if got does not match the expectation. Against
+// pinned addresses, every returned address must be pinned. Against a
+// reference resolver, the answers must share at least one address (or
+// rcode), which tolerates CDNs rotating addresses.
+func (e expectation) diverges(got []string) bool {
+	if len(got) == 0 || len(e.answers) == 0 {
+		return len(got) != len(e.answers)
+	}
+	if e.pinned {
+		for _, answer := range got {
+			if !slices.Contains(e.answers, answer) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, answer := range got {
+		if slices.Contains(e.answers, answer) {
+			return false
+		}
+	}
+	return true
+}
+
+// answerSummary returns the sorted distinct addresses of resp, or its rcode
+// name if it is an error response
+func answerSummary(resp *dns.Msg) []string {
+	if resp.Rcode != dns.RcodeSuccess {
+		return []string{dns.RcodeToString[resp.Rcode]}
+	}
+	var answers []string
+	for _, rr := range resp.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		default:
+			continue
+		}
+		if !slices.Contains(answers, ip.String()) {
+			answers = append(answers, ip.String())
+		}
+	}
+	sort.Strings(answers)
+	return answers
+}
+
+// recordDivergence keeps the latest divergence of a target for a domain
+func (p *Prober) recordDivergence(key string, res DivergenceResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.divergences == nil {
+		p.divergences = make(map[string]Divergence)
+	}
+	p.divergences[fmt.Sprintf("%s|%s", key, res.Domain.Name)] = Divergence{
+		Target:   key,
+		View:     res.Server.View,
+		Domain:   res.Domain.Name,
+		Expected: res.Expected,
+		Got:      res.Got,
+		Time:     time.Now(),
+	}
+}
+
+// Divergences returns the latest diverging answer of every target and
+// domain, sorted by target and domain
+func (p *Prober) Divergences() []Divergence {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	divergences := make([]Divergence, 0, len(p.divergences))
+	for _, d := range p.divergences {
+		divergences = append(divergences, d)
+	}
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].Target != divergences[j].Target {
+			return divergences[i].Target < divergences[j].Target
+		}
+		return divergences[i].Domain < divergences[j].Domain
+	})
+	return divergences
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+// Package prober periodically queries the configured domains against the
+// configured DNS servers and hands each outcome to result callbacks, so it
+// can be embedded into other agents with their own sinks:
+//
+//	cfg, err := config.Load("dnspulse.yml")
+//	if err != nil {
+//		return err
+//	}
+//	p, err := prober.New(cfg, prober.WithResultCallback(func(res prober.Result) {
+//		log.Printf("%s via %s: %v in %s", res.Hostname, res.Server.Address, res.Err, res.Duration)
+//	}))
+//	if err != nil {
+//		return err
+//	}
+//	defer p.Close()
+//
+//	for ctx.Err() == nil {
+//		p.Run(ctx)
+//		time.Sleep(p.Interval())
+//	}
+package prober
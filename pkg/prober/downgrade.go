@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// DowngradeResult is the outcome of checking an encrypted server's
+// plaintext path. Encrypted is true if any of the server's latest probes
+// succeeded.
+type DowngradeResult struct {
+	Server    config.DNSServer
+	Protocol  string
+	Encrypted bool
+	Plaintext resolver.QueryResult
+}
+
+// Downgraded returns true if only plaintext DNS works, which suggests that
+// the encrypted transport is blocked on the path rather than the server
+// being down
+func (r DowngradeResult) Downgraded() bool {
+	return !r.Encrypted && r.Plaintext.Err == nil
+}
+
+// WithDowngradeCallback registers fn to receive the result of every
+// downgrade check. Like result callbacks, it runs on the probing goroutine.
+func WithDowngradeCallback(fn func(DowngradeResult)) Option {
+	return func(p *Prober) {
+		p.downgradeCallbacks = append(p.downgradeCallbacks, fn)
+	}
+}
+
+// plaintext returns the Do53 over UDP path of an encrypted server
+func plaintext(server config.DNSServer) config.DNSServer {
+	do53 := server
+	do53.Protocol = config.ProtocolDo53UDP
+	do53.Port = resolver.DefaultPort(config.ProtocolDo53UDP)
+	do53.TLS = nil
+	do53.TCP = nil
+	return do53
+}
+
+// checkDowngrades asks every due server checking for downgrades for the
+// first enabled domain over Do53 and compares the outcome with the latest
+// probes of the encrypted transport
+func (p *Prober) checkDowngrades(ctx context.Context, due map[string]bool) {
+	var domain config.Domain
+	for _, d := range p.config.Domains {
+		if d.IsEnabled() {
+			domain = d
+			break
+		}
+	}
+	if domain.Name == "" {
+		return
+	}
+
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.companions[key]
+		if !ok || !server.DowngradeCheck || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() {
+			return
+		}
+		encrypted, ok := p.encryptedUp(key)
+		if !ok {
+			continue
+		}
+
+		result, done := p.compareQuery(ctx, key, domain, plaintext(server), r)
+		if !done {
+			return
+		}
+		res := DowngradeResult{Server: server, Protocol: p.resolvers[key].Protocol(), Encrypted: encrypted, Plaintext: result}
+		if res.Downgraded() {
+			logging.Warnf("[%s] %s:%s fails while plaintext DNS to it answers; encrypted transport may be blocked",
+				res.Protocol, server.Address, server.Port)
+		}
+		for _, fn := range p.downgradeCallbacks {
+			fn(res)
+		}
+	}
+}
+
+// encryptedUp returns true if any latest probe of the target succeeded. It
+// returns false as second value if the target has not been probed yet.
+func (p *Prober) encryptedUp(key string) (bool, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	probed := false
+	for _, domain := range p.config.Domains {
+		res, ok := p.latest[fmt.Sprintf("%s|%s", key, domain.Name)]
+		if !ok || !domain.IsEnabled() {
+			continue
+		}
+		if res.Success {
+			return true, true
+		}
+		probed = true
+	}
+	return false, probed
+}
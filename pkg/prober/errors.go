@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ProbeError details a failed probe of a target, so that failures can be
+// examined after the fact without verbose logging
+type ProbeError struct {
+	Target   string    `json:"target"`
+	Server   string    `json:"server"`
+	Protocol string    `json:"protocol"`
+	Domain   string    `json:"domain"`
+	Hostname string    `json:"hostname"`
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error"`
+
+	// Chain lists the error and the errors it wraps, outermost first
+	Chain []ErrorCause `json:"error_chain"`
+
+	// Attempts holds every attempt of the probe in order
+	Attempts []AttemptDetail `json:"attempts"`
+}
+
+// ErrorCause is one error of a chain of wrapped errors
+type ErrorCause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// AttemptDetail is the outcome of one attempt of a failed probe
+type AttemptDetail struct {
+	Duration   float64 `json:"duration_seconds"`
+	RemoteAddr string  `json:"remote_addr,omitempty"`
+	Rcode      string  `json:"rcode,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// recordError adds res to the recent failures of its target, dropping the
+// oldest beyond the configured history
+func (p *Prober) recordError(res Result) {
+	limit := p.config.ErrorHistory
+	if limit <= 0 {
+		return
+	}
+	key := serverKey(res.Server)
+	probeErr := ProbeError{
+		Target:   key,
+		Server:   fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port),
+		Protocol: res.Protocol,
+		Domain:   res.Domain.Name,
+		Hostname: res.Hostname,
+		Time:     time.Now(),
+		Error:    res.Err.Error(),
+		Chain:    errorChain(res.Err),
+	}
+	for _, attempt := range res.Attempts {
+		detail := AttemptDetail{
+			Duration:   attempt.Duration.Seconds(),
+			RemoteAddr: attempt.RemoteAddr,
+		}
+		if attempt.Response != nil {
+			detail.Rcode = dns.RcodeToString[attempt.Response.Rcode]
+		}
+		if attempt.Err != nil {
+			detail.Error = attempt.Err.Error()
+		}
+		probeErr.Attempts = append(probeErr.Attempts, detail)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.errors == nil {
+		p.errors = make(map[string][]ProbeError)
+	}
+	history := append(p.errors[key], probeErr)
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	p.errors[key] = history
+}
+
+// Errors returns the recent failures of the target with the given key, or
+// of every target when key is empty, sorted by target and newest first
+func (p *Prober) Errors(key string) ([]ProbeError, error) {
+	if _, ok := p.resolvers[key]; key != "" && !ok {
+		return nil, fmt.Errorf("unknown target: %s", key)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	errs := make([]ProbeError, 0)
+	for target, history := range p.errors {
+		if key == "" || target == key {
+			errs = append(errs, history...)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Target != errs[j].Target {
+			return errs[i].Target < errs[j].Target
+		}
+		return errs[i].Time.After(errs[j].Time)
+	})
+	return errs, nil
+}
+
+// errorChain unwraps err into its chain of causes
+func errorChain(err error) []ErrorCause {
+	var chain []ErrorCause
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, ErrorCause{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+	}
+	return chain
+}
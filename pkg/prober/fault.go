@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// errInjectedTimeout fails queries that faults time out
+var errInjectedTimeout = fmt.Errorf("injected fault: %w", os.ErrDeadlineExceeded)
+
+// faultyResolver injects the faults configured for a server into the
+// queries to it, and otherwise behaves like the resolver it wraps
+type faultyResolver struct {
+	resolver.Resolver
+	faults  config.Faults
+	timeout time.Duration
+	random  *random
+}
+
+// Query implements resolver.Resolver
+func (f *faultyResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return f.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+// Exchange delays the query by a random extra latency, then either lets it
+// time out without sending it, or sends it and answers SERVFAIL instead of
+// the response. The delay is part of the duration.
+func (f *faultyResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	var delay time.Duration
+	if f.faults.Latency > 0 {
+		delay = f.random.duration(time.Duration(f.faults.Latency))
+	}
+	roll := f.random.float64()
+	timeout := roll < f.faults.Timeout
+	if timeout {
+		delay += f.timeout
+	}
+
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return resolver.QueryResult{Duration: time.Since(start), Err: ctx.Err()}
+	case <-time.After(delay):
+	}
+	if timeout {
+		return resolver.QueryResult{Duration: time.Since(start), Err: errInjectedTimeout}
+	}
+
+	delayed := time.Since(start)
+	result := f.Resolver.Exchange(ctx, msg)
+	result.Duration += delayed
+	if roll < f.faults.Timeout+f.faults.Servfail && result.Err == nil {
+		resp := new(dns.Msg)
+		resp.SetRcode(msg, dns.RcodeServerFailure)
+		result.Response = resp
+	}
+	return result
+}
+
+// OpenConns passes on the count of the wrapped resolver
+func (f *faultyResolver) OpenConns() int {
+	if c, ok := f.Resolver.(resolver.ConnCounter); ok {
+		return c.OpenConns()
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// FilteringResult reports whether a server filters a category of test
+// domains
+type FilteringResult struct {
+	Server   config.DNSServer
+	Protocol string
+	Category string
+
+	// Checked is the number of test domains answered by both the server and
+	// the reference resolver
+	Checked int
+
+	// Blocked lists the test domains the server withheld
+	Blocked []string
+}
+
+// Active returns true if the server blocked any test domain of the category
+func (r FilteringResult) Active() bool {
+	return len(r.Blocked) > 0
+}
+
+// WithFilteringCallback registers fn to receive the result of every
+// filtering check. Like result callbacks, it runs on the probing goroutine.
+func WithFilteringCallback(fn func(FilteringResult)) Option {
+	return func(p *Prober) {
+		p.filteringCallbacks = append(p.filteringCallbacks, fn)
+	}
+}
+
+// checkFiltering asks every due recursive server for the test domains of
+// each filtering category and compares the answers with the reference
+// resolver. Domains the reference cannot resolve are not compared, and a
+// category with no comparable domain reports no result.
+func (p *Prober) checkFiltering(ctx context.Context, due map[string]bool) {
+	if !p.config.Filtering.Enabled() {
+		return
+	}
+	filtering := p.config.Filtering
+	references := make(map[string]bool)
+
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || !due[key] || p.isDrained(key) || server.Authoritative || !server.IsRecursive() {
+			continue
+		}
+
+		for _, category := range filtering.CategoryNames() {
+			res := FilteringResult{Server: server, Protocol: r.Protocol(), Category: category}
+			for _, name := range filtering.Categories[category] {
+				if p.Paused() {
+					return
+				}
+				resolvable, ok := references[name]
+				if !ok {
+					resolvable = p.referenceResolves(ctx, name)
+					references[name] = resolvable
+				}
+				if !resolvable {
+					continue
+				}
+
+				if err := p.wait(ctx, key); err != nil {
+					return
+				}
+				result := p.query(ctx, server, r, resolver.NewQuery(name, dns.TypeA))
+				if ctx.Err() != nil {
+					return
+				}
+				if result.Err != nil {
+					continue
+				}
+				res.Checked++
+				if blocked(result.Response) {
+					res.Blocked = append(res.Blocked, name)
+				}
+			}
+			if res.Checked == 0 {
+				continue
+			}
+
+			if res.Active() {
+				logging.Debugf("[%s] %s:%s filters %s: %v", res.Protocol, server.Address, server.Port, category, res.Blocked)
+			}
+			for _, fn := range p.filteringCallbacks {
+				fn(res)
+			}
+		}
+	}
+}
+
+// referenceResolves returns true if the reference resolver returns a
+// routable address for name
+func (p *Prober) referenceResolves(ctx context.Context, name string) bool {
+	resp := p.queryReference(ctx, p.config.Filtering.Reference, name, dns.TypeA)
+	return resp != nil && !blocked(resp)
+}
+
+// blocked returns true if resp withholds the addresses of its question: it
+// has an error rcode, no addresses, or only sinkhole addresses
+// (unspecified, loopback or private)
+func blocked(resp *dns.Msg) bool {
+	if resp.Rcode != dns.RcodeSuccess {
+		return true
+	}
+	for _, rr := range resp.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		default:
+			continue
+		}
+		if !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsPrivate() {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedAs returns true if resp blocks its question the way b expects:
+// with b's response code or only b's walled garden addresses, or like
+// blocked when b sets neither
+func blockedAs(resp *dns.Msg, b *config.Blocked) bool {
+	if b.Rcode == "" && len(b.Answers) == 0 {
+		return blocked(resp)
+	}
+	if b.Rcode != "" && resp.Rcode == dns.StringToRcode[strings.ToUpper(b.Rcode)] {
+		return true
+	}
+	if len(b.Answers) == 0 || resp.Rcode != dns.RcodeSuccess {
+		return false
+	}
+	walled := false
+	for _, rr := range resp.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		default:
+			continue
+		}
+		if !slices.ContainsFunc(b.Answers, func(answer string) bool { return ip.Equal(net.ParseIP(answer)) }) {
+			return false
+		}
+		walled = true
+	}
+	return walled
+}
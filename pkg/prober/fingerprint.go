@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// SoftwareUnknown is reported for servers that could not be identified
+const SoftwareUnknown = "unknown"
+
+// Fingerprint identifies the software of a server
+type Fingerprint struct {
+	// Software is a short name such as "bind" or "unbound", or
+	// SoftwareUnknown
+	Software string
+
+	// Version is the version string the server reported, empty if hidden
+	Version string
+
+	// NSID is the name server identifier (RFC 5001), empty if not sent
+	NSID string
+}
+
+// FingerprintResult is the outcome of fingerprinting a server. Err is set
+// if the server answered none of the queries.
+type FingerprintResult struct {
+	Server      config.DNSServer
+	Protocol    string
+	Fingerprint Fingerprint
+	Err         error
+}
+
+// WithFingerprintCallback registers fn to receive the result of every
+// fingerprint. Like result callbacks, it runs on the probing goroutine.
+func WithFingerprintCallback(fn func(FingerprintResult)) Option {
+	return func(p *Prober) {
+		p.fingerprintCallbacks = append(p.fingerprintCallbacks, fn)
+	}
+}
+
+// versionPatterns tell the software from the version it reports, e.g.
+// "unbound 1.19.0" or, for BIND, a bare "9.18.24-1-Debian"
+var versionPatterns = []struct {
+	software string
+	pattern  *regexp.Regexp
+}{
+	{"bind", regexp.MustCompile(`^(?i:bind\s*)?9\.\d+`)},
+	{"unbound", regexp.MustCompile(`(?i)^unbound\b`)},
+	{"nsd", regexp.MustCompile(`(?i)^nsd\b`)},
+	{"powerdns", regexp.MustCompile(`(?i)^powerdns\b`)},
+	{"knot-resolver", regexp.MustCompile(`(?i)^knot[ -]resolver\b`)},
+	{"knot", regexp.MustCompile(`(?i)^knot\b`)},
+	{"dnsmasq", regexp.MustCompile(`(?i)^dnsmasq\b`)},
+	{"coredns", regexp.MustCompile(`(?i)^coredns\b`)},
+	{"microsoft", regexp.MustCompile(`(?i)^microsoft\b`)},
+}
+
+// chaosHints tell the software of servers hiding their version by the
+// CHAOS names only it answers, in the spirit of fpdns
+var chaosHints = []struct {
+	name     string
+	software string
+}{
+	{"authors.bind.", "bind"},
+	{"cachesize.bind.", "dnsmasq"},
+}
+
+// softwareOf returns the software reporting version
+func softwareOf(version string) string {
+	for _, p := range versionPatterns {
+		if p.pattern.MatchString(version) {
+			return p.software
+		}
+	}
+	return SoftwareUnknown
+}
+
+// chaosQuery builds a TXT query for a CHAOS class name, without recursion
+func chaosQuery(name string) *dns.Msg {
+	msg := resolver.NewQuery(name, dns.TypeTXT)
+	msg.Question[0].Qclass = dns.ClassCHAOS
+	msg.RecursionDesired = false
+	return msg
+}
+
+// nsidQuery builds a query for the root NS set asking for the NSID
+func nsidQuery() *dns.Msg {
+	msg := resolver.NewQuery(".", dns.TypeNS)
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+	return msg
+}
+
+// txtAnswer returns the joined strings of the first TXT record of resp
+func txtAnswer(resp *dns.Msg) string {
+	if resp == nil || resp.Rcode != dns.RcodeSuccess {
+		return ""
+	}
+	for _, rr := range resp.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			return strings.Join(txt.Txt, "")
+		}
+	}
+	return ""
+}
+
+// nsidOf returns the NSID of resp as text if printable, and in hexadecimal
+// otherwise
+func nsidOf(resp *dns.Msg) string {
+	if resp == nil {
+		return ""
+	}
+	opt := resp.IsEdns0()
+	if opt == nil {
+		return ""
+	}
+	for _, o := range opt.Option {
+		nsid, ok := o.(*dns.EDNS0_NSID)
+		if !ok {
+			continue
+		}
+		raw, err := hex.DecodeString(nsid.Nsid)
+		if err != nil || strings.IndexFunc(string(raw), func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+			return nsid.Nsid
+		}
+		return string(raw)
+	}
+	return ""
+}
+
+// fingerprint queries a server for its version, NSID and, if the version
+// is hidden, the CHAOS names that give its software away. It returns false
+// if ctx was cancelled.
+func (p *Prober) fingerprint(ctx context.Context, key string, server config.DNSServer, r resolver.Resolver) (FingerprintResult, bool) {
+	res := FingerprintResult{Server: server, Protocol: r.Protocol()}
+	answered := false
+	ask := func(msg *dns.Msg) (*dns.Msg, bool) {
+		result, ok := p.exchange(ctx, key, server, r, msg)
+		if ok && result.Err == nil {
+			answered = true
+		} else if ok {
+			res.Err = result.Err
+		}
+		return result.Response, ok
+	}
+
+	for _, name := range []string{"version.bind.", "version.server."} {
+		resp, ok := ask(chaosQuery(name))
+		if !ok {
+			return res, false
+		}
+		if res.Fingerprint.Version = txtAnswer(resp); res.Fingerprint.Version != "" {
+			break
+		}
+	}
+	res.Fingerprint.Software = softwareOf(res.Fingerprint.Version)
+	for _, hint := range chaosHints {
+		if res.Fingerprint.Software != SoftwareUnknown {
+			break
+		}
+		resp, ok := ask(chaosQuery(hint.name))
+		if !ok {
+			return res, false
+		}
+		if txtAnswer(resp) != "" {
+			res.Fingerprint.Software = hint.software
+		}
+	}
+
+	resp, ok := ask(nsidQuery())
+	if !ok {
+		return res, false
+	}
+	res.Fingerprint.NSID = nsidOf(resp)
+	if answered {
+		res.Err = nil
+	}
+	return res, true
+}
+
+// checkFingerprints fingerprints every due server that asks for it and
+// logs changes of its software or version
+func (p *Prober) checkFingerprints(ctx context.Context, due map[string]bool) {
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || !server.Fingerprint || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() {
+			return
+		}
+
+		res, ok := p.fingerprint(ctx, key, server, r)
+		if !ok {
+			return
+		}
+		if res.Err != nil {
+			logging.Debugf("[%s] %s:%s fingerprint failed: %v", res.Protocol, server.Address, server.Port, res.Err)
+		} else if p.recordFingerprint(key, res.Fingerprint) {
+			logging.Infof("[%s] %s:%s runs %s %s", res.Protocol, server.Address, server.Port,
+				res.Fingerprint.Software, res.Fingerprint.Version)
+		}
+		for _, fn := range p.fingerprintCallbacks {
+			fn(res)
+		}
+	}
+}
+
+// recordFingerprint remembers the latest fingerprint of a target and
+// returns true if its software or version differs from the previous one
+func (p *Prober) recordFingerprint(key string, fp Fingerprint) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fingerprints == nil {
+		p.fingerprints = make(map[string]Fingerprint)
+	}
+	prev, ok := p.fingerprints[key]
+	p.fingerprints[key] = fp
+	return !ok || prev.Software != fp.Software || prev.Version != fp.Version
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// LargeResponseResult is the outcome of asking a server for a large
+// response. A truncated Do53 over UDP answer is asked again over TCP, as a
+// client would.
+type LargeResponseResult struct {
+	Server   config.DNSServer
+	Protocol string
+	Query    resolver.QueryResult
+
+	// Fallback is the TCP query after a truncated answer, nil if there was
+	// none
+	Fallback *resolver.QueryResult
+}
+
+// Truncated returns true if the first answer had the TC bit set
+func (r LargeResponseResult) Truncated() bool {
+	return r.Query.Err == nil && r.Query.Response.Truncated
+}
+
+// Final returns the TCP query after a truncated answer, or else the first
+// query
+func (r LargeResponseResult) Final() resolver.QueryResult {
+	if r.Fallback != nil {
+		return *r.Fallback
+	}
+	return r.Query
+}
+
+// Success returns true if the server sent the complete response without
+// an error code
+func (r LargeResponseResult) Success() bool {
+	final := r.Final()
+	return final.Err == nil && !final.Response.Truncated && final.Response.Rcode == dns.RcodeSuccess
+}
+
+// Size returns the size in bytes of the final response, 0 without one
+func (r LargeResponseResult) Size() int {
+	if final := r.Final(); final.Err == nil {
+		return final.Response.Len()
+	}
+	return 0
+}
+
+// WithLargeResponseCallback registers fn to receive the result of every
+// large response query. Like result callbacks, it runs on the probing
+// goroutine.
+func WithLargeResponseCallback(fn func(LargeResponseResult)) Option {
+	return func(p *Prober) {
+		p.largeCallbacks = append(p.largeCallbacks, fn)
+	}
+}
+
+// checkLargeResponses asks every due server for the configured large
+// response with its EDNS buffer size, and asks Do53 over UDP servers again
+// over TCP if the answer was truncated
+func (p *Prober) checkLargeResponses(ctx context.Context, due map[string]bool) {
+	large := p.config.LargeResponse
+	if !large.Enabled() {
+		return
+	}
+
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() {
+			return
+		}
+
+		res := LargeResponseResult{Server: server, Protocol: r.Protocol()}
+		var done bool
+		if res.Query, done = p.largeQuery(ctx, key, server, r, large); !done {
+			return
+		}
+		if tcp, ok := p.fallbacks[key]; ok && res.Truncated() {
+			fallback, done := p.largeQuery(ctx, key, otherTransport(server), tcp, large)
+			if !done {
+				return
+			}
+			res.Fallback = &fallback
+		}
+
+		if res.Success() {
+			logging.Debugf("[%s] %s:%s answered %s %s with %d bytes (truncated: %v)",
+				res.Protocol, server.Address, server.Port, large.QType, large.Name, res.Size(), res.Truncated())
+		} else {
+			logging.Debugf("[%s] %s:%s failed to answer %s %s completely: %v",
+				res.Protocol, server.Address, server.Port, large.QType, large.Name, res.Final().Err)
+		}
+		for _, fn := range p.largeCallbacks {
+			fn(res)
+		}
+	}
+}
+
+// largeQuery asks r for the large response, within the rate limits of the
+// target with the given key. It returns false if ctx was cancelled.
+func (p *Prober) largeQuery(ctx context.Context, key string, server config.DNSServer, r resolver.Resolver, large config.LargeResponse) (resolver.QueryResult, bool) {
+	msg := resolver.AcquireQuery(large.Name, large.QueryType())
+	msg.RecursionDesired = !server.Authoritative
+	msg.SetEdns0(uint16(large.BufferSize), false)
+	result, ok := p.exchange(ctx, key, server, r, msg)
+	if result.Err != errWatchdog {
+		resolver.ReleaseQuery(msg)
+	}
+	return result, ok
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LatestResult is the outcome of the latest probe of a target for a domain,
+// as exchanged between federated exporters
+type LatestResult struct {
+	Site     string    `json:"site"`
+	Target   string    `json:"target"`
+	Server   string    `json:"server"`
+	Protocol string    `json:"protocol"`
+	Domain   string    `json:"domain"`
+	Success  bool      `json:"success"`
+	Duration float64   `json:"duration_seconds"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// recordLatest keeps res as the latest result of its target for its domain
+func (p *Prober) recordLatest(res Result) {
+	latest := p.latestResult(res)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.latest == nil {
+		p.latest = make(map[string]LatestResult)
+	}
+	p.latest[fmt.Sprintf("%s|%s", latest.Target, res.Domain.Name)] = latest
+}
+
+// latestResult summarizes res
+func (p *Prober) latestResult(res Result) LatestResult {
+	latest := LatestResult{
+		Site:     p.config.Site,
+		Target:   serverKey(res.Server),
+		Server:   fmt.Sprintf("%s:%s", res.Server.Address, res.Server.Port),
+		Protocol: res.Protocol,
+		Domain:   res.Domain.Name,
+		Success:  res.Success(),
+		Duration: res.Duration.Seconds(),
+		Time:     time.Now(),
+	}
+	if res.Err != nil {
+		latest.Error = res.Err.Error()
+	}
+	return latest
+}
+
+// LatestResults returns the latest result of every target and domain,
+// sorted by target and domain
+func (p *Prober) LatestResults() []LatestResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	results := make([]LatestResult, 0, len(p.latest))
+	for _, res := range p.latest {
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Target != results[j].Target {
+			return results[i].Target < results[j].Target
+		}
+		return results[i].Domain < results[j].Domain
+	})
+	return results
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// lazyResolver creates the resolver of a server on first use. With an idle
+// timeout, the resolver is closed once it has not been used for that long
+// and created again when next needed, so that targets probed at long
+// intervals do not hold connections and sockets in between.
+type lazyResolver struct {
+	server  config.DNSServer
+	timeout time.Duration
+	idle    time.Duration
+	random  *random
+
+	mu       sync.Mutex
+	r        resolver.Resolver
+	caps     resolver.Capabilities
+	inFlight int
+	timer    *time.Timer
+	closed   bool
+}
+
+// newLazyResolver prepares the resolver for a server without creating it
+func newLazyResolver(server config.DNSServer, timeout, idle time.Duration, random *random) *lazyResolver {
+	return &lazyResolver{server: server, timeout: timeout, idle: idle, random: random}
+}
+
+// errResolverClosed is returned for queries after the resolver was closed
+var errResolverClosed = errors.New("resolver closed")
+
+// acquire returns the resolver, creating it if needed, and keeps it from
+// being closed as idle until release
+func (l *lazyResolver) acquire() (resolver.Resolver, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, errResolverClosed
+	}
+	if l.r == nil {
+		r, err := newResolver(l.server, l.timeout, l.random)
+		if err != nil {
+			return nil, err
+		}
+		l.r = r
+		l.caps = r.Capabilities()
+	}
+	l.inFlight++
+	return l.r, nil
+}
+
+// release ends a use of the resolver and restarts the idle timer once it
+// is no longer in use
+func (l *lazyResolver) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if l.idle <= 0 || l.inFlight > 0 || l.closed {
+		return
+	}
+	if l.timer == nil {
+		l.timer = time.AfterFunc(l.idle, l.closeIdle)
+	} else {
+		l.timer.Reset(l.idle)
+	}
+}
+
+// closeIdle closes the resolver unless it was used again meanwhile
+func (l *lazyResolver) closeIdle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.r == nil || l.inFlight > 0 {
+		return
+	}
+	logging.Debugf("Closing resolver for %s (%s) after %s idle", l.server.Address, l.server.Protocol, l.idle)
+	if err := l.r.Close(); err != nil {
+		logging.Warnf("Failed to close idle resolver for %s: %v", l.server.Address, err)
+	}
+	l.r = nil
+}
+
+// Query implements resolver.Resolver
+func (l *lazyResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	r, err := l.acquire()
+	if err != nil {
+		return resolver.QueryResult{Err: err}
+	}
+	defer l.release()
+	return r.Query(ctx, hostname, qtype)
+}
+
+// Exchange implements resolver.Resolver
+func (l *lazyResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	r, err := l.acquire()
+	if err != nil {
+		return resolver.QueryResult{Err: err}
+	}
+	defer l.release()
+	return r.Exchange(ctx, msg)
+}
+
+// Healthcheck implements resolver.Resolver
+func (l *lazyResolver) Healthcheck(ctx context.Context) error {
+	r, err := l.acquire()
+	if err != nil {
+		return err
+	}
+	defer l.release()
+	return r.Healthcheck(ctx)
+}
+
+// Capabilities implements resolver.Resolver. It returns those of the
+// resolver last created, without creating one, and so none before the
+// first use.
+func (l *lazyResolver) Capabilities() resolver.Capabilities {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.caps
+}
+
+// OpenConns returns the number of connections and sockets the resolver
+// holds open, without creating it
+func (l *lazyResolver) OpenConns() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.r.(resolver.ConnCounter); ok {
+		return c.OpenConns()
+	}
+	return 0
+}
+
+// Protocol returns the server's protocol without creating the resolver.
+// Iterative resolvers use the server's protocol, Do53 over UDP or TCP.
+func (l *lazyResolver) Protocol() string {
+	return l.server.Protocol
+}
+
+// Close closes the resolver if it was created. Later uses fail rather than
+// create it again.
+func (l *lazyResolver) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	if l.r == nil {
+		return nil
+	}
+	err := l.r.Close()
+	l.r = nil
+	return err
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// created returns true while the lazy resolver holds a resolver
+func (l *lazyResolver) created() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r != nil
+}
+
+func TestLazyResolver(t *testing.T) {
+	server := config.DNSServer{Address: "127.0.0.1", Port: "1", Protocol: config.ProtocolDo53UDP}
+	l := newLazyResolver(server, 50*time.Millisecond, 100*time.Millisecond, nil)
+	defer l.Close()
+
+	if l.created() {
+		t.Fatal("Expected no resolver before the first query")
+	}
+	if l.Protocol() != config.ProtocolDo53UDP {
+		t.Errorf("Expected protocol do53-udp, got %s", l.Protocol())
+	}
+	if n := l.OpenConns(); n != 0 || l.created() {
+		t.Errorf("Expected no open connections without creating the resolver, got %d", n)
+	}
+	if caps := l.Capabilities(); caps != (resolver.Capabilities{}) || l.created() {
+		t.Errorf("Expected no capabilities without creating the resolver, got %+v", caps)
+	}
+
+	l.Exchange(context.Background(), resolver.NewQuery("example.com", dns.TypeA))
+	if !l.created() {
+		t.Fatal("Expected the resolver to be created by the query")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.created() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l.created() {
+		t.Error("Expected the idle resolver to be closed")
+	}
+
+	l.Exchange(context.Background(), resolver.NewQuery("example.com", dns.TypeA))
+	if !l.created() {
+		t.Error("Expected the resolver to be created again")
+	}
+
+	l.Close()
+	if result := l.Exchange(context.Background(), resolver.NewQuery("example.com", dns.TypeA)); result.Err == nil || l.created() {
+		t.Errorf("Expected queries after Close to fail without creating the resolver, got %v", result.Err)
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"time"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// ProbeOnce probes a server that need not be configured for domain, like a
+// probe of a cycle but on a resolver of its own that is closed afterwards.
+// The server's retries apply, each attempt within timeout, and ctx bounds
+// the probe as a whole. Nothing is recorded.
+func ProbeOnce(ctx context.Context, domain config.Domain, server config.DNSServer, timeout time.Duration) Result {
+	res := Result{Domain: domain, Server: server, Protocol: server.Protocol, Timeout: timeout}
+	r, err := newResolver(server, timeout, nil)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer r.Close()
+
+	res.Protocol = r.Protocol()
+	res.Hostname = domain.QueryName(generateRandomPrefix(5))
+	msg := queryMessage(domain, server, res.Hostname)
+	defer resolver.ReleaseQuery(msg)
+	for attempt := 0; attempt <= server.RetryCount(); attempt++ {
+		result := r.Exchange(ctx, msg)
+		res.Attempts = append(res.Attempts, result)
+		res.Duration += result.Duration
+		res.Err = result.Err
+		if result.Err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return res
+}
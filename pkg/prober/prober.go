@@ -0,0 +1,917 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/robfig/cron/v3"
+	"golang.org/x/time/rate"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// watchdogFactor is how many timeouts a query may block before the
+// watchdog cancels and abandons it
+const watchdogFactor = 3
+
+// errWatchdog is recorded for queries abandoned by the watchdog
+var errWatchdog = errors.New("query cancelled by watchdog")
+
+// Prober orchestrates DNS queries across multiple resolvers
+type Prober struct {
+	config    *config.Config
+	resolvers map[string]resolver.Resolver
+	timeouts  map[string]time.Duration // overall timeout, for the watchdog
+	limiter   *rate.Limiter
+	limiters  map[string]*rate.Limiter
+	schedules map[string]cron.Schedule
+	nextRun   map[string]time.Time
+	cycles    int // cycles started, for the startup ramp
+
+	references map[string]resolver.Resolver // trusted resolvers for comparisons
+	companions map[string]resolver.Resolver // Do53 paths for transport comparisons and downgrade checks
+	http3      map[string]resolver.Resolver // DoH3 probes of DoH servers advertising HTTP/3
+	fallbacks  map[string]resolver.Resolver // Do53 over TCP paths for truncated large responses
+
+	callbacks           []func(Result)
+	filteringCallbacks  []func(FilteringResult)
+	divergenceCallbacks []func(DivergenceResult)
+	comparisonCallbacks []func(TransportComparison)
+	downgradeCallbacks  []func(DowngradeResult)
+	statsCallbacks      []func(StatsResult)
+	metrics             *metrics.Metrics
+
+	fingerprintCallbacks []func(FingerprintResult)
+	catchmentCallbacks   []func(CatchmentResult)
+	quicCallbacks        []func(QUICResult)
+	largeCallbacks       []func(LargeResponseResult)
+
+	mu          sync.Mutex
+	drained     map[string]bool
+	divergences map[string]Divergence
+	latest      map[string]LatestResult
+	errors      map[string][]ProbeError
+	responses   map[string]lastResponse
+	advertised  map[string]bool // DoH servers advertising HTTP/3
+	inFlight    map[string]int  // probes and queries running per target
+	paused      atomic.Bool
+
+	fingerprints map[string]Fingerprint // latest fingerprint per target, under mu
+	instances    map[string]string      // latest anycast instance per target, under mu
+	quicFailures map[string]int         // cycles in a row QUIC failed while TCP worked, under mu
+	upstreams    map[string][]string    // addresses tracked per target, under mu
+
+	random *random
+
+	kept map[string]bool // resolvers taken over by the next prober, under mu
+}
+
+// Option configures a Prober
+type Option func(*Prober)
+
+// WithResultCallback registers fn to receive the result of every probe.
+// Callbacks run synchronously on the probing goroutine in registration
+// order and should return quickly.
+func WithResultCallback(fn func(Result)) Option {
+	return func(p *Prober) {
+		p.callbacks = append(p.callbacks, fn)
+	}
+}
+
+// WithMetrics reports the prober's operational state (heartbeat, cycle
+// overruns, watchdog cancels and pausing) to m. Without it these events
+// are not recorded.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(p *Prober) {
+		p.metrics = m
+	}
+}
+
+// Result is the outcome of a single probe
+type Result struct {
+	Domain   config.Domain
+	Server   config.DNSServer
+	Protocol string
+	Hostname string
+
+	// Attempts holds every network exchange of the probe in order; the last
+	// one is the final outcome
+	Attempts []resolver.QueryResult
+
+	// Duration is the time spent in all attempts
+	Duration time.Duration
+
+	// Timeout is the per-attempt timeout of the server
+	Timeout time.Duration
+
+	// Index is the position of the probe among those of the domain against
+	// the server in a cycle, from 0, or onDemand for probes outside cycles
+	Index int
+
+	// Upstream is the address that answered the final attempt, for servers
+	// tracking upstreams; see upstream
+	Upstream string
+
+	// Err is nil if the probe succeeded
+	Err error
+}
+
+// Success returns true if the final attempt succeeded
+func (r Result) Success() bool {
+	return r.Err == nil
+}
+
+// Last returns the final attempt
+func (r Result) Last() resolver.QueryResult {
+	return r.Attempts[len(r.Attempts)-1]
+}
+
+// onDemand is the Result.Index of probes requested through ProbeNow, which
+// are never the first of a cycle
+const onDemand = -1
+
+// First returns true for the first of several probes of the domain against
+// the server in a cycle, which often pays for a cache miss or connection
+// setup that the following probes do not
+func (r Result) First() bool {
+	return r.Index == 0 && r.Domain.ProbeCount() > 1
+}
+
+// DNSSECChecked returns true if the domain declares an expected DNSSEC
+// status and the probe got a response to check it against
+func (r Result) DNSSECChecked() bool {
+	return r.Domain.DNSSEC != "" && r.Success() && r.Last().Response != nil
+}
+
+// DNSSECMismatch returns true if the response contradicts the domain's
+// expected DNSSEC status: a secure domain answered without the AD flag
+// (including SERVFAIL from a failed validation), or an insecure domain
+// answered with it
+func (r Result) DNSSECMismatch() bool {
+	if !r.DNSSECChecked() {
+		return false
+	}
+	authenticated := r.Last().Response.AuthenticatedData
+	switch r.Domain.DNSSEC {
+	case config.DNSSECSecure:
+		return !authenticated
+	case config.DNSSECInsecure:
+		return authenticated
+	}
+	return false
+}
+
+// BlockChecked returns true if the domain is expected to be blocked and the
+// probe got a response to check it against
+func (r Result) BlockChecked() bool {
+	return r.Domain.Blocked != nil && r.Success() && r.Last().Response != nil
+}
+
+// BlockBypassed returns true if the server answered a domain expected to
+// be blocked without blocking it
+func (r Result) BlockBypassed() bool {
+	return r.BlockChecked() && !blockedAs(r.Last().Response, r.Domain.Blocked)
+}
+
+// SLAChecked returns true if the server has latency thresholds
+func (r Result) SLAChecked() bool {
+	return r.Server.SLA != nil
+}
+
+// SLABreaches tells which latency thresholds of the server the probe
+// breached, by taking at least as long or by failing
+func (r Result) SLABreaches() (warning, critical bool) {
+	if !r.SLAChecked() {
+		return false, false
+	}
+	breached := func(threshold config.Duration) bool {
+		return threshold > 0 && (!r.Success() || r.Duration >= time.Duration(threshold))
+	}
+	return breached(r.Server.SLA.Warning), breached(r.Server.SLA.Critical)
+}
+
+// New creates a new Prober for all enabled servers. Resolvers are created
+// on first use, and closed after the configured idle timeout.
+func New(cfg *config.Config, opts ...Option) (*Prober, error) {
+	resolvers := make(map[string]resolver.Resolver)
+	timeouts := make(map[string]time.Duration)
+	limiters := make(map[string]*rate.Limiter)
+	schedules := make(map[string]cron.Schedule)
+	nextRun := make(map[string]time.Time)
+	companions := make(map[string]resolver.Resolver)
+	http3 := make(map[string]resolver.Resolver)
+	fallbacks := make(map[string]resolver.Resolver)
+	random := new(random)
+	now := time.Now()
+	for _, server := range cfg.DNSServers {
+		if !server.IsEnabled() {
+			continue
+		}
+		key := serverKey(server)
+		timeout := time.Duration(server.Timeout) * time.Millisecond
+		if timeout == 0 {
+			timeout = time.Duration(cfg.Timeout) * time.Millisecond
+		}
+		if timeout == 0 {
+			timeout = config.DefaultTimeout(server.Protocol)
+		}
+		if server.IsRecursive() && !resolver.Registered(server.Protocol) {
+			return nil, fmt.Errorf("failed to create resolver for %s: unsupported protocol: %s", server.Address, server.Protocol)
+		}
+		resolvers[key] = newLazyResolver(server, timeout, time.Duration(cfg.IdleTimeout), random)
+		timeouts[key] = timeout
+		if server.Faults != nil {
+			logging.Warnf("Injecting faults into queries to %s:%s (%s)", server.Address, server.Port, server.Protocol)
+			resolvers[key] = &faultyResolver{Resolver: resolvers[key], faults: *server.Faults, timeout: timeout, random: random}
+		}
+		if other, ok := companion(server); ok {
+			companions[key] = newLazyResolver(other, timeout, time.Duration(cfg.IdleTimeout), random)
+			timeouts[serverKey(other)] = timeout
+		}
+		if server.AltSvc == config.AltSvcProbe {
+			h3 := http3Server(server)
+			http3[key] = newLazyResolver(h3, timeout, time.Duration(cfg.IdleTimeout), random)
+			timeouts[serverKey(h3)] = timeout
+		}
+		if cfg.LargeResponse.Enabled() && server.Protocol == config.ProtocolDo53UDP && server.IsRecursive() {
+			tcp := otherTransport(server)
+			fallbacks[key] = newLazyResolver(tcp, timeout, time.Duration(cfg.IdleTimeout), random)
+			timeouts[serverKey(tcp)] = timeout
+		}
+		if server.QPS > 0 {
+			limiters[key] = newLimiter(server.QPS, 1)
+		}
+		if server.Schedule != "" {
+			schedule, err := config.ParseSchedule(server.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schedule for %s: %w", server.Address, err)
+			}
+			schedules[key] = schedule
+			nextRun[key] = schedule.Next(now)
+		}
+	}
+
+	references, err := newReferences(cfg, timeouts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Prober{
+		config:     cfg,
+		resolvers:  resolvers,
+		references: references,
+		companions: companions,
+		http3:      http3,
+		fallbacks:  fallbacks,
+		timeouts:   timeouts,
+		limiter:    newLimiter(cfg.RateLimit.QPS, cfg.RateLimit.Burst),
+		limiters:   limiters,
+		schedules:  schedules,
+		nextRun:    nextRun,
+		drained:    make(map[string]bool),
+		random:     random,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// companion returns the Do53 server queried alongside a server: the other
+// transport when comparing transports, or the plaintext path of an
+// encrypted server when checking for downgrades
+func companion(server config.DNSServer) (config.DNSServer, bool) {
+	switch {
+	case server.CompareTransports:
+		return otherTransport(server), true
+	case server.DowngradeCheck:
+		return plaintext(server), true
+	}
+	return config.DNSServer{}, false
+}
+
+// newResolver creates the resolver for a server. Servers with recursive:
+// false are resolved iteratively over Do53, trying name servers in the
+// order random puts them.
+func newResolver(server config.DNSServer, timeout time.Duration, random *random) (resolver.Resolver, error) {
+	opts := resolverOptions(server, timeout)
+	if !server.IsRecursive() {
+		opts.Shuffle = random.shuffle
+		return resolver.NewIterativeResolver(opts, server.Protocol == config.ProtocolDo53TCP), nil
+	}
+	return resolver.New(server.Protocol, opts)
+}
+
+// resolverOptions maps a server's configuration to resolver options
+func resolverOptions(server config.DNSServer, timeout time.Duration) resolver.Options {
+	opts := resolver.Options{
+		Address: server.Address,
+		Port:    server.Port,
+		Timeouts: resolver.Timeouts{
+			Total:   timeout,
+			Connect: time.Duration(server.ConnectTimeout) * time.Millisecond,
+			Query:   time.Duration(server.QueryTimeout) * time.Millisecond,
+		},
+		Netns:          server.Netns,
+		VRF:            server.VRF,
+		SourceAddress:  server.SourceAddress,
+		ReuseSocket:    server.ReuseSocket,
+		CaptureHeaders: server.CaptureHeaders,
+	}
+	if server.TLS != nil {
+		opts.ServerName = server.TLS.ServerName
+		opts.InsecureSkipVerify = server.TLS.SkipsVerification()
+		opts.ALPN = server.TLS.ALPN
+		opts.TLSMinVersion = config.TLSVersion(server.TLS.MinVersion)
+		opts.TLSMaxVersion = config.TLSVersion(server.TLS.MaxVersion)
+		opts.ResumeSessions = server.TLS.ResumesSessions()
+	}
+	if server.HTTP != nil {
+		opts.Headers = server.HTTP.Headers
+		opts.UserAgent = server.HTTP.UserAgent
+	}
+	if server.TCP != nil {
+		opts.TCP = resolver.TCPOptions{
+			FastOpen:          server.TCP.FastOpen,
+			NoDelay:           server.TCP.NoDelay,
+			KeepAlive:         time.Duration(server.TCP.KeepAlive),
+			KeepAliveInterval: time.Duration(server.TCP.KeepAliveInterval),
+		}
+	}
+	return opts
+}
+
+// newLimiter creates a token bucket allowing qps queries per second, or nil
+// when qps is zero (unlimited)
+func newLimiter(qps float64, burst int) *rate.Limiter {
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(qps), burst)
+}
+
+// serverKey generates a unique key for a server configuration, see
+// config.DNSServer.Key
+func serverKey(server config.DNSServer) string {
+	return server.Key()
+}
+
+// Run executes one round of DNS probes for all configured domains and servers.
+// If a cycle deadline is configured and reached, the remaining probes are
+// skipped and the overrun is counted.
+func (p *Prober) Run(ctx context.Context) {
+	if p.config.CycleDeadline > 0 {
+		cycleCtx, cancel := context.WithTimeout(ctx, time.Duration(p.config.CycleDeadline))
+		defer cancel()
+		p.runCycle(cycleCtx)
+		if ctx.Err() == nil && cycleCtx.Err() == context.DeadlineExceeded {
+			p.metrics.CycleOverrun()
+			logging.Warnf("Probe cycle exceeded deadline of %s, remaining probes skipped", time.Duration(p.config.CycleDeadline))
+		}
+		return
+	}
+	p.runCycle(ctx)
+}
+
+// runCycle warms up the due servers with warmup, probes every enabled
+// domain against every active server that is due, then runs the HTTP/3,
+// filtering, hijack, transport, downgrade and large response checks,
+// fingerprints servers, checks anycast catchments and scrapes server
+// statistics, until done or ctx is cancelled. Finally it looks for
+// blocked QUIC in the latest results and aggregates them by provider.
+func (p *Prober) runCycle(ctx context.Context) {
+	p.metrics.Heartbeat()
+	due := p.dueServers(time.Now())
+
+	p.warmUp(ctx, due)
+	p.probeDomains(ctx, due)
+	p.checkAltSvc(ctx, due)
+	p.checkFiltering(ctx, due)
+	p.checkDivergence(ctx, due)
+	p.checkTransports(ctx, due)
+	p.checkDowngrades(ctx, due)
+	p.checkLargeResponses(ctx, due)
+	p.checkFingerprints(ctx, due)
+	p.checkCatchments(ctx, due)
+	p.checkStats(ctx, due)
+	p.checkQUIC(due)
+	p.recordOpenConns()
+	p.recordProviders()
+}
+
+// recordOpenConns reports the connections and sockets held open by the
+// resolvers of each target, including those kept idle for reuse
+func (p *Prober) recordOpenConns() {
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		serverAddr := fmt.Sprintf("%s:%s", server.Address, server.Port)
+		for _, r := range []resolver.Resolver{p.resolvers[key], p.http3[key]} {
+			if c, ok := r.(resolver.ConnCounter); ok {
+				p.metrics.SetOpenConnections(serverAddr, r.Protocol(), server.Labels, c.OpenConns())
+			}
+		}
+	}
+}
+
+// probeDomains probes every enabled domain against the due servers
+func (p *Prober) probeDomains(ctx context.Context, due map[string]bool) {
+	for _, domain := range p.config.Domains {
+		if !domain.IsEnabled() {
+			continue
+		}
+		for _, server := range p.config.DNSServers {
+			key := serverKey(server)
+			r, ok := p.resolvers[key]
+			if !ok || !due[key] || p.isDrained(key) {
+				continue
+			}
+
+			for i := 0; i < domain.ProbeCount(); i++ {
+				if p.Paused() {
+					return
+				}
+				if err := p.wait(ctx, key); err != nil {
+					return
+				}
+
+				p.probe(ctx, domain, server, r, i)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(500 * time.Millisecond):
+				}
+			}
+		}
+	}
+}
+
+// dueServers returns the keys of servers to probe in a cycle starting at
+// now. Servers without a schedule are probed every cycle, except for those
+// held back by the startup ramp; scheduled servers are probed once their
+// next cron time has passed.
+func (p *Prober) dueServers(now time.Time) map[string]bool {
+	p.cycles++
+	ramp := p.ramp()
+	due := make(map[string]bool, len(p.resolvers))
+	for key := range p.resolvers {
+		schedule, ok := p.schedules[key]
+		if !ok {
+			if ramp == nil || ramp[key] {
+				due[key] = true
+			}
+			continue
+		}
+		if !now.Before(p.nextRun[key]) {
+			due[key] = true
+			p.nextRun[key] = schedule.Next(now)
+		}
+	}
+	return due
+}
+
+// ramp returns the servers without a schedule that are probed in the
+// current cycle while ramping up, or nil once all are. Over the first
+// startup_ramp cycles, a growing share of them is probed in configuration
+// order, so that a large configuration does not query every target (and
+// set up every connection) at once.
+func (p *Prober) ramp() map[string]bool {
+	cycles := p.config.StartupRamp
+	if p.cycles >= cycles {
+		return nil
+	}
+	var keys []string
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		if _, ok := p.resolvers[key]; ok && p.schedules[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+	n := (len(keys)*p.cycles + cycles - 1) / cycles
+	logging.Infof("Startup ramp cycle %d of %d: probing %d of %d targets", p.cycles, cycles, n, len(keys))
+	ramp := make(map[string]bool, n)
+	for _, key := range keys[:n] {
+		ramp[key] = true
+	}
+	return ramp
+}
+
+// wait blocks until both the global and the per-server rate limits allow
+// another query, or ctx is cancelled
+func (p *Prober) wait(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.limiter != nil {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l := p.limiters[key]; l != nil {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probe queries a random name under domain, retrying failed attempts up to
+// the server's retry count, and passes the result to the callbacks. The
+// index is the position of the probe in the cycle, see Result.Index. It
+// returns false without a result if ctx was cancelled, or if an earlier
+// probe of the target is still in flight, e.g. a query abandoned by the
+// watchdog or a probe requested through the API, so that probes of a slow
+// or dead server do not pile up. Probes during which the wall clock jumped
+// are discarded the same way, so that a suspend or clock step does not
+// end up in the latency metrics.
+func (p *Prober) probe(ctx context.Context, domain config.Domain, server config.DNSServer, r resolver.Resolver, index int) (Result, bool) {
+	serverAddr := fmt.Sprintf("%s:%s", server.Address, server.Port)
+	protocol := r.Protocol()
+
+	key := serverKey(server)
+	busy := p.begin(key) > 0
+	defer p.end(key)
+	if busy {
+		p.metrics.OverlapSkip(serverAddr, protocol)
+		logging.Debugf("[%s] %s - skipping %s, previous probe still in flight", protocol, serverAddr, domain.Name)
+		return Result{}, false
+	}
+
+	prefix := p.random.prefix(5)
+	hostname := domain.QueryName(prefix)
+
+	res := Result{
+		Domain:   domain,
+		Server:   server,
+		Protocol: protocol,
+		Hostname: hostname,
+		Timeout:  p.timeouts[key],
+		Index:    index,
+	}
+	msg := queryMessage(domain, server, hostname)
+	start := time.Now()
+	abandoned := false
+	defer func() {
+		// The query abandoned by the watchdog releases its own message
+		if !abandoned {
+			resolver.ReleaseQuery(msg)
+		}
+	}()
+	for attempt := 0; attempt <= server.RetryCount(); attempt++ {
+		if attempt > 0 {
+			logging.Debugf("[%s] (%-25s)?(%s) - retrying after error: %s", protocol, hostname, serverAddr, res.Err)
+			if err := p.wait(ctx, key); err != nil {
+				return Result{}, false
+			}
+			if abandoned {
+				// The abandoned query may still use its message
+				msg, abandoned = queryMessage(domain, server, hostname), false
+			}
+		}
+		result := p.query(ctx, server, r, msg)
+		abandoned = result.Err == errWatchdog
+		p.metrics.Heartbeat()
+		if ctx.Err() != nil {
+			// Interrupted by shutdown or the cycle deadline, not a server failure
+			return Result{}, false
+		}
+		res.Attempts = append(res.Attempts, result)
+		res.Duration += result.Duration
+		res.Err = result.Err
+		if result.Err == nil {
+			break
+		}
+	}
+
+	if jump := clockJump(start, time.Now()); jumped(jump) {
+		p.metrics.ClockJump()
+		logging.Warnf("[%s] %s - discarding probe of %s, wall clock jumped by %s",
+			protocol, serverAddr, domain.Name, jump.Round(time.Millisecond))
+		return Result{}, false
+	}
+
+	if logging.Enabled(logging.LevelDebug) {
+		duration := res.Duration.Seconds()
+		if res.Success() {
+			logging.Samplef(logging.LevelDebug, "[%s] (%-25s)?(%s) - success - %-5.0f msec",
+				protocol, hostname, serverAddr, duration*1000)
+		} else {
+			logging.Debugf("[%s] (%-25s)?(%s) - failed  - %-5.0f msec - error: %s",
+				protocol, hostname, serverAddr, duration*1000, res.Err)
+		}
+	}
+
+	res.Upstream = p.upstream(res)
+	p.recordLatest(res)
+	p.recordResponse(res)
+	if server.AltSvc == config.AltSvcProbe && res.Success() {
+		p.recordAltSvc(res)
+	}
+	if !res.Success() {
+		p.recordError(res)
+	}
+	for _, fn := range p.callbacks {
+		fn(res)
+	}
+	return res, true
+}
+
+// ProbeNow probes the target with the given key once for every enabled
+// domain, out of band of the probe cycles, and returns the results. The
+// results are recorded like those of a cycle, though never as the first
+// probe of the domain. Drained targets are probed too, and the rate limits
+// apply. Domains are left out while a probe of the cycle is in flight.
+func (p *Prober) ProbeNow(ctx context.Context, key string) ([]LatestResult, error) {
+	r, ok := p.resolvers[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown target: %s", key)
+	}
+	var server config.DNSServer
+	for _, s := range p.config.DNSServers {
+		if serverKey(s) == key {
+			server = s
+			break
+		}
+	}
+
+	results := make([]LatestResult, 0, len(p.config.Domains))
+	for _, domain := range p.config.Domains {
+		if !domain.IsEnabled() {
+			continue
+		}
+		if err := p.wait(ctx, key); err != nil {
+			return results, err
+		}
+		res, ok := p.probe(ctx, domain, server, r, onDemand)
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			continue
+		}
+		results = append(results, p.latestResult(res))
+	}
+	return results, nil
+}
+
+// queryMessage builds the probe query for hostname from the message pool.
+// Domains with an expected DNSSEC status set the DO bit so that validating
+// resolvers report the validation result, and queries to authoritative
+// servers do not ask for recursion.
+func queryMessage(domain config.Domain, server config.DNSServer, hostname string) *dns.Msg {
+	msg := resolver.AcquireQuery(hostname, domain.QueryType())
+	msg.RecursionDesired = !server.Authoritative
+	if domain.DNSSEC != "" {
+		msg.SetEdns0(dns.DefaultMsgSize, true)
+	}
+	return msg
+}
+
+// query runs a resolver query under the watchdog. A query still blocked
+// well past its timeout (e.g. a hung QUIC dial) is cancelled and abandoned
+// so that it cannot stall the scheduler. The message of an abandoned query
+// is returned to the query pool once the query returns, so callers must
+// neither reuse nor release it after errWatchdog.
+func (p *Prober) query(ctx context.Context, server config.DNSServer, r resolver.Resolver, msg *dns.Msg) resolver.QueryResult {
+	queryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	key := serverKey(server)
+	results := make(chan resolver.QueryResult, 1)
+	// finished is set by whichever comes first, the query or the watchdog
+	var finished atomic.Bool
+	start := time.Now()
+	p.begin(key)
+	go func() {
+		// An abandoned query keeps the target in flight until it returns
+		defer p.end(key)
+		results <- r.Exchange(queryCtx, msg)
+		if !finished.CompareAndSwap(false, true) {
+			resolver.ReleaseQuery(msg)
+		}
+	}()
+
+	watchdog := time.NewTimer(watchdogFactor * p.timeouts[key])
+	defer watchdog.Stop()
+
+	select {
+	case result := <-results:
+		return result
+	case <-watchdog.C:
+		if !finished.CompareAndSwap(false, true) {
+			// The query returned just in time
+			return <-results
+		}
+		cancel()
+		serverAddr := fmt.Sprintf("%s:%s", server.Address, server.Port)
+		p.metrics.WatchdogCancel(serverAddr, r.Protocol())
+		logging.Warnf("Watchdog cancelled query to %s (%s) after %s", serverAddr, r.Protocol(), time.Since(start).Round(time.Millisecond))
+		return resolver.QueryResult{Duration: time.Since(start), Err: errWatchdog}
+	}
+}
+
+// Drain stops probing the target with the given key (address:port:protocol)
+// until it is undrained
+func (p *Prober) Drain(target string) error {
+	if _, ok := p.resolvers[target]; !ok {
+		return fmt.Errorf("unknown target: %s", target)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drained[target] = true
+	return nil
+}
+
+// Undrain resumes probing a drained target
+func (p *Prober) Undrain(target string) error {
+	if _, ok := p.resolvers[target]; !ok {
+		return fmt.Errorf("unknown target: %s", target)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.drained, target)
+	return nil
+}
+
+// Drained returns the sorted keys of drained targets
+func (p *Prober) Drained() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	targets := make([]string, 0, len(p.drained))
+	for target := range p.drained {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// begin marks a probe or query of the target as in flight and returns how
+// many others already were. Every begin needs a matching end.
+func (p *Prober) begin(key string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight == nil {
+		p.inFlight = make(map[string]int)
+	}
+	p.inFlight[key]++
+	return p.inFlight[key] - 1
+}
+
+// end marks a probe or query started by begin as done
+func (p *Prober) end(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight[key]--
+	if p.inFlight[key] == 0 {
+		delete(p.inFlight, key)
+	}
+}
+
+// isDrained returns true if the target is currently drained
+func (p *Prober) isDrained(target string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.drained[target]
+}
+
+// Target describes a probed server and its current state
+type Target struct {
+	Key          string                `json:"key"`
+	Address      string                `json:"address"`
+	Port         string                `json:"port"`
+	Protocol     string                `json:"protocol"`
+	Drained      bool                  `json:"drained"`
+	Capabilities resolver.Capabilities `json:"capabilities"`
+	Ready        bool                  `json:"ready"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// Targets returns every active target with its capabilities. Readiness is
+// determined by health checking all targets concurrently, which also
+// creates lazily created resolvers before their capabilities are read.
+func (p *Prober) Targets(ctx context.Context) []Target {
+	var targets []Target
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok {
+			continue
+		}
+		targets = append(targets, Target{
+			Key:      key,
+			Address:  server.Address,
+			Port:     server.Port,
+			Protocol: r.Protocol(),
+			Drained:  p.isDrained(key),
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(t *Target) {
+			defer wg.Done()
+			r := p.resolvers[t.Key]
+			err := r.Healthcheck(ctx)
+			t.Capabilities = r.Capabilities()
+			if err != nil {
+				t.Error = err.Error()
+				return
+			}
+			t.Ready = true
+		}(&targets[i])
+	}
+	wg.Wait()
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Key < targets[j].Key })
+	return targets
+}
+
+// Pause suspends probing; a cycle in progress stops before its next probe
+func (p *Prober) Pause() {
+	p.paused.Store(true)
+	p.metrics.SetPaused(true)
+}
+
+// Resume continues probing after Pause
+func (p *Prober) Resume() {
+	p.paused.Store(false)
+	p.metrics.SetPaused(false)
+}
+
+// Paused returns true while probing is paused
+func (p *Prober) Paused() bool {
+	return p.paused.Load()
+}
+
+// Interval returns the configured time between probe cycles
+func (p *Prober) Interval() time.Duration {
+	if p.config.Interval > 0 {
+		return time.Duration(p.config.Interval)
+	}
+	return 30 * time.Second
+}
+
+// Close releases all resolver resources, except those another prober took
+// over with Reuse
+func (p *Prober) Close() {
+	p.mu.Lock()
+	kept := p.kept
+	p.mu.Unlock()
+	for name, r := range p.resolvers {
+		if kept[name] {
+			continue
+		}
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close resolver %s: %v", name, err)
+		}
+	}
+	for name, r := range p.references {
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close reference resolver %s: %v", name, err)
+		}
+	}
+	for name, r := range p.companions {
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close companion resolver %s: %v", name, err)
+		}
+	}
+	for name, r := range p.http3 {
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close DoH3 resolver %s: %v", name, err)
+		}
+	}
+	for name, r := range p.fallbacks {
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close TCP fallback resolver %s: %v", name, err)
+		}
+	}
+}
+
+// generateRandomPrefix creates a short random string to use as a hostname prefix
+func generateRandomPrefix(length uint) string {
+	b := make([]byte, length)
+	_, err := rand.Read(b)
+	if err != nil {
+		logging.Warnf("Failed to generate random prefix: %v", err)
+		return "random"
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
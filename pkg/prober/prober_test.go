@@ -0,0 +1,1796 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+func TestNew(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		Timeout: 2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	if len(p.resolvers) != 1 {
+		t.Errorf("Expected 1 resolver, got %d", len(p.resolvers))
+	}
+}
+
+func TestNewWithMultipleServers(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53TCP},
+		},
+		Timeout: 2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	if len(p.resolvers) != 2 {
+		t.Errorf("Expected 2 resolvers, got %d", len(p.resolvers))
+	}
+}
+
+func TestNewWithInvalidProtocol(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: "invalid"},
+		},
+		Timeout: 2000,
+	}
+
+	_, err := New(cfg)
+	if err == nil {
+		t.Error("Expected error for invalid protocol, got nil")
+	}
+}
+
+func TestGenerateRandomPrefix(t *testing.T) {
+	t.Run("generates prefix of correct length", func(t *testing.T) {
+		prefix := generateRandomPrefix(5)
+		if len(prefix) != 8 {
+			t.Errorf("Expected prefix length 8, got %d", len(prefix))
+		}
+	})
+
+	t.Run("generates unique prefixes", func(t *testing.T) {
+		prefix1 := generateRandomPrefix(5)
+		prefix2 := generateRandomPrefix(5)
+		if prefix1 == prefix2 {
+			t.Error("Expected different prefixes, got identical ones")
+		}
+	})
+
+	t.Run("generates valid base32 string", func(t *testing.T) {
+		prefix := generateRandomPrefix(5)
+		for _, c := range prefix {
+			if (c < 'A' || c > 'Z') && (c < '2' || c > '7') {
+				t.Errorf("Invalid base32 character: %c", c)
+			}
+		}
+	})
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		Timeout: 0,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+}
+
+func TestDisabledServersAndDrain(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP, Enabled: &disabled},
+		},
+		Timeout: 2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	if len(p.resolvers) != 1 {
+		t.Errorf("Expected 1 resolver for enabled servers, got %d", len(p.resolvers))
+	}
+
+	if err := p.Drain("1.1.1.1:53:do53-udp"); err == nil {
+		t.Error("Expected error draining a disabled server, got nil")
+	}
+	if err := p.Drain("8.8.8.8:53:do53-udp"); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !p.isDrained("8.8.8.8:53:do53-udp") {
+		t.Error("Expected target to be drained")
+	}
+	if err := p.Undrain("8.8.8.8:53:do53-udp"); err != nil {
+		t.Fatalf("Undrain failed: %v", err)
+	}
+	if len(p.Drained()) != 0 {
+		t.Errorf("Expected no drained targets, got %v", p.Drained())
+	}
+}
+
+func TestRateLimiters(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP, QPS: 1},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		RateLimit: config.RateLimit{QPS: 100},
+		Timeout:   2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	if p.limiter == nil || p.limiter.Burst() != 1 {
+		t.Error("Expected global limiter with burst 1")
+	}
+	if p.limiters["8.8.8.8:53:do53-udp"] == nil {
+		t.Error("Expected per-server limiter for 8.8.8.8")
+	}
+	if p.limiters["1.1.1.1:53:do53-udp"] != nil {
+		t.Error("Expected no per-server limiter for 1.1.1.1")
+	}
+
+	// The first query uses the burst, the second must wait a full second
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.wait(ctx, "8.8.8.8:53:do53-udp"); err != nil {
+		t.Fatalf("First wait failed: %v", err)
+	}
+	if err := p.wait(ctx, "8.8.8.8:53:do53-udp"); err == nil {
+		t.Error("Expected second wait to exceed the deadline")
+	}
+}
+
+func TestCycleDeadline(t *testing.T) {
+	probes := 3
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com", Probes: &probes},
+		},
+		DNSServers: []config.DNSServer{
+			{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		Timeout:       1000,
+		CycleDeadline: config.Duration(50 * time.Millisecond),
+	}
+
+	m := metrics.New(prometheus.NewRegistry())
+	p, err := New(cfg, WithMetrics(m))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	before := testutil.ToFloat64(m.CycleOverruns)
+	start := time.Now()
+	p.Run(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected cycle to stop at the deadline, took %v", elapsed)
+	}
+	if got := testutil.ToFloat64(m.CycleOverruns) - before; got != 1 {
+		t.Errorf("Expected 1 cycle overrun, got %v", got)
+	}
+}
+
+func TestDueServers(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP, Schedule: "0 * * * *"},
+		},
+		Timeout: 2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	scheduled := "1.1.1.1:53:do53-udp"
+	next := p.nextRun[scheduled]
+	if next.Minute() != 0 || !next.After(time.Now()) {
+		t.Fatalf("Expected next run at the top of a future hour, got %v", next)
+	}
+
+	due := p.dueServers(next.Add(-time.Second))
+	if !due["8.8.8.8:53:do53-udp"] || due[scheduled] {
+		t.Errorf("Expected only unscheduled server to be due before the hour, got %v", due)
+	}
+
+	due = p.dueServers(next)
+	if !due[scheduled] {
+		t.Error("Expected scheduled server to be due at its cron time")
+	}
+	if !p.nextRun[scheduled].Equal(next.Add(time.Hour)) {
+		t.Errorf("Expected next run one hour later, got %v", p.nextRun[scheduled])
+	}
+}
+
+func TestStartupRamp(t *testing.T) {
+	cfg := &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "192.0.2.3", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP, Schedule: "* * * * *"},
+		},
+		StartupRamp: 3,
+		Timeout:     2000,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	now := time.Now()
+	for cycle, want := range [][]string{
+		{"192.0.2.1:53:do53-udp"},
+		{"192.0.2.1:53:do53-udp", "192.0.2.2:53:do53-udp"},
+		{"192.0.2.1:53:do53-udp", "192.0.2.2:53:do53-udp", "192.0.2.3:53:do53-udp"},
+		{"192.0.2.1:53:do53-udp", "192.0.2.2:53:do53-udp", "192.0.2.3:53:do53-udp"},
+	} {
+		due := p.dueServers(now)
+		delete(due, "192.0.2.4:53:do53-udp")
+		if len(due) != len(want) {
+			t.Errorf("Cycle %d: expected %d due servers, got %v", cycle+1, len(want), due)
+			continue
+		}
+		for _, key := range want {
+			if !due[key] {
+				t.Errorf("Cycle %d: expected %s to be due, got %v", cycle+1, key, due)
+			}
+		}
+	}
+}
+
+// stuckResolver ignores context cancellation and blocks until released
+type stuckResolver struct {
+	release chan struct{}
+}
+
+func (r *stuckResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *stuckResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	<-r.release
+	return resolver.QueryResult{}
+}
+
+func (r *stuckResolver) Protocol() string { return "do53-udp" }
+
+func (r *stuckResolver) Close() error { return nil }
+
+func (r *stuckResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *stuckResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestWatchdog(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP}
+	stuck := &stuckResolver{release: make(chan struct{})}
+	defer close(stuck.release)
+
+	p := &Prober{
+		config:    &config.Config{},
+		resolvers: map[string]resolver.Resolver{serverKey(server): stuck},
+		timeouts:  map[string]time.Duration{serverKey(server): 10 * time.Millisecond},
+		drained:   make(map[string]bool),
+	}
+
+	p.metrics = metrics.New(prometheus.NewRegistry())
+	cancels := p.metrics.WatchdogCancels.WithLabelValues("192.0.2.1:53", "do53-udp")
+	before := testutil.ToFloat64(cancels)
+
+	result := p.query(context.Background(), server, stuck, resolver.NewQuery("example.com", dns.TypeA))
+	if result.Err != errWatchdog {
+		t.Errorf("Expected watchdog error, got %v", result.Err)
+	}
+	if result.Duration < 30*time.Millisecond {
+		t.Errorf("Expected watchdog to wait %d timeouts, returned after %v", watchdogFactor, result.Duration)
+	}
+	if got := testutil.ToFloat64(cancels) - before; got != 1 {
+		t.Errorf("Expected 1 watchdog cancel, got %v", got)
+	}
+}
+
+// recordingResolver is a stuckResolver that remembers the messages it was
+// asked to send
+type recordingResolver struct {
+	stuckResolver
+	mu   sync.Mutex
+	msgs []*dns.Msg
+}
+
+func (r *recordingResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	r.mu.Lock()
+	r.msgs = append(r.msgs, msg)
+	r.mu.Unlock()
+	return r.stuckResolver.Exchange(ctx, msg)
+}
+
+func TestWatchdogRetry(t *testing.T) {
+	retries := 1
+	server := config.DNSServer{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP, Retries: &retries}
+	r := &recordingResolver{stuckResolver: stuckResolver{release: make(chan struct{})}}
+	defer close(r.release)
+
+	p := &Prober{
+		config:    &config.Config{},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): 10 * time.Millisecond},
+		drained:   make(map[string]bool),
+	}
+	res, ok := p.probe(context.Background(), config.Domain{Name: "retry.example"}, server, r, 0)
+	if !ok || len(res.Attempts) != 2 {
+		t.Fatalf("Expected 2 abandoned attempts, got %+v", res.Attempts)
+	}
+
+	// The first query still runs, so the retry must not share its message
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.msgs) != 2 || r.msgs[0] == r.msgs[1] {
+		t.Errorf("Expected a message per abandoned attempt, got %v", r.msgs)
+	}
+}
+
+func TestOverlapSkip(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP}
+	stuck := &stuckResolver{release: make(chan struct{})}
+
+	var results []Result
+	p := &Prober{
+		config:    &config.Config{},
+		resolvers: map[string]resolver.Resolver{serverKey(server): stuck},
+		timeouts:  map[string]time.Duration{serverKey(server): 10 * time.Millisecond},
+		drained:   make(map[string]bool),
+		callbacks: []func(Result){func(res Result) { results = append(results, res) }},
+	}
+	p.metrics = metrics.New(prometheus.NewRegistry())
+	skips := p.metrics.OverlapSkips.WithLabelValues("192.0.2.1:53", "do53-udp")
+	domain := config.Domain{Name: "overlap.example"}
+
+	if _, ok := p.probe(context.Background(), domain, server, stuck, 0); !ok {
+		t.Fatal("Expected the first probe to be recorded")
+	}
+	if _, ok := p.probe(context.Background(), domain, server, stuck, 0); ok {
+		t.Error("Expected a probe to be skipped while the abandoned query is in flight")
+	}
+	if got := testutil.ToFloat64(skips); got != 1 {
+		t.Errorf("Expected 1 skipped probe, got %v", got)
+	}
+
+	close(stuck.release)
+	inFlight := func() int {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.inFlight[serverKey(server)]
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for inFlight() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := p.probe(context.Background(), domain, server, stuck, 0); !ok {
+		t.Error("Expected probing to resume once the abandoned query returned")
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+}
+
+func TestSLABreaches(t *testing.T) {
+	sla := &config.SLA{Warning: config.Duration(50 * time.Millisecond), Critical: config.Duration(200 * time.Millisecond)}
+	tests := []struct {
+		name              string
+		sla               *config.SLA
+		duration          time.Duration
+		err               error
+		warning, critical bool
+	}{
+		{name: "no sla", duration: time.Second},
+		{name: "within", sla: sla, duration: 10 * time.Millisecond},
+		{name: "warning", sla: sla, duration: 50 * time.Millisecond, warning: true},
+		{name: "critical", sla: sla, duration: 300 * time.Millisecond, warning: true, critical: true},
+		{name: "failed", sla: sla, duration: 10 * time.Millisecond, err: context.DeadlineExceeded, warning: true, critical: true},
+		{name: "critical only", sla: &config.SLA{Critical: sla.Critical}, duration: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Result{Server: config.DNSServer{SLA: tt.sla}, Duration: tt.duration, Err: tt.err}
+			if res.SLAChecked() != (tt.sla != nil) {
+				t.Errorf("Expected checked %v", tt.sla != nil)
+			}
+			if warning, critical := res.SLABreaches(); warning != tt.warning || critical != tt.critical {
+				t.Errorf("Expected breaches %v/%v, got %v/%v", tt.warning, tt.critical, warning, critical)
+			}
+		})
+	}
+}
+
+// flakyResolver fails the first failures queries, then succeeds
+type flakyResolver struct {
+	failures int
+}
+
+func (r *flakyResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *flakyResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	if r.failures > 0 {
+		r.failures--
+		return resolver.QueryResult{Duration: 10 * time.Millisecond, Err: context.DeadlineExceeded}
+	}
+	return resolver.QueryResult{Duration: 20 * time.Millisecond}
+}
+
+func (r *flakyResolver) Protocol() string { return "do53-udp" }
+
+func (r *flakyResolver) Close() error { return nil }
+
+func (r *flakyResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *flakyResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestRetries(t *testing.T) {
+	retries := 2
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP, Retries: &retries}
+	r := &flakyResolver{failures: 1}
+
+	var results []Result
+	p := &Prober{
+		config:    &config.Config{},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   make(map[string]bool),
+	}
+	WithResultCallback(func(res Result) { results = append(results, res) })(p)
+
+	p.probe(context.Background(), config.Domain{Name: "retry.example"}, server, r, 0)
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	res := results[0]
+	if !res.Success() {
+		t.Errorf("Expected probe to succeed after a retry, got %v", res.Err)
+	}
+	if len(res.Attempts) != 2 || res.Attempts[0].Err == nil {
+		t.Errorf("Expected a failed and a successful attempt, got %+v", res.Attempts)
+	}
+	if res.Duration != 30*time.Millisecond {
+		t.Errorf("Expected duration to sum both attempts (30ms), got %v", res.Duration)
+	}
+	if res.Timeout != time.Second || res.Domain.Name != "retry.example" || res.Protocol != "do53-udp" {
+		t.Errorf("Unexpected result metadata: %+v", res)
+	}
+}
+
+func TestLatestResults(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP}
+	r := &flakyResolver{failures: 1}
+	p := &Prober{
+		config:    &config.Config{Site: "fra1"},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   make(map[string]bool),
+	}
+
+	p.probe(context.Background(), config.Domain{Name: "latest.example"}, server, r, 0)
+	p.probe(context.Background(), config.Domain{Name: "latest.example"}, server, r, 0)
+
+	latest := p.LatestResults()
+	if len(latest) != 1 {
+		t.Fatalf("Expected 1 latest result, got %d", len(latest))
+	}
+	res := latest[0]
+	if !res.Success || res.Error != "" {
+		t.Errorf("Expected the later successful probe to replace the failure, got %+v", res)
+	}
+	if res.Site != "fra1" || res.Server != "192.0.2.4:53" || res.Domain != "latest.example" {
+		t.Errorf("Unexpected result metadata: %+v", res)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	retries := 1
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP, Retries: &retries}
+	r := &flakyResolver{failures: 6}
+	p := &Prober{
+		config:    &config.Config{ErrorHistory: 2},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   make(map[string]bool),
+	}
+
+	for i := 0; i < 3; i++ {
+		p.probe(context.Background(), config.Domain{Name: "errors.example"}, server, r, 0)
+	}
+
+	errs, err := p.Errors(serverKey(server))
+	if err != nil {
+		t.Fatalf("Errors failed: %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("Expected the history to keep 2 failures, got %d", len(errs))
+	}
+	e := errs[0]
+	if e.Time.Before(errs[1].Time) {
+		t.Error("Expected the newest failure first")
+	}
+	if len(e.Attempts) != 2 || e.Attempts[0].Error == "" || e.Attempts[0].Duration != 0.01 {
+		t.Errorf("Expected 2 failed attempts, got %+v", e.Attempts)
+	}
+	if len(e.Chain) != 1 || e.Chain[0].Type != "context.deadlineExceededError" {
+		t.Errorf("Unexpected error chain %+v", e.Chain)
+	}
+	if e.Domain != "errors.example" || e.Server != "192.0.2.4:53" || e.Hostname == "" {
+		t.Errorf("Unexpected error metadata: %+v", e)
+	}
+
+	if _, err := p.Errors("192.0.2.9:53:do53-udp"); err == nil {
+		t.Error("Expected error for unknown target, got nil")
+	}
+}
+
+func TestLastResponse(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP}
+	r := &answerResolver{}
+	p := &Prober{
+		config:    &config.Config{},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   make(map[string]bool),
+	}
+	if _, err := p.LastResponse(serverKey(server)); err == nil {
+		t.Error("Expected error before any response, got nil")
+	}
+
+	p.probe(context.Background(), config.Domain{Name: "raw.example"}, server, r, 0)
+
+	raw, err := p.LastResponse(serverKey(server))
+	if err != nil {
+		t.Fatalf("LastResponse failed: %v", err)
+	}
+	wire, err := hex.DecodeString(raw.Hex)
+	if err != nil {
+		t.Fatalf("Invalid hex: %v", err)
+	}
+	msg := new(dns.Msg)
+	if err := msg.Unpack(wire); err != nil {
+		t.Fatalf("Invalid wire format: %v", err)
+	}
+	if msg.Rcode != dns.RcodeNameError || msg.Question[0].Name != raw.Hostname+"." {
+		t.Errorf("Unexpected response %v", msg)
+	}
+	if !strings.Contains(raw.Text, "NXDOMAIN") || raw.Domain != "raw.example" {
+		t.Errorf("Unexpected response metadata: %+v", raw)
+	}
+}
+
+func TestProbeNow(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP}
+	r := &flakyResolver{failures: 1}
+	disabled := false
+	probes := 3
+	var recorded, first int
+	p := &Prober{
+		config: &config.Config{
+			Domains: []config.Domain{
+				{Name: "first.example", Probes: &probes},
+				{Name: "second.example"},
+				{Name: "off.example", Enabled: &disabled},
+			},
+			DNSServers: []config.DNSServer{server},
+		},
+		resolvers: map[string]resolver.Resolver{serverKey(server): r},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   map[string]bool{serverKey(server): true},
+	}
+	WithResultCallback(func(res Result) {
+		recorded++
+		if res.First() {
+			first++
+		}
+	})(p)
+
+	results, err := p.ProbeNow(context.Background(), serverKey(server))
+	if err != nil {
+		t.Fatalf("ProbeNow failed: %v", err)
+	}
+	if len(results) != 2 || recorded != 2 {
+		t.Fatalf("Expected a recorded result per enabled domain, got %d results and %d recorded", len(results), recorded)
+	}
+	if results[0].Domain != "first.example" || results[0].Success || !results[1].Success {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if first != 0 {
+		t.Errorf("Expected no on-demand probe to count as first, got %d", first)
+	}
+
+	if _, err := p.ProbeNow(context.Background(), "192.0.2.9:53:do53-udp"); err == nil {
+		t.Error("Expected error for unknown target, got nil")
+	}
+}
+
+func TestResolverOptions(t *testing.T) {
+	insecure := true
+	server := config.DNSServer{
+		Address:        "9.9.9.9",
+		Port:           "853",
+		Protocol:       config.ProtocolDoT,
+		ConnectTimeout: 500,
+		TLS:            &config.TLSConfig{ServerName: "dns.quad9.net", InsecureSkipVerify: &insecure},
+	}
+
+	opts := resolverOptions(server, 2*time.Second)
+	if opts.Address != "9.9.9.9" || opts.Port != "853" {
+		t.Errorf("Unexpected address %s:%s", opts.Address, opts.Port)
+	}
+	if opts.ServerName != "dns.quad9.net" || !opts.InsecureSkipVerify {
+		t.Errorf("Expected TLS settings to be copied, got %+v", opts)
+	}
+	want := resolver.Timeouts{Total: 2 * time.Second, Connect: 500 * time.Millisecond}
+	if opts.Timeouts != want {
+		t.Errorf("Expected timeouts %+v, got %+v", want, opts.Timeouts)
+	}
+}
+
+func TestDNSSECMismatch(t *testing.T) {
+	response := func(authenticated bool) resolver.QueryResult {
+		msg := new(dns.Msg)
+		msg.AuthenticatedData = authenticated
+		return resolver.QueryResult{Response: msg}
+	}
+
+	tests := []struct {
+		name     string
+		dnssec   string
+		attempt  resolver.QueryResult
+		checked  bool
+		mismatch bool
+	}{
+		{"no expectation", "", response(false), false, false},
+		{"secure validated", config.DNSSECSecure, response(true), true, false},
+		{"secure not validated", config.DNSSECSecure, response(false), true, true},
+		{"insecure plain", config.DNSSECInsecure, response(false), true, false},
+		{"insecure validated", config.DNSSECInsecure, response(true), true, true},
+		{"failed query", config.DNSSECSecure, resolver.QueryResult{Err: context.DeadlineExceeded}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Result{
+				Domain:   config.Domain{Name: "example.com", DNSSEC: tt.dnssec},
+				Attempts: []resolver.QueryResult{tt.attempt},
+				Err:      tt.attempt.Err,
+			}
+			if got := res.DNSSECChecked(); got != tt.checked {
+				t.Errorf("DNSSECChecked() = %v, want %v", got, tt.checked)
+			}
+			if got := res.DNSSECMismatch(); got != tt.mismatch {
+				t.Errorf("DNSSECMismatch() = %v, want %v", got, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestQueryMessageDNSSEC(t *testing.T) {
+	msg := queryMessage(config.Domain{Name: "example.com"}, config.DNSServer{}, "abcde.example.com")
+	if msg.IsEdns0() != nil {
+		t.Error("Expected no EDNS0 record without a DNSSEC expectation")
+	}
+
+	msg = queryMessage(config.Domain{Name: "example.com", DNSSEC: config.DNSSECSecure}, config.DNSServer{}, "abcde.example.com")
+	opt := msg.IsEdns0()
+	if opt == nil || !opt.Do() {
+		t.Error("Expected the DO bit to be set for a DNSSEC expectation")
+	}
+}
+
+func TestQueryMessageAuthoritative(t *testing.T) {
+	domain := config.Domain{Name: "example.com"}
+	if msg := queryMessage(domain, config.DNSServer{}, "abcde.example.com"); !msg.RecursionDesired {
+		t.Error("Expected RD to be set for a recursive server")
+	}
+	if msg := queryMessage(domain, config.DNSServer{Authoritative: true}, "abcde.example.com"); msg.RecursionDesired {
+		t.Error("Expected RD to be cleared for an authoritative server")
+	}
+}
+
+// answerResolver answers every query with the addresses configured for its
+// name, or NXDOMAIN for unknown names
+type answerResolver struct {
+	answers map[string][]string
+}
+
+func (r *answerResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *answerResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	name := msg.Question[0].Name
+	addrs, ok := r.answers[name]
+	if !ok {
+		resp.Rcode = dns.RcodeNameError
+	}
+	for _, addr := range addrs {
+		rr, _ := dns.NewRR(name + " 300 IN " + dns.TypeToString[msg.Question[0].Qtype] + " " + addr)
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resolver.QueryResult{Response: resp}
+}
+
+func (r *answerResolver) Protocol() string { return "do53-udp" }
+
+func (r *answerResolver) Close() error { return nil }
+
+func (r *answerResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *answerResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestBlocked(t *testing.T) {
+	r := &answerResolver{answers: map[string][]string{
+		"public.example.":   {"192.0.2.1"},
+		"sinkhole.example.": {"0.0.0.0"},
+		"private.example.":  {"10.0.0.1", "127.0.0.1"},
+		"mixed.example.":    {"10.0.0.1", "198.51.100.1"},
+		"empty.example.":    {},
+	}}
+	tests := map[string]bool{
+		"public.example":   false,
+		"sinkhole.example": true,
+		"private.example":  true,
+		"mixed.example":    false,
+		"empty.example":    true,
+		"missing.example":  true,
+	}
+	for name, want := range tests {
+		resp := r.Query(context.Background(), name, dns.TypeA).Response
+		if got := blocked(resp); got != want {
+			t.Errorf("blocked(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestBlockBypassed(t *testing.T) {
+	r := &answerResolver{answers: map[string][]string{
+		"public.example.": {"192.0.2.1"},
+		"walled.example.": {"198.51.100.10"},
+		"mixed.example.":  {"198.51.100.10", "192.0.2.1"},
+		"empty.example.":  {},
+	}}
+	nxdomain := &config.Blocked{Rcode: "nxdomain"}
+	walled := &config.Blocked{Answers: config.StringList{"198.51.100.10"}}
+	either := &config.Blocked{Rcode: "NXDOMAIN", Answers: config.StringList{"198.51.100.10"}}
+	sinkhole := &config.Blocked{}
+
+	tests := []struct {
+		name     string
+		blocked  *config.Blocked
+		bypassed bool
+	}{
+		{"public.example", nxdomain, true},
+		{"missing.example", nxdomain, false},
+		{"walled.example", nxdomain, true},
+		{"walled.example", walled, false},
+		{"mixed.example", walled, true},
+		{"empty.example", walled, true},
+		{"missing.example", walled, true},
+		{"missing.example", either, false},
+		{"walled.example", either, false},
+		{"public.example", either, true},
+		{"empty.example", sinkhole, false},
+		{"public.example", sinkhole, true},
+	}
+	for _, tt := range tests {
+		res := Result{
+			Domain:   config.Domain{Name: tt.name, Blocked: tt.blocked},
+			Attempts: []resolver.QueryResult{r.Query(context.Background(), tt.name, dns.TypeA)},
+		}
+		if !res.BlockChecked() {
+			t.Errorf("Expected %s to be checked", tt.name)
+		}
+		if got := res.BlockBypassed(); got != tt.bypassed {
+			t.Errorf("BlockBypassed(%s, %+v) = %v, want %v", tt.name, *tt.blocked, got, tt.bypassed)
+		}
+	}
+
+	res := Result{
+		Domain:   config.Domain{Name: "public.example", Blocked: nxdomain},
+		Attempts: []resolver.QueryResult{{Err: context.DeadlineExceeded}},
+		Err:      context.DeadlineExceeded,
+	}
+	if res.BlockChecked() || res.BlockBypassed() {
+		t.Error("Expected a failed probe not to be checked")
+	}
+}
+
+func TestCheckFiltering(t *testing.T) {
+	filtered := config.DNSServer{Address: "192.0.2.5", Port: "53", Protocol: config.ProtocolDo53UDP}
+	open := config.DNSServer{Address: "192.0.2.6", Port: "53", Protocol: config.ProtocolDo53UDP}
+	reference := config.DNSServer{Address: "192.0.2.7", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		DNSServers: []config.DNSServer{filtered, open},
+		Filtering: config.Filtering{
+			Reference: reference,
+			Categories: map[string]config.StringList{
+				"malware": {"malware.example", "gone.example"},
+			},
+		},
+	}
+	answers := map[string][]string{"malware.example.": {"192.0.2.100"}}
+
+	var results []FilteringResult
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(filtered): &answerResolver{answers: map[string][]string{"malware.example.": {"0.0.0.0"}}},
+			serverKey(open):     &answerResolver{answers: answers},
+		},
+		references: map[string]resolver.Resolver{serverKey(reference): &answerResolver{answers: answers}},
+		timeouts: map[string]time.Duration{
+			serverKey(filtered):  time.Second,
+			serverKey(open):      time.Second,
+			serverKey(reference): time.Second,
+		},
+		drained:            make(map[string]bool),
+		filteringCallbacks: []func(FilteringResult){func(res FilteringResult) { results = append(results, res) }},
+	}
+	p.checkFiltering(context.Background(), map[string]bool{serverKey(filtered): true, serverKey(open): true})
+
+	if len(results) != 2 {
+		t.Fatalf("Expected a result per server, got %d", len(results))
+	}
+	for _, res := range results {
+		if res.Checked != 1 {
+			t.Errorf("Expected only the domain the reference resolves to be checked, got %d", res.Checked)
+		}
+		if want := res.Server.Address == filtered.Address; res.Active() != want {
+			t.Errorf("Expected filtering active=%v for %s, got blocked %v", want, res.Server.Address, res.Blocked)
+		}
+	}
+}
+
+func TestDiverges(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected expectation
+		got      []string
+		diverged bool
+	}{
+		{"pinned match", expectation{answers: []string{"192.0.2.1", "192.0.2.2"}, pinned: true}, []string{"192.0.2.1"}, false},
+		{"pinned extra address", expectation{answers: []string{"192.0.2.1"}, pinned: true}, []string{"192.0.2.1", "203.0.113.1"}, true},
+		{"pinned nxdomain", expectation{answers: []string{"192.0.2.1"}, pinned: true}, []string{"NXDOMAIN"}, true},
+		{"reference overlap", expectation{answers: []string{"192.0.2.1", "192.0.2.2"}}, []string{"192.0.2.2", "192.0.2.3"}, false},
+		{"reference disjoint", expectation{answers: []string{"192.0.2.1"}}, []string{"203.0.113.1"}, true},
+		{"reference both nxdomain", expectation{answers: []string{"NXDOMAIN"}}, []string{"NXDOMAIN"}, false},
+		{"reference both empty", expectation{}, nil, false},
+		{"reference empty answer", expectation{answers: []string{"192.0.2.1"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expected.diverges(tt.got); got != tt.diverged {
+				t.Errorf("diverges() = %v, want %v", got, tt.diverged)
+			}
+		})
+	}
+}
+
+func TestCheckDivergence(t *testing.T) {
+	honest := config.DNSServer{Address: "192.0.2.5", Port: "53", Protocol: config.ProtocolDo53UDP}
+	hijacked := config.DNSServer{Address: "192.0.2.6", Port: "53", Protocol: config.ProtocolDo53UDP}
+	reference := config.DNSServer{Address: "192.0.2.7", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "pinned.example", Reference: &config.Reference{Answers: config.StringList{"192.0.2.100"}}},
+			{Name: "referenced.example", Reference: &config.Reference{Resolver: &reference}},
+			{Name: "v6.example", QType: "AAAA", Reference: &config.Reference{Answers: config.StringList{"2001:db8::100"}}},
+		},
+		DNSServers: []config.DNSServer{honest, hijacked},
+	}
+	answers := map[string][]string{
+		"pinned.example.":     {"192.0.2.100"},
+		"referenced.example.": {"192.0.2.101"},
+		"v6.example.":         {"2001:db8::100"},
+	}
+
+	var results []DivergenceResult
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(honest): &answerResolver{answers: answers},
+			serverKey(hijacked): &answerResolver{answers: map[string][]string{
+				"pinned.example.":     {"203.0.113.66"},
+				"referenced.example.": {"203.0.113.66"},
+				"v6.example.":         {"2001:db8::66"},
+			}},
+		},
+		references: map[string]resolver.Resolver{serverKey(reference): &answerResolver{answers: answers}},
+		timeouts: map[string]time.Duration{
+			serverKey(honest):    time.Second,
+			serverKey(hijacked):  time.Second,
+			serverKey(reference): time.Second,
+		},
+		drained:             make(map[string]bool),
+		divergenceCallbacks: []func(DivergenceResult){func(res DivergenceResult) { results = append(results, res) }},
+	}
+	p.checkDivergence(context.Background(), map[string]bool{serverKey(honest): true, serverKey(hijacked): true})
+
+	if len(results) != 6 {
+		t.Fatalf("Expected 6 comparisons, got %d", len(results))
+	}
+	for _, res := range results {
+		if want := res.Server.Address == hijacked.Address; res.Diverged != want {
+			t.Errorf("Expected diverged=%v for %s on %s, got %v", want, res.Domain.Name, res.Server.Address, res.Got)
+		}
+	}
+
+	divergences := p.Divergences()
+	if len(divergences) != 3 {
+		t.Fatalf("Expected 3 recorded divergences, got %d", len(divergences))
+	}
+	if divergences[0].Target != serverKey(hijacked) || divergences[0].Domain != "pinned.example" {
+		t.Errorf("Unexpected first divergence %+v", divergences[0])
+	}
+}
+
+func TestCheckDivergenceViews(t *testing.T) {
+	internal := config.DNSServer{Address: "10.0.0.53", Port: "53", Protocol: config.ProtocolDo53UDP, View: "internal"}
+	external := config.DNSServer{Address: "192.0.2.53", Port: "53", Protocol: config.ProtocolDo53UDP, View: "external"}
+	leaking := config.DNSServer{Address: "192.0.2.54", Port: "53", Protocol: config.ProtocolDo53UDP, View: "external"}
+	unviewed := config.DNSServer{Address: "192.0.2.55", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		Domains: []config.Domain{{Name: "intranet.example", Reference: &config.Reference{Views: map[string]config.StringList{
+			"internal": {"10.0.0.80"},
+			"external": {"nxdomain"},
+		}}}},
+		DNSServers: []config.DNSServer{internal, external, leaking, unviewed},
+	}
+	inside := &answerResolver{answers: map[string][]string{"intranet.example.": {"10.0.0.80"}}}
+	outside := &answerResolver{answers: map[string][]string{}}
+
+	var results []DivergenceResult
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(internal): inside,
+			serverKey(external): outside,
+			serverKey(leaking):  inside,
+			serverKey(unviewed): inside,
+		},
+		timeouts:            make(map[string]time.Duration),
+		drained:             make(map[string]bool),
+		divergenceCallbacks: []func(DivergenceResult){func(res DivergenceResult) { results = append(results, res) }},
+	}
+	due := make(map[string]bool)
+	for _, server := range cfg.DNSServers {
+		p.timeouts[serverKey(server)] = time.Second
+		due[serverKey(server)] = true
+	}
+	p.checkDivergence(context.Background(), due)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 comparisons without the server outside any view, got %d", len(results))
+	}
+	for _, res := range results {
+		if want := res.Server.Address == leaking.Address; res.Diverged != want {
+			t.Errorf("Expected diverged=%v for %s in view %s, got %v", want, res.Server.Address, res.Server.View, res.Got)
+		}
+	}
+	if divergences := p.Divergences(); len(divergences) != 1 || divergences[0].View != "external" {
+		t.Errorf("Expected the leaked record to be recorded for the external view, got %+v", divergences)
+	}
+}
+
+func TestCheckTransports(t *testing.T) {
+	udp := config.DNSServer{Address: "192.0.2.5", Port: "53", Protocol: config.ProtocolDo53UDP, CompareTransports: true}
+	tcp := config.DNSServer{Address: "192.0.2.6", Port: "53", Protocol: config.ProtocolDo53TCP, CompareTransports: true}
+	plain := config.DNSServer{Address: "192.0.2.7", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{udp, tcp, plain},
+	}
+
+	// TCP fails on both servers, whichever transport is configured
+	var results []TransportComparison
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(udp):   &flakyResolver{},
+			serverKey(tcp):   &flakyResolver{failures: 1},
+			serverKey(plain): &flakyResolver{},
+		},
+		companions: map[string]resolver.Resolver{
+			serverKey(udp): &flakyResolver{failures: 1},
+			serverKey(tcp): &flakyResolver{},
+		},
+		timeouts: map[string]time.Duration{
+			serverKey(udp):                 time.Second,
+			serverKey(otherTransport(udp)): time.Second,
+			serverKey(tcp):                 time.Second,
+			serverKey(otherTransport(tcp)): time.Second,
+		},
+		drained:             make(map[string]bool),
+		comparisonCallbacks: []func(TransportComparison){func(res TransportComparison) { results = append(results, res) }},
+	}
+	p.checkTransports(context.Background(), map[string]bool{serverKey(udp): true, serverKey(tcp): true, serverKey(plain): true})
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 comparisons, got %d", len(results))
+	}
+	for _, res := range results {
+		if res.UDP.Err != nil || res.TCP.Err == nil {
+			t.Errorf("Expected only TCP to fail for %s, got udp: %v, tcp: %v", res.Server.Address, res.UDP.Err, res.TCP.Err)
+		}
+		if _, ok := res.Delta(); ok {
+			t.Errorf("Expected no delta for %s with a failed query", res.Server.Address)
+		}
+	}
+
+	p.companions[serverKey(udp)] = &flakyResolver{}
+	results = nil
+	p.checkTransports(context.Background(), map[string]bool{serverKey(udp): true})
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 comparison, got %d", len(results))
+	}
+	if delta, ok := results[0].Delta(); !ok || delta != 0 {
+		t.Errorf("Expected a delta of 0, got %s (%v)", delta, ok)
+	}
+}
+
+func TestCheckDowngrades(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.5", Port: "853", Protocol: config.ProtocolDoT, DowngradeCheck: true}
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{server},
+	}
+	key := serverKey(server)
+
+	var results []DowngradeResult
+	p := &Prober{
+		config:     cfg,
+		resolvers:  map[string]resolver.Resolver{key: &flakyResolver{failures: 1}},
+		companions: map[string]resolver.Resolver{key: &flakyResolver{failures: 1}},
+		timeouts: map[string]time.Duration{
+			key:                          time.Second,
+			serverKey(plaintext(server)): time.Second,
+		},
+		drained:            make(map[string]bool),
+		downgradeCallbacks: []func(DowngradeResult){func(res DowngradeResult) { results = append(results, res) }},
+	}
+	due := map[string]bool{key: true}
+
+	// Not probed yet, so nothing to compare with
+	p.checkDowngrades(context.Background(), due)
+	if len(results) != 0 {
+		t.Fatalf("Expected no check before the first probe, got %d", len(results))
+	}
+
+	// Both transports fail: the server is down
+	p.probeDomains(context.Background(), due)
+	p.checkDowngrades(context.Background(), due)
+	if len(results) != 1 || results[0].Encrypted || results[0].Downgraded() {
+		t.Fatalf("Expected a failed check without downgrade, got %+v", results)
+	}
+
+	// Only plaintext answers
+	p.checkDowngrades(context.Background(), due)
+	if len(results) != 2 || !results[1].Downgraded() {
+		t.Fatalf("Expected a downgrade, got %+v", results)
+	}
+
+	// Both transports answer
+	p.probeDomains(context.Background(), due)
+	p.checkDowngrades(context.Background(), due)
+	if len(results) != 3 || !results[2].Encrypted || results[2].Downgraded() {
+		t.Fatalf("Expected a working encrypted transport, got %+v", results)
+	}
+}
+
+// altSvcResolver answers every query with the configured Alt-Svc header
+type altSvcResolver struct {
+	altSvc string
+}
+
+func (r *altSvcResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *altSvcResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	return resolver.QueryResult{Duration: 20 * time.Millisecond, AltSvc: r.altSvc}
+}
+
+func (r *altSvcResolver) Protocol() string { return "doh" }
+
+func (r *altSvcResolver) Close() error { return nil }
+
+func (r *altSvcResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *altSvcResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestCheckAltSvc(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.5", Port: "443", Protocol: config.ProtocolDoH, AltSvc: config.AltSvcProbe}
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{server},
+	}
+	key := serverKey(server)
+	doh := &altSvcResolver{altSvc: `h3=":8443"; ma=86400`}
+
+	var results []Result
+	p := &Prober{
+		config:    cfg,
+		resolvers: map[string]resolver.Resolver{key: doh},
+		http3:     map[string]resolver.Resolver{key: &flakyResolver{}},
+		timeouts: map[string]time.Duration{
+			key:                            time.Second,
+			serverKey(http3Server(server)): time.Second,
+		},
+		drained:   make(map[string]bool),
+		callbacks: []func(Result){func(res Result) { results = append(results, res) }},
+	}
+	due := map[string]bool{key: true}
+
+	// HTTP/3 on another port is not probed
+	p.runCycle(context.Background())
+	if len(results) != 1 {
+		t.Fatalf("Expected only the DoH probe, got %d results", len(results))
+	}
+
+	doh.altSvc = `h3=":443"; ma=86400`
+	results = nil
+	p.probeDomains(context.Background(), due)
+	p.checkAltSvc(context.Background(), due)
+	if len(results) != 2 {
+		t.Fatalf("Expected a DoH and a DoH3 probe, got %d results", len(results))
+	}
+	if results[1].Server.Protocol != config.ProtocolDoH3 || results[1].Server.Port != "443" {
+		t.Errorf("Expected a DoH3 probe on port 443, got %+v", results[1].Server)
+	}
+}
+
+func TestCheckStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"nsstats":{"Requestv4":5},"qtypes":{"A":3}}`))
+	}))
+	defer srv.Close()
+
+	bind := config.DNSServer{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP,
+		Stats: &config.StatsConfig{Type: config.StatsBIND, Address: strings.TrimPrefix(srv.URL, "http://"), Include: config.StringList{"nsstats."}}}
+	down := config.DNSServer{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP,
+		Stats: &config.StatsConfig{Type: config.StatsUnbound, Address: "/nonexistent/unbound.ctl"}}
+	plain := config.DNSServer{Address: "192.0.2.3", Port: "53", Protocol: config.ProtocolDo53UDP}
+
+	var results []StatsResult
+	p := &Prober{
+		config: &config.Config{DNSServers: []config.DNSServer{bind, down, plain}},
+		resolvers: map[string]resolver.Resolver{
+			serverKey(bind):  &flakyResolver{},
+			serverKey(down):  &flakyResolver{},
+			serverKey(plain): &flakyResolver{},
+		},
+		timeouts: map[string]time.Duration{
+			serverKey(bind): time.Second,
+			serverKey(down): time.Second,
+		},
+		drained:        make(map[string]bool),
+		statsCallbacks: []func(StatsResult){func(res StatsResult) { results = append(results, res) }},
+	}
+	p.checkStats(context.Background(), map[string]bool{serverKey(bind): true, serverKey(down): true, serverKey(plain): true})
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 scrapes, got %d", len(results))
+	}
+	if res := results[0]; res.Err != nil || len(res.Stats) != 1 || res.Stats["nsstats.Requestv4"] != 5 || res.Protocol != "do53-udp" {
+		t.Errorf("Unexpected BIND statistics: %+v", res)
+	}
+	if res := results[1]; res.Err == nil || res.Stats != nil {
+		t.Errorf("Expected the unbound scrape to fail, got %+v", res)
+	}
+}
+
+// chaosResolver answers CHAOS TXT queries from txt and NSID requests with
+// nsid
+type chaosResolver struct {
+	txt  map[string]string
+	nsid string
+}
+
+func (r *chaosResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *chaosResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	q := msg.Question[0]
+	if txt, ok := r.txt[q.Name]; ok && q.Qclass == dns.ClassCHAOS {
+		resp.Answer = []dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS}, Txt: []string{txt}}}
+	} else if q.Qclass == dns.ClassCHAOS {
+		resp.Rcode = dns.RcodeRefused
+	}
+	if msg.IsEdns0() != nil && r.nsid != "" {
+		resp.SetEdns0(dns.DefaultMsgSize, false)
+		opt := resp.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: hex.EncodeToString([]byte(r.nsid))})
+	}
+	return resolver.QueryResult{Response: resp}
+}
+
+func (r *chaosResolver) Protocol() string { return "do53-udp" }
+
+func (r *chaosResolver) Close() error { return nil }
+
+func (r *chaosResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *chaosResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestCheckFingerprints(t *testing.T) {
+	servers := []config.DNSServer{
+		{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP, Fingerprint: true},
+		{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP, Fingerprint: true},
+		{Address: "192.0.2.3", Port: "53", Protocol: config.ProtocolDo53UDP, Fingerprint: true},
+		{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP},
+	}
+	resolvers := []resolver.Resolver{
+		&chaosResolver{txt: map[string]string{"version.server.": "unbound 1.19.0"}, nsid: "fra1"},
+		&chaosResolver{txt: map[string]string{"authors.bind.": "Mark Andrews"}},
+		&flakyResolver{failures: 100},
+		&chaosResolver{},
+	}
+
+	var results []FingerprintResult
+	p := &Prober{
+		config:               &config.Config{DNSServers: servers},
+		resolvers:            make(map[string]resolver.Resolver),
+		timeouts:             make(map[string]time.Duration),
+		drained:              make(map[string]bool),
+		fingerprintCallbacks: []func(FingerprintResult){func(res FingerprintResult) { results = append(results, res) }},
+	}
+	due := make(map[string]bool)
+	for i, server := range servers {
+		p.resolvers[serverKey(server)] = resolvers[i]
+		p.timeouts[serverKey(server)] = time.Second
+		due[serverKey(server)] = true
+	}
+	p.checkFingerprints(context.Background(), due)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 fingerprints, got %d", len(results))
+	}
+	if fp := results[0].Fingerprint; results[0].Err != nil || fp != (Fingerprint{Software: "unbound", Version: "unbound 1.19.0", NSID: "fra1"}) {
+		t.Errorf("Unexpected fingerprint of unbound: %+v (%v)", fp, results[0].Err)
+	}
+	if fp := results[1].Fingerprint; results[1].Err != nil || fp != (Fingerprint{Software: "bind"}) {
+		t.Errorf("Expected BIND hiding its version, got %+v (%v)", fp, results[1].Err)
+	}
+	if results[2].Err == nil {
+		t.Error("Expected the fingerprint of an unreachable server to fail")
+	}
+}
+
+func TestSoftwareOf(t *testing.T) {
+	for version, want := range map[string]string{
+		"9.18.24-1-Debian":               "bind",
+		"BIND 9.16.1":                    "bind",
+		"unbound 1.19.0":                 "unbound",
+		"PowerDNS Recursor 5.0.2":        "powerdns",
+		"Knot Resolver 5.7.1":            "knot-resolver",
+		"Knot DNS 3.3.4":                 "knot",
+		"dnsmasq-2.90":                   "dnsmasq",
+		"Microsoft DNS 10.0.17763":       "microsoft",
+		"NSD 4.8.0":                      "nsd",
+		"CoreDNS-1.11.1":                 "coredns",
+		"go away":                        SoftwareUnknown,
+		"":                               SoftwareUnknown,
+		"Resolver 9.1 built by somebody": SoftwareUnknown,
+	} {
+		if got := softwareOf(version); got != want {
+			t.Errorf("softwareOf(%q) = %s, expected %s", version, got, want)
+		}
+	}
+}
+
+func TestCheckCatchments(t *testing.T) {
+	servers := []config.DNSServer{
+		{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP, Anycast: true},
+		{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP, Anycast: true},
+		{Address: "192.0.2.3", Port: "53", Protocol: config.ProtocolDo53UDP, Anycast: true},
+	}
+	nsid := &chaosResolver{nsid: "fra1"}
+	idServer := &chaosResolver{txt: map[string]string{"id.server.": "ams2"}}
+	anonymous := &chaosResolver{}
+
+	var results []CatchmentResult
+	p := &Prober{
+		config:             &config.Config{DNSServers: servers},
+		resolvers:          make(map[string]resolver.Resolver),
+		timeouts:           make(map[string]time.Duration),
+		drained:            make(map[string]bool),
+		catchmentCallbacks: []func(CatchmentResult){func(res CatchmentResult) { results = append(results, res) }},
+	}
+	due := make(map[string]bool)
+	for i, r := range []resolver.Resolver{nsid, idServer, anonymous} {
+		p.resolvers[serverKey(servers[i])] = r
+		p.timeouts[serverKey(servers[i])] = time.Second
+		due[serverKey(servers[i])] = true
+	}
+
+	p.checkCatchments(context.Background(), due)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Instance != "fra1" || results[1].Instance != "ams2" || results[0].Changed || results[1].Changed {
+		t.Errorf("Unexpected first results: %+v, %+v", results[0], results[1])
+	}
+	if results[2].Err != errNoInstance {
+		t.Errorf("Expected %v, got %v", errNoInstance, results[2].Err)
+	}
+
+	results = nil
+	nsid.nsid = "fra2"
+	p.checkCatchments(context.Background(), due)
+	if res := results[0]; !res.Changed || res.Instance != "fra2" || res.Previous != "fra1" {
+		t.Errorf("Expected a change from fra1 to fra2, got %+v", res)
+	}
+	if results[1].Changed {
+		t.Errorf("Expected no change for the same instance, got %+v", results[1])
+	}
+}
+
+func TestCheckQUIC(t *testing.T) {
+	domain := config.Domain{Name: "example.com"}
+	doq := config.DNSServer{Address: "192.0.2.1", Port: "853", Protocol: config.ProtocolDoQ}
+	dot := config.DNSServer{Address: "192.0.2.1", Port: "853", Protocol: config.ProtocolDoT}
+	doh := config.DNSServer{Address: "192.0.2.2", Port: "443", Protocol: config.ProtocolDoH, AltSvc: config.AltSvcProbe}
+	lonely := config.DNSServer{Address: "192.0.2.3", Port: "853", Protocol: config.ProtocolDoQ}
+	servers := []config.DNSServer{doq, dot, doh, lonely}
+
+	var results []QUICResult
+	p := &Prober{
+		config:        &config.Config{Domains: []config.Domain{domain}, DNSServers: servers},
+		resolvers:     make(map[string]resolver.Resolver),
+		http3:         map[string]resolver.Resolver{serverKey(doh): &flakyResolver{}},
+		quicCallbacks: []func(QUICResult){func(res QUICResult) { results = append(results, res) }},
+	}
+	due := make(map[string]bool)
+	for _, server := range servers {
+		p.resolvers[serverKey(server)] = &flakyResolver{}
+		due[serverKey(server)] = true
+	}
+	record := func(server config.DNSServer, err error) {
+		p.recordLatest(Result{Domain: domain, Server: server, Protocol: server.Protocol, Err: err})
+	}
+	record(doq, context.DeadlineExceeded)
+	record(dot, nil)
+	record(http3Server(doh), context.DeadlineExceeded)
+	record(doh, nil)
+	record(lonely, context.DeadlineExceeded)
+
+	for cycle := 1; cycle <= quicBlockedCycles; cycle++ {
+		results = nil
+		p.checkQUIC(due)
+		if len(results) != 2 {
+			t.Fatalf("Expected results for the QUIC targets with a TCP target, got %+v", results)
+		}
+		for _, res := range results {
+			if want := cycle == quicBlockedCycles; res.Blocked != want {
+				t.Errorf("Expected blocked=%v for %s in cycle %d, got %v", want, res.Protocol, cycle, res.Blocked)
+			}
+		}
+	}
+	if results[1].Protocol != config.ProtocolDoH3 || results[1].Server.Port != "443" {
+		t.Errorf("Expected the HTTP/3 probe of the DoH server, got %+v", results[1])
+	}
+
+	record(doq, nil)
+	results = nil
+	p.checkQUIC(due)
+	if results[0].Blocked || !results[1].Blocked {
+		t.Errorf("Expected only DoQ to recover, got %+v", results)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	warm := config.DNSServer{Address: "192.0.2.5", Port: "53", Protocol: config.ProtocolDo53UDP, Warmup: true}
+	cold := config.DNSServer{Address: "192.0.2.6", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{warm, cold},
+	}
+
+	results := make(map[string]Result)
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(warm): &flakyResolver{failures: 1},
+			serverKey(cold): &flakyResolver{failures: 1},
+		},
+		timeouts: map[string]time.Duration{serverKey(warm): time.Second, serverKey(cold): time.Second},
+		drained:  make(map[string]bool),
+	}
+	WithResultCallback(func(res Result) { results[res.Server.Address] = res })(p)
+	due := map[string]bool{serverKey(warm): true, serverKey(cold): true}
+
+	p.warmUp(context.Background(), due)
+	if len(results) != 0 {
+		t.Fatalf("Expected the warmup query not to be recorded, got %+v", results)
+	}
+
+	// The warmup query took the first failure of the warm server only
+	p.probeDomains(context.Background(), due)
+	if res := results[warm.Address]; !res.Success() {
+		t.Errorf("Expected the probe after warmup to succeed, got %v", res.Err)
+	}
+	if res := results[cold.Address]; res.Success() {
+		t.Errorf("Expected the probe without warmup to fail")
+	}
+}
+
+func TestProbeIndex(t *testing.T) {
+	server := config.DNSServer{Address: "192.0.2.4", Port: "53", Protocol: config.ProtocolDo53UDP}
+	probes := 2
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com", Probes: &probes}},
+		DNSServers: []config.DNSServer{server},
+	}
+	var results []Result
+	p := &Prober{
+		config:    cfg,
+		resolvers: map[string]resolver.Resolver{serverKey(server): &flakyResolver{}},
+		timeouts:  map[string]time.Duration{serverKey(server): time.Second},
+		drained:   make(map[string]bool),
+	}
+	WithResultCallback(func(res Result) { results = append(results, res) })(p)
+
+	p.probeDomains(context.Background(), map[string]bool{serverKey(server): true})
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if !results[0].First() || results[1].First() || results[1].Index != 1 {
+		t.Errorf("Expected only the first probe to be first, got indexes %d and %d", results[0].Index, results[1].Index)
+	}
+}
+
+// largeResolver answers with as many TXT records as asked for, truncated
+// if they exceed the EDNS buffer size unless over TCP
+type largeResolver struct {
+	records int
+	tcp     bool
+}
+
+func (r *largeResolver) Query(ctx context.Context, hostname string, qtype uint16) resolver.QueryResult {
+	return r.Exchange(ctx, resolver.NewQuery(hostname, qtype))
+}
+
+func (r *largeResolver) Exchange(ctx context.Context, msg *dns.Msg) resolver.QueryResult {
+	resp := new(dns.Msg)
+	resp.SetReply(msg)
+	for range r.records {
+		rr, _ := dns.NewRR(msg.Question[0].Name + ` 300 IN TXT "` + strings.Repeat("x", 200) + `"`)
+		resp.Answer = append(resp.Answer, rr)
+	}
+	if opt := msg.IsEdns0(); !r.tcp && opt != nil && resp.Len() > int(opt.UDPSize()) {
+		resp.Answer = nil
+		resp.Truncated = true
+	}
+	return resolver.QueryResult{Response: resp}
+}
+
+func (r *largeResolver) Protocol() string {
+	if r.tcp {
+		return "do53-tcp"
+	}
+	return "do53-udp"
+}
+
+func (r *largeResolver) Close() error { return nil }
+
+func (r *largeResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *largeResolver) Capabilities() resolver.Capabilities { return resolver.Capabilities{} }
+
+func TestCheckLargeResponses(t *testing.T) {
+	small := config.DNSServer{Address: "192.0.2.5", Port: "53", Protocol: config.ProtocolDo53UDP}
+	large := config.DNSServer{Address: "192.0.2.6", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		DNSServers:    []config.DNSServer{small, large},
+		LargeResponse: config.LargeResponse{Name: "large.example.", QType: "TXT", BufferSize: 1232},
+	}
+
+	results := make(map[string]LargeResponseResult)
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(small): &largeResolver{records: 2},
+			serverKey(large): &largeResolver{records: 20},
+		},
+		fallbacks: map[string]resolver.Resolver{
+			serverKey(small): &largeResolver{records: 2, tcp: true},
+			serverKey(large): &largeResolver{records: 20, tcp: true},
+		},
+		timeouts: map[string]time.Duration{
+			serverKey(small):                 time.Second,
+			serverKey(large):                 time.Second,
+			serverKey(otherTransport(large)): time.Second,
+		},
+		drained:        make(map[string]bool),
+		largeCallbacks: []func(LargeResponseResult){func(res LargeResponseResult) { results[res.Server.Address] = res }},
+	}
+	p.checkLargeResponses(context.Background(), map[string]bool{serverKey(small): true, serverKey(large): true})
+
+	if res := results[small.Address]; !res.Success() || res.Truncated() || res.Fallback != nil || res.Size() < 400 {
+		t.Errorf("Expected a complete answer over UDP, got %+v", res)
+	}
+	res := results[large.Address]
+	if !res.Truncated() || res.Fallback == nil {
+		t.Fatalf("Expected a truncated answer asked again over TCP, got %+v", res)
+	}
+	if !res.Success() || res.Size() < 4000 {
+		t.Errorf("Expected the complete answer over TCP, got %d bytes: %v", res.Size(), res.Final().Err)
+	}
+}
+
+func TestProviderSummaries(t *testing.T) {
+	a := config.DNSServer{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP, Labels: map[string]string{ProviderLabel: "alpha"}}
+	b := config.DNSServer{Address: "192.0.2.2", Port: "53", Protocol: config.ProtocolDo53UDP, Labels: map[string]string{ProviderLabel: "alpha"}}
+	other := config.DNSServer{Address: "192.0.2.3", Port: "53", Protocol: config.ProtocolDo53UDP}
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{a, b, other},
+	}
+	p := &Prober{
+		config: cfg,
+		resolvers: map[string]resolver.Resolver{
+			serverKey(a):     &flakyResolver{},
+			serverKey(b):     &flakyResolver{failures: 1},
+			serverKey(other): &flakyResolver{},
+		},
+		timeouts: map[string]time.Duration{serverKey(a): time.Second, serverKey(b): time.Second, serverKey(other): time.Second},
+		drained:  make(map[string]bool),
+	}
+	p.probeDomains(context.Background(), map[string]bool{serverKey(a): true, serverKey(b): true, serverKey(other): true})
+
+	summaries := p.providerSummaries()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 provider, got %+v", summaries)
+	}
+	s := summaries[0]
+	if s.Provider != "alpha" || s.Protocol != "do53-udp" || s.Probes != 2 || s.Successes != 1 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+	if s.Latency != 0.02 {
+		t.Errorf("Expected the latency of the successful probe, got %v", s.Latency)
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	server := config.DNSServer{Address: "dns.example", Port: "853", Protocol: config.ProtocolDoT, Upstreams: 2}
+	p := &Prober{config: &config.Config{}}
+	answeredBy := func(server config.DNSServer, addr string) Result {
+		return Result{Server: server, Attempts: []resolver.QueryResult{{RemoteAddr: addr}}}
+	}
+
+	for _, tt := range []struct{ addr, want string }{
+		{"192.0.2.1:853", "192.0.2.1"},
+		{"[2001:db8::1]:853", "2001:db8::1"},
+		{"192.0.2.3:853", UpstreamOther},
+		{"192.0.2.1:853", "192.0.2.1"},
+		{"", ""},
+	} {
+		if got := p.upstream(answeredBy(server, tt.addr)); got != tt.want {
+			t.Errorf("Expected upstream %q for %q, got %q", tt.want, tt.addr, got)
+		}
+	}
+
+	server.Upstreams = 0
+	if got := p.upstream(answeredBy(server, "192.0.2.1:853")); got != "" {
+		t.Errorf("Expected no upstream without tracking, got %q", got)
+	}
+}
+
+func TestClockJump(t *testing.T) {
+	start := time.Now()
+	if jump := clockJump(start, start.Add(5*time.Second)); jump != 0 {
+		t.Errorf("Expected no jump while both clocks advance alike, got %s", jump)
+	}
+	if jump := clockJump(start.Round(0), time.Now().Round(0)); jump != 0 {
+		t.Errorf("Expected no jump without monotonic readings, got %s", jump)
+	}
+
+	for _, tc := range []struct {
+		jump time.Duration
+		want bool
+	}{
+		{0, false},
+		{500 * time.Millisecond, false},
+		{-maxClockJump, false},
+		{2 * time.Second, true},
+		{-time.Hour, true},
+	} {
+		if got := jumped(tc.jump); got != tc.want {
+			t.Errorf("jumped(%s) = %v, want %v", tc.jump, got, tc.want)
+		}
+	}
+}
+
+func TestFaultyResolver(t *testing.T) {
+	answers := &answerResolver{answers: map[string][]string{"example.com.": {"192.0.2.1"}}}
+	msg := resolver.NewQuery("example.com", dns.TypeA)
+
+	f := &faultyResolver{Resolver: answers, faults: config.Faults{Latency: config.Duration(20 * time.Millisecond)}, timeout: time.Second}
+	result := f.Exchange(context.Background(), msg)
+	if result.Err != nil || result.Response.Rcode != dns.RcodeSuccess || len(result.Response.Answer) != 1 {
+		t.Errorf("Expected the answer with added latency only, got %+v", result)
+	}
+
+	f = &faultyResolver{Resolver: answers, faults: config.Faults{Servfail: 1}, timeout: time.Second}
+	result = f.Exchange(context.Background(), msg)
+	if result.Err != nil || result.Response.Rcode != dns.RcodeServerFailure || len(result.Response.Answer) != 0 {
+		t.Errorf("Expected SERVFAIL, got %+v", result)
+	}
+
+	f = &faultyResolver{Resolver: answers, faults: config.Faults{Timeout: 1}, timeout: 50 * time.Millisecond}
+	result = f.Exchange(context.Background(), msg)
+	if !errors.Is(result.Err, os.ErrDeadlineExceeded) || result.Duration < 50*time.Millisecond {
+		t.Errorf("Expected a timeout after 50ms, got %v after %s", result.Err, result.Duration)
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	cfg := &config.Config{
+		Domains:    []config.Domain{{Name: "example.com"}},
+		DNSServers: []config.DNSServer{{Address: "192.0.2.1", Port: "53", Protocol: config.ProtocolDo53UDP}},
+	}
+	a, err := New(cfg, WithSeed(42))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer a.Close()
+	b, err := New(cfg, WithSeed(42))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer b.Close()
+
+	for range 3 {
+		if x, y := a.random.prefix(5), b.random.prefix(5); x != y || len(x) != 8 {
+			t.Errorf("Expected the same prefixes with the same seed, got %s and %s", x, y)
+		}
+		if x, y := a.random.float64(), b.random.float64(); x != y {
+			t.Errorf("Expected the same numbers with the same seed, got %v and %v", x, y)
+		}
+	}
+
+	var unseeded *random
+	if unseeded.prefix(5) == unseeded.prefix(5) {
+		t.Error("Expected different prefixes without a seed")
+	}
+	if d := unseeded.duration(time.Millisecond); d < 0 || d >= time.Millisecond {
+		t.Errorf("Expected a duration below 1ms, got %s", d)
+	}
+}
+
+func TestReuse(t *testing.T) {
+	retries := 2
+	oldCfg := &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "8.8.8.8", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "9.9.9.9", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		Timeout: 2000,
+	}
+	newCfg := &config.Config{
+		DNSServers: []config.DNSServer{
+			{Address: "9.9.9.9", Port: "53", Protocol: config.ProtocolDo53UDP},
+			{Address: "1.1.1.1", Port: "53", Protocol: config.ProtocolDo53UDP, Retries: &retries},
+			{Address: "1.0.0.1", Port: "53", Protocol: config.ProtocolDo53UDP},
+		},
+		Timeout: 2000,
+	}
+	old, err := New(oldCfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	old.cycles = 7
+	p, err := New(newCfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer p.Close()
+
+	if n := p.Reuse(old); n != 1 {
+		t.Errorf("Expected 1 resolver taken over, got %d", n)
+	}
+	if p.cycles != old.cycles {
+		t.Errorf("Expected the cycle count to carry over, so that the startup ramp does not run again, got %d", p.cycles)
+	}
+	old.Close()
+
+	tests := []struct {
+		key    string
+		reused bool
+	}{
+		{"9.9.9.9:53:do53-udp", true},
+		{"1.1.1.1:53:do53-udp", false},
+		{"1.0.0.1:53:do53-udp", false},
+	}
+	for _, tt := range tests {
+		if reused := p.resolvers[tt.key] == old.resolvers[tt.key]; reused != tt.reused {
+			t.Errorf("%s: expected reused=%v, got %v", tt.key, tt.reused, reused)
+		}
+	}
+}
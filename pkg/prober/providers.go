@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/farrokhi/dnspulse_exporter/pkg/metrics"
+)
+
+// ProviderLabel is the custom label grouping servers by the operator of
+// the service, such as several anycast addresses of one public resolver
+const ProviderLabel = "provider"
+
+// recordProviders exports the aggregates of every provider
+func (p *Prober) recordProviders() {
+	p.metrics.SetProviders(p.providerSummaries())
+}
+
+// providerSummaries aggregates the latest probes of the servers carrying a
+// provider label by provider and protocol, for the enabled domains. The
+// latency is the mean duration of the successful probes.
+func (p *Prober) providerSummaries() []metrics.ProviderSummary {
+	type providerKey struct{ provider, protocol string }
+	summaries := make(map[providerKey]*metrics.ProviderSummary)
+
+	p.mu.Lock()
+	for _, server := range p.config.DNSServers {
+		provider := server.Labels[ProviderLabel]
+		if provider == "" {
+			continue
+		}
+		key := serverKey(server)
+		for _, domain := range p.config.Domains {
+			res, ok := p.latest[fmt.Sprintf("%s|%s", key, domain.Name)]
+			if !ok || !domain.IsEnabled() {
+				continue
+			}
+			k := providerKey{provider, res.Protocol}
+			s, ok := summaries[k]
+			if !ok {
+				s = &metrics.ProviderSummary{Provider: provider, Protocol: res.Protocol}
+				summaries[k] = s
+			}
+			s.Probes++
+			if res.Success {
+				s.Successes++
+				s.Latency += res.Duration
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	result := make([]metrics.ProviderSummary, 0, len(summaries))
+	for _, s := range summaries {
+		if s.Successes > 0 {
+			s.Latency /= float64(s.Successes)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].Protocol < result[j].Protocol
+	})
+	return result
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"fmt"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// quicBlockedCycles is how many cycles in a row QUIC must fail while TCP
+// works before it counts as blocked
+const quicBlockedCycles = 3
+
+// QUICResult tells whether QUIC to a server address appears blocked on the
+// path: for quicBlockedCycles cycles in a row, every latest probe over QUIC
+// (DoQ or DoH3) failed, while a probe over TCP (Do53, DoT or DoH) to the
+// same address succeeded. Middleboxes dropping UDP/443 or UDP/853 cause
+// this pattern.
+type QUICResult struct {
+	Server   config.DNSServer
+	Protocol string
+	Blocked  bool
+}
+
+// WithQUICCallback registers fn to receive the result of every check of a
+// QUIC target with a TCP target at the same address. Like result
+// callbacks, it runs on the probing goroutine.
+func WithQUICCallback(fn func(QUICResult)) Option {
+	return func(p *Prober) {
+		p.quicCallbacks = append(p.quicCallbacks, fn)
+	}
+}
+
+// networkPath identifies a server address as reached through a network
+// namespace, VRF and source address
+func networkPath(server config.DNSServer) string {
+	return fmt.Sprintf("%s|%s|%s|%s", server.Address, server.Netns, server.VRF, server.SourceAddress)
+}
+
+// checkQUIC compares the latest probes of due QUIC targets with those of
+// TCP targets at the same address, through the same network path, and logs
+// when QUIC becomes blocked or unblocked
+func (p *Prober) checkQUIC(due map[string]bool) {
+	var quic []config.DNSServer
+	tcpUp := make(map[string]bool)
+	tcpProbed := make(map[string]bool)
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		if _, ok := p.resolvers[key]; !ok {
+			continue
+		}
+		if config.IsQUICProtocol(server.Protocol) && due[key] {
+			quic = append(quic, server)
+		}
+		if _, ok := p.http3[key]; ok && due[key] {
+			quic = append(quic, http3Server(server))
+		}
+		if config.IsTCPProtocol(server.Protocol) {
+			up, probed := p.encryptedUp(key)
+			path := networkPath(server)
+			tcpUp[path] = tcpUp[path] || up
+			tcpProbed[path] = tcpProbed[path] || probed
+		}
+	}
+
+	for _, server := range quic {
+		up, probed := p.encryptedUp(serverKey(server))
+		if !probed || !tcpProbed[networkPath(server)] {
+			continue
+		}
+		res := QUICResult{Server: server, Protocol: server.Protocol}
+		var changed bool
+		res.Blocked, changed = p.recordQUICFailure(serverKey(server), !up && tcpUp[networkPath(server)])
+		if changed && res.Blocked {
+			logging.Warnf("[%s] %s:%s fails while TCP to the same address works; UDP may be blocked on the path",
+				res.Protocol, server.Address, server.Port)
+		} else if changed {
+			logging.Infof("[%s] %s:%s no longer appears blocked", res.Protocol, server.Address, server.Port)
+		}
+		for _, fn := range p.quicCallbacks {
+			fn(res)
+		}
+	}
+}
+
+// recordQUICFailure counts the cycles in a row in which QUIC to a target
+// failed while TCP worked. It returns whether QUIC counts as blocked, and
+// true if that changed.
+func (p *Prober) recordQUICFailure(key string, failed bool) (bool, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.quicFailures == nil {
+		p.quicFailures = make(map[string]int)
+	}
+	before := p.quicFailures[key] >= quicBlockedCycles
+	if failed {
+		p.quicFailures[key]++
+	} else {
+		p.quicFailures[key] = 0
+	}
+	blocked := p.quicFailures[key] >= quicBlockedCycles
+	return blocked, blocked != before
+}
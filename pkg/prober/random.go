@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"encoding/base32"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// random draws the query names, injected faults and name server order of
+// a prober. Unless seeded, and for a nil random, query names come from
+// crypto/rand and everything else from the randomly seeded generator of
+// math/rand. A seeded random is deterministic, so that a run can be
+// reproduced.
+type random struct {
+	mu  sync.Mutex
+	rng *rand.Rand // nil unless seeded
+}
+
+// WithSeed makes the prober draw its random choices from a generator
+// seeded with seed, so that runs with the same seed and configuration send
+// the same queries. Without it they are unpredictable.
+func WithSeed(seed uint64) Option {
+	return func(p *Prober) {
+		p.random.seed(seed)
+	}
+}
+
+// seed switches to a deterministic generator
+func (r *random) seed(seed uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// locked returns the seeded generator with mu held, or nil without holding
+// mu if r is not seeded
+func (r *random) locked() *rand.Rand {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if r.rng == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	return r.rng
+}
+
+// prefix returns length random bytes, base32 encoded, to use as a hostname
+// prefix
+func (r *random) prefix(length uint) string {
+	rng := r.locked()
+	if rng == nil {
+		return generateRandomPrefix(length)
+	}
+	defer r.mu.Unlock()
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
+
+// float64 returns a number in [0, 1)
+func (r *random) float64() float64 {
+	rng := r.locked()
+	if rng == nil {
+		return rand.Float64()
+	}
+	defer r.mu.Unlock()
+	return rng.Float64()
+}
+
+// duration returns a duration in [0, max); max must be positive
+func (r *random) duration(max time.Duration) time.Duration {
+	rng := r.locked()
+	if rng == nil {
+		return rand.N(max)
+	}
+	defer r.mu.Unlock()
+	return time.Duration(rng.Int64N(int64(max)))
+}
+
+// shuffle puts n elements in random order with swap
+func (r *random) shuffle(n int, swap func(i, j int)) {
+	rng := r.locked()
+	if rng == nil {
+		rand.Shuffle(n, swap)
+		return
+	}
+	defer r.mu.Unlock()
+	rng.Shuffle(n, swap)
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// referenceServers returns the reference resolvers of the filtering checks
+// and of the domains' hijack detection
+func referenceServers(cfg *config.Config) []config.DNSServer {
+	var servers []config.DNSServer
+	if cfg.Filtering.Enabled() {
+		servers = append(servers, cfg.Filtering.Reference)
+	}
+	for _, domain := range cfg.Domains {
+		if domain.Reference != nil && domain.Reference.Resolver != nil {
+			servers = append(servers, *domain.Reference.Resolver)
+		}
+	}
+	return servers
+}
+
+// newReferences creates the reference resolvers keyed by server key and
+// adds their timeouts to timeouts
+func newReferences(cfg *config.Config, timeouts map[string]time.Duration) (map[string]resolver.Resolver, error) {
+	references := make(map[string]resolver.Resolver)
+	for _, server := range referenceServers(cfg) {
+		key := serverKey(server)
+		if _, ok := references[key]; ok {
+			continue
+		}
+		timeout := time.Duration(server.Timeout) * time.Millisecond
+		if timeout == 0 {
+			timeout = config.DefaultTimeout(server.Protocol)
+		}
+		r, err := newResolver(server, timeout, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reference resolver %s: %w", server.Address, err)
+		}
+		references[key] = r
+		timeouts[key] = timeout
+	}
+	return references, nil
+}
+
+// queryReference asks a reference resolver for the qtype records of name and
+// returns its response, or nil if the query failed
+func (p *Prober) queryReference(ctx context.Context, server config.DNSServer, name string, qtype uint16) *dns.Msg {
+	key := serverKey(server)
+	if err := p.wait(ctx, key); err != nil {
+		return nil
+	}
+	result := p.query(ctx, server, p.references[key], resolver.NewQuery(name, qtype))
+	if result.Err != nil {
+		logging.Debugf("Reference resolver %s:%s failed to resolve %s: %v", server.Address, server.Port, name, result.Err)
+		return nil
+	}
+	return result.Response
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// Reuse takes over the resolvers of old for the targets configured the same
+// in both, closing those p created for them, so that a reload keeps their
+// connections and sockets. The resolvers of targets that changed or were
+// removed stay with old and are closed with it. It returns the number of
+// resolvers taken over. It also carries over the count of cycles, so that
+// the startup ramp is not run again. It must be called before p starts
+// probing, once old has stopped.
+func (p *Prober) Reuse(old *Prober) int {
+	p.cycles = old.cycles
+	if p.config.IdleTimeout != old.config.IdleTimeout {
+		return 0
+	}
+	previous := make(map[string]config.DNSServer, len(old.config.DNSServers))
+	for _, server := range old.config.DNSServers {
+		previous[serverKey(server)] = server
+	}
+
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	if old.kept == nil {
+		old.kept = make(map[string]bool)
+	}
+	reused := 0
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok {
+			continue
+		}
+		prev, ok := old.resolvers[key]
+		if !ok || !server.Equal(previous[key]) || p.timeouts[key] != old.timeouts[key] {
+			continue
+		}
+		if err := r.Close(); err != nil {
+			logging.Warnf("Failed to close resolver %s: %v", key, err)
+		}
+		p.resolvers[key] = prev
+		old.kept[key] = true
+		reused++
+	}
+	return reused
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RawResponse is the most recent response of a target in wire format and
+// as text
+type RawResponse struct {
+	Target     string    `json:"target"`
+	Domain     string    `json:"domain"`
+	Hostname   string    `json:"hostname"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	Time       time.Time `json:"time"`
+	Hex        string    `json:"hex"`
+	Text       string    `json:"text"`
+}
+
+// lastResponse is the most recent response of a target, kept parsed and
+// only encoded when requested
+type lastResponse struct {
+	msg        *dns.Msg
+	domain     string
+	hostname   string
+	remoteAddr string
+	time       time.Time
+}
+
+// recordResponse keeps the final response of res, if any, as the latest of
+// its target
+func (p *Prober) recordResponse(res Result) {
+	last := res.Last()
+	if last.Response == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.responses == nil {
+		p.responses = make(map[string]lastResponse)
+	}
+	p.responses[serverKey(res.Server)] = lastResponse{
+		msg:        last.Response,
+		domain:     res.Domain.Name,
+		hostname:   res.Hostname,
+		remoteAddr: last.RemoteAddr,
+		time:       time.Now(),
+	}
+}
+
+// LastResponse returns the most recent response of the target with the
+// given key. The wire format is encoded again from the parsed message, so
+// name compression may differ from the bytes the server sent.
+func (p *Prober) LastResponse(key string) (RawResponse, error) {
+	if _, ok := p.resolvers[key]; !ok {
+		return RawResponse{}, fmt.Errorf("unknown target: %s", key)
+	}
+	p.mu.Lock()
+	last, ok := p.responses[key]
+	p.mu.Unlock()
+	if !ok {
+		return RawResponse{}, fmt.Errorf("no response from %s yet", key)
+	}
+
+	wire, err := last.msg.Pack()
+	if err != nil {
+		return RawResponse{}, fmt.Errorf("failed to encode response: %w", err)
+	}
+	return RawResponse{
+		Target:     key,
+		Domain:     last.domain,
+		Hostname:   last.hostname,
+		RemoteAddr: last.remoteAddr,
+		Time:       last.time,
+		Hex:        hex.EncodeToString(wire),
+		Text:       last.msg.String(),
+	}, nil
+}
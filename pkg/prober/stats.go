@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/internal/stats"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// StatsResult is the outcome of scraping the native statistics of a server
+type StatsResult struct {
+	Server   config.DNSServer
+	Protocol string
+
+	// Stats are keyed by the software's own names; nil if Err is set
+	Stats map[string]float64
+	Err   error
+}
+
+// WithStatsCallback registers fn to receive the result of every statistics
+// scrape. Like result callbacks, it runs on the probing goroutine.
+func WithStatsCallback(fn func(StatsResult)) Option {
+	return func(p *Prober) {
+		p.statsCallbacks = append(p.statsCallbacks, fn)
+	}
+}
+
+// statsAddress returns where the statistics of a server are read from.
+// dnsmasq answers the CHAOS queries on its DNS port by default.
+func statsAddress(server config.DNSServer) string {
+	if server.Stats.Address == "" {
+		return net.JoinHostPort(server.Address, server.Port)
+	}
+	return server.Stats.Address
+}
+
+// filterStats drops the statistics whose names start with none of the
+// prefixes, if any are given
+func filterStats(stats map[string]float64, prefixes []string) {
+	if len(prefixes) == 0 {
+		return
+	}
+	for name := range stats {
+		if !slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+			delete(stats, name)
+		}
+	}
+}
+
+// checkStats scrapes the statistics of every due server that has them
+// configured, within the server's timeout
+func (p *Prober) checkStats(ctx context.Context, due map[string]bool) {
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || server.Stats == nil || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() || ctx.Err() != nil {
+			return
+		}
+
+		scrapeCtx, cancel := context.WithTimeout(ctx, p.timeouts[key])
+		res := StatsResult{Server: server, Protocol: r.Protocol()}
+		res.Stats, res.Err = stats.Scrape(scrapeCtx, server.Stats.Type, statsAddress(server))
+		cancel()
+		filterStats(res.Stats, server.Stats.Include)
+		if ctx.Err() != nil {
+			return
+		}
+		if res.Err != nil {
+			logging.Warnf("[%s] %s:%s failed to scrape %s statistics: %v",
+				res.Protocol, server.Address, server.Port, server.Stats.Type, res.Err)
+		}
+		for _, fn := range p.statsCallbacks {
+			fn(res)
+		}
+	}
+}
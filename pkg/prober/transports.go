@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+	"github.com/farrokhi/dnspulse_exporter/pkg/resolver"
+)
+
+// TransportComparison is the outcome of querying a Do53 server for a domain
+// over UDP and TCP back to back
+type TransportComparison struct {
+	Domain   config.Domain
+	Server   config.DNSServer
+	Protocol string
+	UDP      resolver.QueryResult
+	TCP      resolver.QueryResult
+}
+
+// Delta returns how much longer the TCP query took than the UDP query. It
+// returns false unless both succeeded.
+func (c TransportComparison) Delta() (time.Duration, bool) {
+	if c.UDP.Err != nil || c.TCP.Err != nil {
+		return 0, false
+	}
+	return c.TCP.Duration - c.UDP.Duration, true
+}
+
+// WithComparisonCallback registers fn to receive the result of every
+// transport comparison. Like result callbacks, it runs on the probing
+// goroutine.
+func WithComparisonCallback(fn func(TransportComparison)) Option {
+	return func(p *Prober) {
+		p.comparisonCallbacks = append(p.comparisonCallbacks, fn)
+	}
+}
+
+// otherTransport returns a copy of a Do53 server using the other transport,
+// without the options that only apply to the server's own transport
+func otherTransport(server config.DNSServer) config.DNSServer {
+	other := server
+	other.ReuseSocket = false
+	other.TCP = nil
+	if server.Protocol == config.ProtocolDo53TCP {
+		other.Protocol = config.ProtocolDo53UDP
+	} else {
+		other.Protocol = config.ProtocolDo53TCP
+	}
+	return other
+}
+
+// checkTransports asks every due server comparing transports for each
+// domain over UDP, then over TCP, and reports both results
+func (p *Prober) checkTransports(ctx context.Context, due map[string]bool) {
+	for _, domain := range p.config.Domains {
+		if !domain.IsEnabled() {
+			continue
+		}
+		for _, server := range p.config.DNSServers {
+			key := serverKey(server)
+			other, ok := p.companions[key]
+			if !ok || !server.CompareTransports || !due[key] || p.isDrained(key) {
+				continue
+			}
+			if p.Paused() {
+				return
+			}
+
+			udp, tcp := server, otherTransport(server)
+			udpResolver, tcpResolver := p.resolvers[key], other
+			if server.Protocol == config.ProtocolDo53TCP {
+				udp, tcp = tcp, udp
+				udpResolver, tcpResolver = tcpResolver, udpResolver
+			}
+			res := TransportComparison{Domain: domain, Server: server, Protocol: p.resolvers[key].Protocol()}
+			var done bool
+			if res.UDP, done = p.compareQuery(ctx, key, domain, udp, udpResolver); !done {
+				return
+			}
+			if res.TCP, done = p.compareQuery(ctx, key, domain, tcp, tcpResolver); !done {
+				return
+			}
+
+			if delta, ok := res.Delta(); ok {
+				logging.Debugf("[%s] %s:%s answered %s over TCP %s slower than over UDP",
+					res.Protocol, server.Address, server.Port, domain.Name, delta.Round(time.Microsecond))
+			} else {
+				logging.Debugf("[%s] %s:%s transport comparison for %s failed: udp: %v, tcp: %v",
+					res.Protocol, server.Address, server.Port, domain.Name, res.UDP.Err, res.TCP.Err)
+			}
+			for _, fn := range p.comparisonCallbacks {
+				fn(res)
+			}
+		}
+	}
+}
+
+// compareQuery asks r for a random name under domain, within the rate
+// limits of the target with the given key. It returns false if ctx was
+// cancelled.
+func (p *Prober) compareQuery(ctx context.Context, key string, domain config.Domain, server config.DNSServer, r resolver.Resolver) (resolver.QueryResult, bool) {
+	msg := queryMessage(domain, server, domain.QueryName(p.random.prefix(5)))
+	result, ok := p.exchange(ctx, key, server, r, msg)
+	if result.Err != errWatchdog {
+		resolver.ReleaseQuery(msg)
+	}
+	return result, ok
+}
+
+// exchange sends msg to r within the rate limits of the target with the
+// given key. It returns false if ctx was cancelled, or if the wall clock
+// jumped during the query, which leaves the rest of the check to the next
+// cycle.
+func (p *Prober) exchange(ctx context.Context, key string, server config.DNSServer, r resolver.Resolver, msg *dns.Msg) (resolver.QueryResult, bool) {
+	if err := p.wait(ctx, key); err != nil {
+		return resolver.QueryResult{}, false
+	}
+	start := time.Now()
+	result := p.query(ctx, server, r, msg)
+	if ctx.Err() != nil {
+		return resolver.QueryResult{}, false
+	}
+	if jump := clockJump(start, time.Now()); jumped(jump) {
+		p.metrics.ClockJump()
+		logging.Warnf("[%s] %s:%s - discarding check, wall clock jumped by %s",
+			r.Protocol(), server.Address, server.Port, jump.Round(time.Millisecond))
+		return result, false
+	}
+	return result, true
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"net"
+	"slices"
+)
+
+// UpstreamOther is the upstream of probes answered by an address beyond
+// the upstreams limit of their server
+const UpstreamOther = "other"
+
+// upstream returns the address that answered the final attempt of res: one
+// of the first addresses of the target seen, up to the server's upstreams
+// limit, or UpstreamOther. It returns an empty string if the server does
+// not track upstreams or the address is unknown.
+func (p *Prober) upstream(res Result) string {
+	limit := res.Server.Upstreams
+	if limit == 0 || len(res.Attempts) == 0 {
+		return ""
+	}
+	addr := res.Last().RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if addr == "" {
+		return ""
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.upstreams == nil {
+		p.upstreams = make(map[string][]string)
+	}
+	key := serverKey(res.Server)
+	seen := p.upstreams[key]
+	switch {
+	case slices.Contains(seen, addr):
+		return addr
+	case len(seen) < limit:
+		p.upstreams[key] = append(seen, addr)
+		return addr
+	}
+	return UpstreamOther
+}
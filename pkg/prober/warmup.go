@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package prober
+
+import (
+	"context"
+
+	"github.com/farrokhi/dnspulse_exporter/internal/logging"
+	"github.com/farrokhi/dnspulse_exporter/pkg/config"
+)
+
+// warmUp sends one query for the first enabled domain to every due server
+// with warmup, so that the probes of the cycle find warm caches and open
+// connections. The outcome is logged but not recorded.
+func (p *Prober) warmUp(ctx context.Context, due map[string]bool) {
+	var domain config.Domain
+	for _, d := range p.config.Domains {
+		if d.IsEnabled() {
+			domain = d
+			break
+		}
+	}
+	if domain.Name == "" {
+		return
+	}
+
+	for _, server := range p.config.DNSServers {
+		key := serverKey(server)
+		r, ok := p.resolvers[key]
+		if !ok || !server.Warmup || !due[key] || p.isDrained(key) {
+			continue
+		}
+		if p.Paused() {
+			return
+		}
+		result, done := p.compareQuery(ctx, key, domain, server, r)
+		if !done {
+			return
+		}
+		if result.Err != nil {
+			logging.Debugf("[%s] %s:%s warmup query failed: %v", r.Protocol(), server.Address, server.Port, result.Err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Do53Resolver implements traditional DNS over UDP or TCP (RFC 1035)
+type Do53Resolver struct {
+	address  string
+	port     string
+	useTCP   bool
+	timeouts Timeouts
+	client   *dns.Client
+	socket   socket
+	protocol string
+
+	// reuse keeps one UDP socket for all queries; mu serializes them
+	reuse bool
+	mu    sync.Mutex
+	conn  *dns.Conn
+}
+
+// NewDo53Resolver creates a resolver sending plain DNS queries over UDP,
+// or over TCP if useTCP is set. Port defaults to 53.
+func NewDo53Resolver(opts Options, useTCP bool) *Do53Resolver {
+	opts = opts.withDefaults(ProtocolDo53UDP)
+	protocol := ProtocolDo53UDP
+	net := "udp"
+	if useTCP {
+		protocol = ProtocolDo53TCP
+		net = "tcp"
+	}
+	timeouts := opts.Timeouts
+	sock := opts.socket()
+
+	client := &dns.Client{
+		Net:          net,
+		DialTimeout:  timeouts.connect(),
+		ReadTimeout:  timeouts.query(),
+		WriteTimeout: timeouts.query(),
+	}
+	if !sock.isDefault() {
+		client.Dialer = sock.dialer(net, timeouts.connect())
+	}
+
+	return &Do53Resolver{
+		address:  opts.Address,
+		port:     opts.Port,
+		useTCP:   useTCP,
+		timeouts: timeouts,
+		client:   client,
+		socket:   sock,
+		protocol: protocol,
+		reuse:    opts.ReuseSocket && !useTCP,
+	}
+}
+
+// Query performs a DNS query using Do53
+func (r *Do53Resolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange sends msg using Do53 and returns the response
+func (r *Do53Resolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	serverAddr := net.JoinHostPort(r.address, r.port)
+
+	ctx, cancel := r.timeouts.withTotal(ctx)
+	defer cancel()
+
+	if r.reuse {
+		return r.exchangeReused(ctx, msg, serverAddr)
+	}
+
+	start := time.Now()
+	result := r.socket.exchange(ctx, r.client, msg, serverAddr)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// exchangeReused sends msg over the shared UDP socket, opening it if
+// needed. Queries wait for each other, and the time spent waiting is not
+// part of the duration. Replies with another ID, such as late answers to
+// queries that timed out, are skipped. The socket is replaced after an
+// error other than a timeout.
+func (r *Do53Resolver) exchangeReused(ctx context.Context, msg *dns.Msg, serverAddr string) QueryResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := time.Now()
+	if r.conn == nil {
+		err := inNetns(r.socket.netns, func() (err error) {
+			r.conn, err = r.client.DialContext(ctx, serverAddr)
+			return err
+		})
+		if err != nil {
+			return QueryResult{Duration: time.Since(start), Err: err}
+		}
+		r.socket.opened()
+	}
+	resp, err := exchangeConn(ctx, r.client, msg, r.conn)
+	duration := time.Since(start)
+	if err != nil && !isTimeout(err) {
+		_ = r.conn.Close()
+		r.conn = nil
+		r.socket.closed()
+	}
+
+	return QueryResult{
+		Response:   resp,
+		Duration:   duration,
+		Err:        err,
+		RemoteAddr: serverAddr,
+	}
+}
+
+// isTimeout returns true if err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Healthcheck verifies the server answers a simple query
+func (r *Do53Resolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; a new socket is used per
+// query unless the UDP socket is reused
+func (r *Do53Resolver) Capabilities() Capabilities {
+	return Capabilities{ConnectionReuse: r.reuse}
+}
+
+// Protocol returns the protocol identifier
+func (r *Do53Resolver) Protocol() string {
+	return r.protocol
+}
+
+// Close releases the reused UDP socket, if any
+func (r *Do53Resolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	r.socket.closed()
+	return err
+}
+
+// OpenConns returns the number of sockets open, including the reused UDP
+// socket
+func (r *Do53Resolver) OpenConns() int {
+	return r.socket.openConns()
+}
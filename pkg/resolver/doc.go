@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+// Package resolver sends DNS queries over Do53 (UDP and TCP), DNS over TLS,
+// DNS over HTTPS (HTTP/2 and HTTP/3) and DNS over QUIC behind a common
+// Resolver interface.
+//
+// A resolver is created for a protocol with New, or with the constructor of
+// the protocol, and is safe for concurrent queries:
+//
+//	r, err := resolver.New(resolver.ProtocolDoQ, resolver.Options{
+//		Address:  "dns.adguard-dns.com",
+//		Timeouts: resolver.Timeouts{Total: 5 * time.Second, Connect: time.Second},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer r.Close()
+//
+//	result := r.Query(ctx, "example.com", dns.TypeA)
+//	if result.Err != nil {
+//		return result.Err
+//	}
+//	fmt.Println(result.Response.Answer, result.Duration)
+//
+// Query never returns a nil result; failures are reported in
+// QueryResult.Err together with the time spent.
+//
+// Callers of Exchange sending many queries can reuse messages with
+// AcquireQuery and ReleaseQuery.
+//
+// The noquic build tag leaves out quic-go and the protocols built on it,
+// DoH3 and DoQ; New then fails for them, and Unsupported tells why.
+package resolver
@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/http2"
+)
+
+// DoHResolver implements DNS over HTTPS (RFC 8484)
+type DoHResolver struct {
+	url            string
+	host           string // HTTP Host header (serverName for virtual hosting)
+	captureHeaders []string
+	header         http.Header // extra request headers
+	timeouts       Timeouts
+	httpClient     *http.Client
+	transport      *http2.Transport
+	socket         socket
+}
+
+// NewDoHResolver creates a DNS over HTTPS resolver using strict HTTP/2.
+// Queries are posted to https://address:port/dns-query with the server name
+// as Host header. Port defaults to 443.
+func NewDoHResolver(opts Options) *DoHResolver {
+	opts = opts.withDefaults(ProtocolDoH)
+	timeouts := opts.Timeouts
+	sock := opts.socket()
+	tlsConfig := opts.tlsConfig([]string{"h2"})
+
+	transport := &http2.Transport{
+		TLSClientConfig:    tlsConfig,
+		DisableCompression: false,
+		AllowHTTP:          false,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeouts.connect())
+			defer cancel()
+			conn, err := sock.dialContext(ctx, network, addr, 0)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	}
+
+	httpClient := &http.Client{
+		Transport: transport,
+		Timeout:   timeouts.Total,
+	}
+
+	url := "https://" + net.JoinHostPort(opts.Address, opts.Port) + "/dns-query"
+
+	return &DoHResolver{
+		url:            url,
+		host:           opts.ServerName,
+		captureHeaders: opts.CaptureHeaders,
+		header:         requestHeader(opts),
+		timeouts:       timeouts,
+		httpClient:     httpClient,
+		transport:      transport,
+		socket:         sock,
+	}
+}
+
+// Query performs a DNS query using DoH (RFC 8484 wire format over HTTP/2)
+func (r *DoHResolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange sends msg using DoH and returns the response
+func (r *DoHResolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	// RFC 8484 recommends ID 0 for cache friendliness. Packing only reads
+	// the message, so a shallow copy suffices.
+	query := *msg
+	query.Id = 0
+
+	wireMsg, err := query.Pack()
+	if err != nil {
+		return QueryResult{Err: fmt.Errorf("failed to pack DNS message: %w", err)}
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeouts.query())
+	defer cancel()
+	var remote string
+	var reused bool
+	ctx = withRemoteAddr(ctx, &remote)
+	ctx = withReused(ctx, &reused)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(wireMsg))
+	if err != nil {
+		return QueryResult{Err: fmt.Errorf("failed to create HTTP request: %w", err)}
+	}
+
+	req.Host = r.host // Override Host header for virtual hosting
+	maps.Copy(req.Header, r.header)
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return QueryResult{
+			Duration: time.Since(start),
+			Err:      fmt.Errorf("HTTP/2 request failed: %w", err),
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	headers := captureHeaders(resp.Header, r.captureHeaders)
+	state := httpConnState(resp, reused)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("HTTP status %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+
+	body := acquireBody()
+	defer releaseBody(body)
+	_, err = body.ReadFrom(resp.Body)
+	duration := time.Since(start)
+	if err != nil {
+		return QueryResult{
+			Duration:   duration,
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to read response body: %w", err),
+		}
+	}
+
+	// Unpack copies what it needs, so the buffer can be reused
+	response := new(dns.Msg)
+	if err := response.Unpack(body.Bytes()); err != nil {
+		return QueryResult{
+			Duration:   duration,
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to unpack DNS response: %w", err),
+		}
+	}
+
+	return QueryResult{
+		Response:   response,
+		Duration:   duration,
+		RemoteAddr: remote,
+		Headers:    headers,
+		Conn:       state,
+		AltSvc:     resp.Header.Get("Alt-Svc"),
+	}
+}
+
+// HTTP3Port returns the port of the first HTTP/3 alternative in an Alt-Svc
+// header value (RFC 7838), e.g. "443" for `h3=":443"; ma=86400`. Draft
+// versions such as h3-29 are not HTTP/3.
+func HTTP3Port(altSvc string) (string, bool) {
+	for _, alt := range strings.Split(altSvc, ",") {
+		alt, _, _ = strings.Cut(alt, ";")
+		protocol, authority, ok := strings.Cut(strings.TrimSpace(alt), "=")
+		if !ok || protocol != "h3" {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(strings.Trim(authority, `"`)); err == nil {
+			return port, true
+		}
+	}
+	return "", false
+}
+
+// requestHeader builds the extra headers of DoH requests from the options
+func requestHeader(opts Options) http.Header {
+	header := make(http.Header, len(opts.Headers)+1)
+	for name, value := range opts.Headers {
+		header.Set(name, value)
+	}
+	if opts.UserAgent != "" {
+		header.Set("User-Agent", opts.UserAgent)
+	}
+	return header
+}
+
+// captureHeaders returns the values of the named response headers that are
+// present, keyed by canonical name
+func captureHeaders(header http.Header, names []string) map[string]string {
+	var captured map[string]string
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			if captured == nil {
+				captured = make(map[string]string, len(names))
+			}
+			captured[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	return captured
+}
+
+// withQueryTimeout returns a request context that is cancelled once timeout
+// has elapsed after the HTTP client obtained a connection, so that the wait
+// for the answer is bounded separately from connection setup
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if timer == nil {
+				timer = time.AfterFunc(timeout, cancel)
+			}
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), func() {
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+		cancel()
+	}
+}
+
+// withRemoteAddr returns a request context that stores the address of the
+// connection the request is sent on in addr, before the response arrives
+func withRemoteAddr(ctx context.Context, addr *string) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			*addr = info.Conn.RemoteAddr().String()
+		},
+	})
+}
+
+// withReused returns a request context that stores in reused whether the
+// request is sent on an existing connection
+func withReused(ctx context.Context, reused *bool) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			*reused = info.Reused
+		},
+	})
+}
+
+// httpConnState describes the connection of an HTTP response. A reused
+// connection reports the handshake it was set up with, so only new
+// connections count as resumed.
+func httpConnState(resp *http.Response, reused bool) *ConnState {
+	return &ConnState{Reused: reused, Resumed: !reused && resp.TLS != nil && resp.TLS.DidResume}
+}
+
+// Healthcheck verifies the server answers a simple query
+func (r *DoHResolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; HTTP/2 connections are reused
+// across queries
+func (r *DoHResolver) Capabilities() Capabilities {
+	return Capabilities{Encrypted: true, ConnectionReuse: true}
+}
+
+// Protocol returns the protocol identifier
+func (r *DoHResolver) Protocol() string {
+	return ProtocolDoH
+}
+
+// Close releases resources
+func (r *DoHResolver) Close() error {
+	r.httpClient.CloseIdleConnections()
+	r.transport.CloseIdleConnections()
+	return nil
+}
+
+// OpenConns returns the number of HTTP/2 connections open, idle or not
+func (r *DoHResolver) OpenConns() int {
+	return r.socket.openConns()
+}
@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !noquic
+
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// DoH3Resolver implements DNS over HTTPS using HTTP/3 (QUIC)
+type DoH3Resolver struct {
+	url            string
+	host           string // HTTP Host header (serverName for virtual hosting)
+	captureHeaders []string
+	header         http.Header // extra request headers
+	timeouts       Timeouts
+	httpClient     *http.Client
+	roundTripper   *http3.Transport
+	socket         socket
+}
+
+// NewDoH3Resolver creates a DNS over HTTPS resolver using HTTP/3. Port
+// defaults to 443.
+func NewDoH3Resolver(opts Options) *DoH3Resolver {
+	opts = opts.withDefaults(ProtocolDoH3)
+	timeouts := opts.Timeouts
+	tlsConfig := opts.tlsConfig(nil)
+
+	roundTripper := &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		QUICConfig: &quic.Config{
+			HandshakeIdleTimeout: timeouts.connect(),
+		},
+	}
+	sock := opts.socket()
+	roundTripper.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+		return sock.dialQUIC(ctx, addr, tlsCfg, cfg)
+	}
+
+	httpClient := &http.Client{
+		Transport: roundTripper,
+		Timeout:   timeouts.Total,
+	}
+
+	url := "https://" + net.JoinHostPort(opts.Address, opts.Port) + "/dns-query"
+
+	return &DoH3Resolver{
+		url:            url,
+		host:           opts.ServerName,
+		captureHeaders: opts.CaptureHeaders,
+		header:         requestHeader(opts),
+		timeouts:       timeouts,
+		httpClient:     httpClient,
+		roundTripper:   roundTripper,
+		socket:         sock,
+	}
+}
+
+// Query performs a DNS query using DoH3 (RFC 8484 over HTTP/3)
+func (r *DoH3Resolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange sends msg using DoH3 and returns the response
+func (r *DoH3Resolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	wireMsg, err := msg.Pack()
+	if err != nil {
+		return QueryResult{Err: fmt.Errorf("failed to pack DNS message: %w", err)}
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.timeouts.query())
+	defer cancel()
+	var remote string
+	var reused bool
+	ctx = withRemoteAddr(ctx, &remote)
+	ctx = withReused(ctx, &reused)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(wireMsg))
+	if err != nil {
+		return QueryResult{Err: fmt.Errorf("failed to create HTTP request: %w", err)}
+	}
+
+	req.Host = r.host // Override Host header for virtual hosting
+	maps.Copy(req.Header, r.header)
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return QueryResult{
+			Duration: time.Since(start),
+			Err:      fmt.Errorf("HTTP/3 request failed: %w", err),
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	headers := captureHeaders(resp.Header, r.captureHeaders)
+	state := httpConnState(resp, reused)
+
+	if resp.StatusCode != http.StatusOK {
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("HTTP status %d", resp.StatusCode),
+		}
+	}
+
+	body := acquireBody()
+	defer releaseBody(body)
+	_, err = body.ReadFrom(resp.Body)
+	duration := time.Since(start)
+	if err != nil {
+		return QueryResult{
+			Duration:   duration,
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to read response body: %w", err),
+		}
+	}
+
+	// Unpack copies what it needs, so the buffer can be reused
+	response := new(dns.Msg)
+	if err := response.Unpack(body.Bytes()); err != nil {
+		return QueryResult{
+			Duration:   duration,
+			RemoteAddr: remote,
+			Headers:    headers,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to unpack DNS response: %w", err),
+		}
+	}
+
+	return QueryResult{
+		Response:   response,
+		Duration:   duration,
+		RemoteAddr: remote,
+		Headers:    headers,
+		Conn:       state,
+	}
+}
+
+// Healthcheck verifies the server answers a simple query
+func (r *DoH3Resolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; QUIC connections are reused
+// across queries
+func (r *DoH3Resolver) Capabilities() Capabilities {
+	return Capabilities{Encrypted: true, ConnectionReuse: true}
+}
+
+// Protocol returns the protocol identifier
+func (r *DoH3Resolver) Protocol() string {
+	return ProtocolDoH3
+}
+
+// Close releases resources
+func (r *DoH3Resolver) Close() error {
+	r.httpClient.CloseIdleConnections()
+	return r.roundTripper.Close()
+}
+
+// OpenConns returns the number of QUIC connections open, idle or not
+func (r *DoH3Resolver) OpenConns() int {
+	return r.socket.openConns()
+}
@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !noquic
+
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqError returns a DoQError for stream resets and connection closes sent
+// by the server, and err unchanged otherwise
+func doqError(err error) error {
+	var streamErr *quic.StreamError
+	if errors.As(err, &streamErr) && streamErr.Remote {
+		return &DoQError{Code: uint64(streamErr.ErrorCode), StreamReset: true, err: err}
+	}
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.Remote {
+		return &DoQError{Code: uint64(appErr.ErrorCode), err: err}
+	}
+	return err
+}
+
+// DoQResolver implements DNS over QUIC (RFC 9250)
+type DoQResolver struct {
+	address   string
+	port      string
+	timeouts  Timeouts
+	tlsConfig *tls.Config
+	socket    socket
+}
+
+// NewDoQResolver creates a DNS over QUIC resolver. Port defaults to 853, and
+// the "doq" ALPN is offered unless others are given.
+func NewDoQResolver(opts Options) *DoQResolver {
+	opts = opts.withDefaults(ProtocolDoQ)
+	alpn := opts.ALPN
+	if len(alpn) == 0 {
+		alpn = []string{"doq"}
+	}
+	tlsConfig := opts.tlsConfig(alpn)
+
+	return &DoQResolver{
+		address:   opts.Address,
+		port:      opts.Port,
+		timeouts:  opts.Timeouts,
+		tlsConfig: tlsConfig,
+		socket:    opts.socket(),
+	}
+}
+
+// Query performs a DNS query using DoQ
+func (r *DoQResolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange sends msg using DoQ and returns the response
+func (r *DoQResolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	// The message is packed after room for the length prefix, so that
+	// both are written at once
+	buf := acquireWire()
+	defer releaseWire(buf)
+	wireMsg, err := msg.PackBuffer((*buf)[2:])
+	if err != nil {
+		return QueryResult{Err: fmt.Errorf("failed to pack DNS message: %w", err)}
+	}
+
+	serverAddr := net.JoinHostPort(r.address, r.port)
+
+	start := time.Now()
+
+	totalCtx, cancel := r.timeouts.withTotal(ctx)
+	defer cancel()
+
+	dialCtx, cancelDial := context.WithTimeout(totalCtx, r.timeouts.connect())
+	conn, err := r.socket.dialQUIC(dialCtx, serverAddr, r.tlsConfig, &quic.Config{
+		HandshakeIdleTimeout: r.timeouts.connect(),
+		MaxIdleTimeout:       r.timeouts.query(),
+	})
+	cancelDial()
+	if err != nil {
+		return QueryResult{
+			Duration: time.Since(start),
+			Err:      fmt.Errorf("QUIC dial failed: %w", doqError(err)),
+		}
+	}
+	defer func() {
+		_ = conn.CloseWithError(0, "")
+	}()
+	// Stream reads only observe deadlines, so closing the connection is what
+	// interrupts them when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { _ = conn.CloseWithError(0, "") })
+	defer stop()
+	remote := conn.RemoteAddr().String()
+	alpn := conn.ConnectionState().TLS.NegotiatedProtocol
+	state := &ConnState{Resumed: conn.ConnectionState().TLS.DidResume}
+
+	queryCtx, cancelQuery := context.WithTimeout(totalCtx, r.timeouts.query())
+	defer cancelQuery()
+
+	stream, err := conn.OpenStreamSync(queryCtx)
+	if err != nil {
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to open QUIC stream: %w", doqError(err)),
+		}
+	}
+	if deadline, ok := queryCtx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	// DoQ uses a 2-byte length prefix (RFC 9250). PackBuffer only
+	// allocates a new slice for a message too large for the buffer.
+	frame := (*buf)[:2+len(wireMsg)]
+	if len(wireMsg) > len(*buf)-2 {
+		frame = make([]byte, 2+len(wireMsg))
+		copy(frame[2:], wireMsg)
+	}
+	frame[0], frame[1] = byte(len(wireMsg)>>8), byte(len(wireMsg))
+	if _, err := stream.Write(frame); err != nil {
+		_ = stream.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to write DNS message: %w", doqError(err)),
+		}
+	}
+
+	// Gracefully close send side (sends FIN) per RFC 9250
+	if err := stream.Close(); err != nil {
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to close send side: %w", doqError(err)),
+		}
+	}
+
+	// Read response length prefix into the buffer, which is free again
+	respLengthBuf := (*buf)[:2]
+	if _, err := io.ReadFull(stream, respLengthBuf); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to read response length: %w", doqError(err)),
+		}
+	}
+	respLength := int(respLengthBuf[0])<<8 | int(respLengthBuf[1])
+
+	// Read the full response; Unpack copies what it needs
+	var respBuf []byte
+	if respLength <= len(*buf) {
+		respBuf = (*buf)[:respLength]
+	} else {
+		respBuf = make([]byte, respLength)
+	}
+	if _, err := io.ReadFull(stream, respBuf); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return QueryResult{
+			Duration:   time.Since(start),
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to read response: %w", doqError(err)),
+		}
+	}
+	duration := time.Since(start)
+
+	response := new(dns.Msg)
+	if err := response.Unpack(respBuf); err != nil {
+		return QueryResult{
+			Duration:   duration,
+			RemoteAddr: remote,
+			ALPN:       alpn,
+			Conn:       state,
+			Err:        fmt.Errorf("failed to unpack DNS response: %w", err),
+		}
+	}
+
+	return QueryResult{
+		Response:   response,
+		Duration:   duration,
+		RemoteAddr: remote,
+		ALPN:       alpn,
+		Conn:       state,
+	}
+}
+
+// Healthcheck verifies the server answers a simple query
+func (r *DoQResolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; a new QUIC connection is used
+// per query
+func (r *DoQResolver) Capabilities() Capabilities {
+	return Capabilities{Encrypted: true}
+}
+
+// Protocol returns the protocol identifier
+func (r *DoQResolver) Protocol() string {
+	return ProtocolDoQ
+}
+
+// Close releases resources
+func (r *DoQResolver) Close() error {
+	return nil
+}
+
+// OpenConns returns the number of QUIC connections open
+func (r *DoQResolver) OpenConns() int {
+	return r.socket.openConns()
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import "fmt"
+
+// DoQ error codes (RFC 9250, section 4.3)
+const (
+	DoQNoError          = 0x0
+	DoQInternalError    = 0x1
+	DoQProtocolError    = 0x2
+	DoQRequestCancelled = 0x3
+	DoQExcessiveLoad    = 0x4
+	DoQUnspecifiedError = 0x5
+	DoQErrorReserved    = 0xd098ea5e
+)
+
+// doqErrorNames maps DoQ error codes to their RFC 9250 names
+var doqErrorNames = map[uint64]string{
+	DoQNoError:          "DOQ_NO_ERROR",
+	DoQInternalError:    "DOQ_INTERNAL_ERROR",
+	DoQProtocolError:    "DOQ_PROTOCOL_ERROR",
+	DoQRequestCancelled: "DOQ_REQUEST_CANCELLED",
+	DoQExcessiveLoad:    "DOQ_EXCESSIVE_LOAD",
+	DoQUnspecifiedError: "DOQ_UNSPECIFIED_ERROR",
+	DoQErrorReserved:    "DOQ_ERROR_RESERVED",
+}
+
+// DoQErrorName returns the RFC 9250 name of a DoQ error code, or the code in
+// hexadecimal if it is not defined
+func DoQErrorName(code uint64) string {
+	if name, ok := doqErrorNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#x", code)
+}
+
+// DoQError is an error code a DoQ server signalled by resetting the query
+// stream or by closing the connection
+type DoQError struct {
+	Code uint64
+
+	// StreamReset is true if the server reset the stream, and false if it
+	// closed the connection
+	StreamReset bool
+
+	err error
+}
+
+// Error describes the code and how the server signalled it
+func (e *DoQError) Error() string {
+	if e.StreamReset {
+		return fmt.Sprintf("server reset the stream with %s", DoQErrorName(e.Code))
+	}
+	return fmt.Sprintf("server closed the connection with %s", DoQErrorName(e.Code))
+}
+
+// Unwrap returns the underlying QUIC error
+func (e *DoQError) Unwrap() error {
+	return e.err
+}
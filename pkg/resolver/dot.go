@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DoTResolver implements DNS over TLS (RFC 7858)
+type DoTResolver struct {
+	address   string
+	port      string
+	timeouts  Timeouts
+	client    *dns.Client
+	tlsConfig *tls.Config
+	socket    socket
+}
+
+// NewDoTResolver creates a DNS over TLS resolver. Port defaults to 853.
+func NewDoTResolver(opts Options) *DoTResolver {
+	opts = opts.withDefaults(ProtocolDoT)
+	timeouts := opts.Timeouts
+	tlsConfig := opts.tlsConfig(nil)
+
+	// The dial timeout also covers the TLS handshake
+	client := &dns.Client{
+		Net:          "tcp-tls",
+		DialTimeout:  timeouts.connect(),
+		ReadTimeout:  timeouts.query(),
+		WriteTimeout: timeouts.query(),
+		TLSConfig:    tlsConfig,
+	}
+	sock := opts.socket()
+	if !sock.isDefault() {
+		client.Dialer = sock.dialer("tcp", timeouts.connect())
+	}
+
+	return &DoTResolver{
+		address:   opts.Address,
+		port:      opts.Port,
+		timeouts:  timeouts,
+		client:    client,
+		tlsConfig: tlsConfig,
+		socket:    sock,
+	}
+}
+
+// Query performs a DNS query using DoT
+func (r *DoTResolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange sends msg using DoT and returns the response
+func (r *DoTResolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	serverAddr := net.JoinHostPort(r.address, r.port)
+
+	ctx, cancel := r.timeouts.withTotal(ctx)
+	defer cancel()
+
+	start := time.Now()
+	result := r.socket.exchange(ctx, r.client, msg, serverAddr)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// Healthcheck verifies the server answers a simple query
+func (r *DoTResolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; a new TLS connection is used
+// per query
+func (r *DoTResolver) Capabilities() Capabilities {
+	return Capabilities{Encrypted: true}
+}
+
+// Protocol returns the protocol identifier
+func (r *DoTResolver) Protocol() string {
+	return ProtocolDoT
+}
+
+// Close releases resources (no-op for DoT)
+func (r *DoTResolver) Close() error {
+	return nil
+}
+
+// OpenConns returns the number of TLS connections open
+func (r *DoTResolver) OpenConns() int {
+	return r.socket.openConns()
+}
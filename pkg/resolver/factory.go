@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Supported protocol identifiers
+const (
+	ProtocolDo53UDP = "do53-udp"
+	ProtocolDo53TCP = "do53-tcp"
+	ProtocolDoT     = "dot"
+	ProtocolDoH     = "doh"
+	ProtocolDoH3    = "doh3"
+	ProtocolDoQ     = "doq"
+)
+
+// Options configures a resolver
+type Options struct {
+	// Address is the server IP address or hostname
+	Address string
+
+	// Port is the server port; empty selects the protocol's standard port
+	Port string
+
+	// ServerName is the TLS server name for encrypted protocols and the
+	// HTTP Host for DoH; empty uses Address
+	ServerName string
+
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+
+	// TLSMinVersion and TLSMaxVersion bound the negotiated TLS version,
+	// e.g. tls.VersionTLS13; zero keeps the crypto/tls defaults. QUIC
+	// always uses TLS 1.3.
+	TLSMinVersion uint16
+	TLSMaxVersion uint16
+
+	// ResumeSessions caches TLS sessions so that new connections resume
+	// them instead of a full handshake
+	ResumeSessions bool
+
+	// ALPN lists the application protocols DoQ offers in order of
+	// preference, e.g. draft versions such as "doq-i02" for servers
+	// predating RFC 9250; empty offers "doq"
+	ALPN []string
+
+	// Headers are added to DoH and DoH3 requests, e.g. Authorization for
+	// private endpoints
+	Headers map[string]string
+
+	// UserAgent replaces the default User-Agent of DoH and DoH3 requests
+	UserAgent string
+
+	// CaptureHeaders names the HTTP response headers of DoH and DoH3
+	// queries that are returned in QueryResult.Headers
+	CaptureHeaders []string
+
+	// Timeouts bounds each query
+	Timeouts Timeouts
+
+	// Netns is the network namespace sockets are created in, a name
+	// created by ip-netns(8) or a namespace file path; Linux only
+	Netns string
+
+	// VRF is the VRF device sockets are bound to; Linux only
+	VRF string
+
+	// SourceAddress is the local IP address queries are sent from; empty
+	// lets the system choose
+	SourceAddress string
+
+	// TCP tunes the TCP connections of Do53 over TCP, DoT and DoH
+	TCP TCPOptions
+
+	// ReuseSocket sends all Do53 UDP queries from one socket, and so from
+	// one source port, instead of a new socket per query
+	ReuseSocket bool
+
+	// Shuffle puts the name servers an iterative resolver tries in random
+	// order, like rand.Shuffle; nil uses math/rand
+	Shuffle func(n int, swap func(i, j int))
+}
+
+// TCPOptions sets socket options of TCP connections. Zero values keep the
+// system defaults.
+type TCPOptions struct {
+	// FastOpen sends the query in the SYN with TCP Fast Open; Linux only
+	FastOpen bool
+
+	// NoDelay sets TCP_NODELAY, which Go enables by default
+	NoDelay *bool
+
+	// KeepAlive is the idle time before keepalive probes are sent
+	KeepAlive time.Duration
+
+	// KeepAliveInterval is the time between keepalive probes
+	KeepAliveInterval time.Duration
+}
+
+// socket returns where the resolver's sockets are created
+func (o Options) socket() socket {
+	return socket{netns: o.Netns, vrf: o.VRF, source: net.ParseIP(o.SourceAddress), tcp: o.TCP, open: new(atomic.Int64)}
+}
+
+// tlsConfig builds the TLS configuration of an encrypted protocol offering
+// the given application protocols
+func (o Options) tlsConfig(nextProtos []string) *tls.Config {
+	config := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MinVersion:         o.TLSMinVersion,
+		MaxVersion:         o.TLSMaxVersion,
+		NextProtos:         nextProtos,
+	}
+	if o.ResumeSessions {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	return config
+}
+
+// withDefaults fills in the port and server name left empty
+func (o Options) withDefaults(protocol string) Options {
+	if o.Port == "" {
+		o.Port = DefaultPort(protocol)
+	}
+	if o.ServerName == "" {
+		o.ServerName = o.Address
+	}
+	return o
+}
+
+// Constructor creates a resolver for a registered protocol
+type Constructor func(opts Options) (Resolver, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Constructor{
+		ProtocolDo53UDP: func(opts Options) (Resolver, error) { return NewDo53Resolver(opts, false), nil },
+		ProtocolDo53TCP: func(opts Options) (Resolver, error) { return NewDo53Resolver(opts, true), nil },
+		ProtocolDoT:     func(opts Options) (Resolver, error) { return NewDoTResolver(opts), nil },
+		ProtocolDoH:     func(opts Options) (Resolver, error) { return NewDoHResolver(opts), nil },
+	}
+)
+
+// Register makes a protocol available to New under the given identifier,
+// typically from the init function of the package implementing it.
+// Register panics if the identifier is empty, already registered or fn is
+// nil.
+func Register(protocol string, fn Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if protocol == "" || fn == nil {
+		panic("resolver: Register called with empty protocol or nil constructor")
+	}
+	if _, exists := registry[protocol]; exists {
+		panic("resolver: Register called twice for protocol " + protocol)
+	}
+	registry[protocol] = fn
+}
+
+// Registered returns true if a constructor exists for the protocol
+func Registered(protocol string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[protocol]
+	return ok
+}
+
+// Protocols returns the sorted identifiers of all registered protocols
+func Protocols() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	protocols := make([]string, 0, len(registry))
+	for protocol := range registry {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// Features returns the sorted names of the optional capabilities compiled
+// into this build, such as the socket options of the platform
+func Features() []string {
+	features := slices.Concat(platformFeatures, quicFeatures)
+	sort.Strings(features)
+	return features
+}
+
+// Unsupported returns why a built-in protocol is not available in this
+// build, such as the QUIC-based protocols in builds with the noquic tag.
+// It returns false for available and unknown protocols.
+func Unsupported(protocol string) (string, bool) {
+	reason, ok := unsupported[protocol]
+	return reason, ok && !Registered(protocol)
+}
+
+// New creates a resolver for the given protocol identifier
+func New(protocol string, opts Options) (Resolver, error) {
+	registryMu.RLock()
+	fn, ok := registry[protocol]
+	registryMu.RUnlock()
+	if !ok {
+		if reason, ok := Unsupported(protocol); ok {
+			return nil, fmt.Errorf("unsupported protocol: %s: %s", protocol, reason)
+		}
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+	return fn(opts)
+}
+
+// DefaultPort returns the standard port for a built-in protocol, or 53 for
+// any other protocol
+func DefaultPort(protocol string) string {
+	switch protocol {
+	case ProtocolDoT, ProtocolDoQ:
+		return "853"
+	case ProtocolDoH, ProtocolDoH3:
+		return "443"
+	default:
+		return "53"
+	}
+}
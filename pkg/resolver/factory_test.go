@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestNew(t *testing.T) {
+	timeouts := Timeouts{Total: 2 * time.Second}
+
+	tests := []struct {
+		name          string
+		protocol      string
+		opts          Options
+		expectedProto string
+		expectError   bool
+	}{
+		{
+			name:          "do53-udp",
+			protocol:      ProtocolDo53UDP,
+			opts:          Options{Address: "8.8.8.8", Port: "53"},
+			expectedProto: "do53-udp",
+		},
+		{
+			name:          "do53-tcp",
+			protocol:      ProtocolDo53TCP,
+			opts:          Options{Address: "8.8.8.8", Port: "53"},
+			expectedProto: "do53-tcp",
+		},
+		{
+			name:          "dot",
+			protocol:      ProtocolDoT,
+			opts:          Options{Address: "1.1.1.1", Port: "853", ServerName: "cloudflare-dns.com"},
+			expectedProto: "dot",
+		},
+		{
+			name:          "doh",
+			protocol:      ProtocolDoH,
+			opts:          Options{Address: "dns.google", Port: "443", ServerName: "dns.google"},
+			expectedProto: "doh",
+		},
+		{
+			name:          "doh3",
+			protocol:      ProtocolDoH3,
+			opts:          Options{Address: "dns.google", Port: "443", ServerName: "dns.google"},
+			expectedProto: "doh3",
+		},
+		{
+			name:          "doq",
+			protocol:      ProtocolDoQ,
+			opts:          Options{Address: "dns.adguard-dns.com", Port: "853", ServerName: "dns.adguard-dns.com"},
+			expectedProto: "doq",
+		},
+		{
+			name:        "unsupported protocol",
+			protocol:    "unknown",
+			opts:        Options{Address: "8.8.8.8", Port: "53"},
+			expectError: true,
+		},
+		{
+			name:        "empty protocol rejected",
+			protocol:    "",
+			opts:        Options{Address: "8.8.8.8", Port: "53"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason, ok := Unsupported(tt.protocol); ok {
+				t.Skip(reason)
+			}
+			tt.opts.Timeouts = timeouts
+			r, err := New(tt.protocol, tt.opts)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			if r.Protocol() != tt.expectedProto {
+				t.Errorf("Expected protocol '%s', got '%s'", tt.expectedProto, r.Protocol())
+			}
+
+			_ = r.Close()
+		})
+	}
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	tests := []struct {
+		protocol   string
+		port       string
+		serverName string
+	}{
+		{ProtocolDo53UDP, "53", "dns.google"},
+		{ProtocolDoT, "853", "dns.google"},
+		{ProtocolDoH, "443", "dns.google"},
+		{ProtocolDoQ, "853", "dns.google"},
+	}
+
+	for _, tt := range tests {
+		opts := Options{Address: "dns.google"}.withDefaults(tt.protocol)
+		if opts.Port != tt.port {
+			t.Errorf("%s: expected port '%s', got '%s'", tt.protocol, tt.port, opts.Port)
+		}
+		if opts.ServerName != tt.serverName {
+			t.Errorf("%s: expected server name '%s', got '%s'", tt.protocol, tt.serverName, opts.ServerName)
+		}
+	}
+
+	opts := Options{Address: "9.9.9.9", Port: "5353", ServerName: "dns.quad9.net"}.withDefaults(ProtocolDoT)
+	if opts.Port != "5353" || opts.ServerName != "dns.quad9.net" {
+		t.Errorf("Expected explicit port and server name to be kept, got %+v", opts)
+	}
+}
+
+// stubResolver is a minimal resolver for registry tests
+type stubResolver struct {
+	opts Options
+}
+
+func (r *stubResolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	return QueryResult{}
+}
+
+func (r *stubResolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	return QueryResult{}
+}
+
+func (r *stubResolver) Protocol() string { return "stub" }
+
+func (r *stubResolver) Healthcheck(ctx context.Context) error { return nil }
+
+func (r *stubResolver) Capabilities() Capabilities { return Capabilities{} }
+
+func (r *stubResolver) Close() error { return nil }
+
+func TestRegister(t *testing.T) {
+	Register("stub", func(opts Options) (Resolver, error) {
+		return &stubResolver{opts: opts}, nil
+	})
+
+	if !Registered("stub") {
+		t.Fatal("Expected stub protocol to be registered")
+	}
+	if !slices.Contains(Protocols(), "stub") || !slices.Contains(Protocols(), ProtocolDoH) {
+		t.Errorf("Expected stub and built-in protocols, got %v", Protocols())
+	}
+
+	r, err := New("stub", Options{Address: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if stub, ok := r.(*stubResolver); !ok || stub.opts.Address != "192.0.2.1" {
+		t.Errorf("Expected stub resolver with options, got %#v", r)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected duplicate registration to panic")
+		}
+	}()
+	Register(ProtocolDoH, func(opts Options) (Resolver, error) { return nil, nil })
+}
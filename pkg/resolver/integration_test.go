@@ -1,7 +1,7 @@
 // SPDX-License-Identifier: BSD-2-Clause
 // Copyright (c) 2026 Babak Farrokhi
 
-//go:build integration
+//go:build integration && !noquic
 
 package resolver
 
@@ -14,17 +14,18 @@ import (
 )
 
 // Integration tests against Quad9 (9.9.9.9) which supports all protocols.
-// Run with: go test -tags=integration -v ./internal/resolver/
+// Run with: go test -tags=integration -v ./pkg/resolver/
 
 const (
 	quad9IP         = "9.9.9.9"
 	quad9ServerName = "dns.quad9.net"
 	testDomain      = "example.com"
-	testTimeout     = 10 * time.Second
 )
 
+var testTimeout = Timeouts{Total: 10 * time.Second}
+
 func TestIntegrationDo53UDP(t *testing.T) {
-	r := NewDo53Resolver(quad9IP, "53", false, testTimeout)
+	r := NewDo53Resolver(Options{Address: quad9IP, Port: "53", Timeouts: testTimeout}, false)
 	defer r.Close()
 
 	ctx := context.Background()
@@ -46,7 +47,7 @@ func TestIntegrationDo53UDP(t *testing.T) {
 }
 
 func TestIntegrationDo53TCP(t *testing.T) {
-	r := NewDo53Resolver(quad9IP, "53", true, testTimeout)
+	r := NewDo53Resolver(Options{Address: quad9IP, Port: "53", Timeouts: testTimeout}, true)
 	defer r.Close()
 
 	ctx := context.Background()
@@ -68,7 +69,7 @@ func TestIntegrationDo53TCP(t *testing.T) {
 }
 
 func TestIntegrationDoT(t *testing.T) {
-	r := NewDoTResolver(quad9IP, "853", quad9ServerName, false, testTimeout)
+	r := NewDoTResolver(Options{Address: quad9IP, Port: "853", ServerName: quad9ServerName, Timeouts: testTimeout})
 	defer r.Close()
 
 	ctx := context.Background()
@@ -90,7 +91,7 @@ func TestIntegrationDoT(t *testing.T) {
 }
 
 func TestIntegrationDoH(t *testing.T) {
-	r := NewDoHResolver(quad9ServerName, "443", quad9ServerName, false, testTimeout)
+	r := NewDoHResolver(Options{Address: quad9ServerName, Port: "443", ServerName: quad9ServerName, Timeouts: testTimeout})
 	defer r.Close()
 
 	ctx := context.Background()
@@ -112,7 +113,7 @@ func TestIntegrationDoH(t *testing.T) {
 }
 
 func TestIntegrationDoH3(t *testing.T) {
-	r := NewDoH3Resolver(quad9ServerName, "443", quad9ServerName, false, testTimeout)
+	r := NewDoH3Resolver(Options{Address: quad9ServerName, Port: "443", ServerName: quad9ServerName, Timeouts: testTimeout})
 	defer r.Close()
 
 	ctx := context.Background()
@@ -134,7 +135,7 @@ func TestIntegrationDoH3(t *testing.T) {
 }
 
 func TestIntegrationDoQ(t *testing.T) {
-	r := NewDoQResolver(quad9ServerName, "853", quad9ServerName, false, testTimeout)
+	r := NewDoQResolver(Options{Address: quad9ServerName, Port: "853", ServerName: quad9ServerName, Timeouts: testTimeout})
 	defer r.Close()
 
 	ctx := context.Background()
@@ -160,12 +161,12 @@ func TestIntegrationAllProtocols(t *testing.T) {
 		name     string
 		resolver Resolver
 	}{
-		{"Do53-UDP", NewDo53Resolver(quad9IP, "53", false, testTimeout)},
-		{"Do53-TCP", NewDo53Resolver(quad9IP, "53", true, testTimeout)},
-		{"DoT", NewDoTResolver(quad9IP, "853", quad9ServerName, false, testTimeout)},
-		{"DoH", NewDoHResolver(quad9ServerName, "443", quad9ServerName, false, testTimeout)},
-		{"DoH3", NewDoH3Resolver(quad9ServerName, "443", quad9ServerName, false, testTimeout)},
-		{"DoQ", NewDoQResolver(quad9ServerName, "853", quad9ServerName, false, testTimeout)},
+		{"Do53-UDP", NewDo53Resolver(Options{Address: quad9IP, Port: "53", Timeouts: testTimeout}, false)},
+		{"Do53-TCP", NewDo53Resolver(Options{Address: quad9IP, Port: "53", Timeouts: testTimeout}, true)},
+		{"DoT", NewDoTResolver(Options{Address: quad9IP, Port: "853", ServerName: quad9ServerName, Timeouts: testTimeout})},
+		{"DoH", NewDoHResolver(Options{Address: quad9ServerName, Port: "443", ServerName: quad9ServerName, Timeouts: testTimeout})},
+		{"DoH3", NewDoH3Resolver(Options{Address: quad9ServerName, Port: "443", ServerName: quad9ServerName, Timeouts: testTimeout})},
+		{"DoQ", NewDoQResolver(Options{Address: quad9ServerName, Port: "853", ServerName: quad9ServerName, Timeouts: testTimeout})},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,333 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RootHints is the address that makes an iterative resolver start at the
+// root servers
+const RootHints = "."
+
+// maxReferrals bounds the delegation chain followed for one query
+const maxReferrals = 16
+
+// maxGluelessDepth bounds the nested lookups of name server addresses that
+// a referral did not include as glue
+const maxGluelessDepth = 3
+
+// IterativeResolver resolves names itself by following referrals from the
+// root servers (or a configured hint server) down to the authoritative
+// servers, using non-recursive Do53 queries (RD=0). It measures the
+// authoritative path independent of any recursive resolver.
+type IterativeResolver struct {
+	hints    []string
+	zone     string // zone served by the hints, empty if unknown
+	port     string
+	timeouts Timeouts
+	client   *dns.Client
+	tcp      *dns.Client // fallback for truncated UDP answers
+	socket   socket
+	protocol string
+	shuffle  func(n int, swap func(i, j int))
+}
+
+// NewIterativeResolver creates an iterative resolver that starts at the
+// server given by Address, or at the root servers if Address is RootHints
+// or empty. Port applies to every server contacted and defaults to 53.
+// Queries use UDP with a TCP fallback for truncated answers, or only TCP if
+// useTCP is set.
+func NewIterativeResolver(opts Options, useTCP bool) *IterativeResolver {
+	opts = opts.withDefaults(ProtocolDo53UDP)
+	timeouts := opts.Timeouts
+
+	var hints []string
+	zone := ""
+	if opts.Address == "" || opts.Address == RootHints {
+		for _, root := range RootServers {
+			hints = append(hints, net.JoinHostPort(root.IPv4, opts.Port))
+		}
+		zone = "."
+	} else {
+		hints = []string{net.JoinHostPort(opts.Address, opts.Port)}
+	}
+
+	sock := opts.socket()
+	newClient := func(network string) *dns.Client {
+		client := &dns.Client{
+			Net:          network,
+			DialTimeout:  timeouts.connect(),
+			ReadTimeout:  timeouts.query(),
+			WriteTimeout: timeouts.query(),
+		}
+		if !sock.isDefault() {
+			client.Dialer = sock.dialer(network, timeouts.connect())
+		}
+		return client
+	}
+
+	r := &IterativeResolver{
+		hints:    hints,
+		zone:     zone,
+		port:     opts.Port,
+		timeouts: timeouts,
+		socket:   sock,
+		protocol: ProtocolDo53UDP,
+		shuffle:  opts.Shuffle,
+	}
+	if r.shuffle == nil {
+		r.shuffle = rand.Shuffle
+	}
+	if useTCP {
+		r.client = newClient("tcp")
+		r.protocol = ProtocolDo53TCP
+	} else {
+		r.client = newClient("udp")
+		r.tcp = newClient("tcp")
+	}
+	return r
+}
+
+// Query resolves hostname iteratively
+func (r *IterativeResolver) Query(ctx context.Context, hostname string, qtype uint16) QueryResult {
+	msg := AcquireQuery(hostname, qtype)
+	defer ReleaseQuery(msg)
+	return r.Exchange(ctx, msg)
+}
+
+// Exchange resolves the question of msg iteratively and returns the
+// authoritative response along with the timing of every step
+func (r *IterativeResolver) Exchange(ctx context.Context, msg *dns.Msg) QueryResult {
+	ctx, cancel := r.timeouts.withTotal(ctx)
+	defer cancel()
+
+	start := time.Now()
+	resp, steps, err := r.iterate(ctx, msg, 0)
+	result := QueryResult{
+		Response: resp,
+		Duration: time.Since(start),
+		Err:      err,
+		Steps:    steps,
+	}
+	if len(steps) > 0 {
+		result.RemoteAddr = steps[len(steps)-1].Server
+	}
+	return result
+}
+
+// iterate follows referrals for the question of msg starting at the hints.
+// The first step is attributed to the root zone when starting at the root
+// servers; the zone of a configured hint server is not known, so its step
+// has an empty zone.
+func (r *IterativeResolver) iterate(ctx context.Context, msg *dns.Msg, depth int) (*dns.Msg, []Step, error) {
+	if len(msg.Question) == 0 {
+		return nil, nil, errors.New("query has no question")
+	}
+	query := msg.Copy()
+	query.RecursionDesired = false
+	qname := dns.CanonicalName(query.Question[0].Name)
+
+	zone := r.zone
+	servers := r.hints
+
+	var steps []Step
+	for range maxReferrals {
+		resp, server, duration, err := r.ask(ctx, query, servers)
+		if err != nil {
+			return nil, steps, fmt.Errorf("querying %s name servers: %w", zoneName(zone), err)
+		}
+		steps = append(steps, Step{Zone: zone, Server: server, Duration: duration})
+
+		child, names := referral(resp, zone, qname)
+		if child == "" {
+			return resp, steps, nil
+		}
+		servers, err = r.addresses(ctx, resp, names, depth)
+		if err != nil {
+			return nil, steps, fmt.Errorf("resolving %s name servers: %w", child, err)
+		}
+		zone = child
+	}
+	return nil, steps, fmt.Errorf("more than %d referrals", maxReferrals)
+}
+
+// ask sends query to the servers in random order until one of them answers
+// without an error. The returned duration includes failing over.
+func (r *IterativeResolver) ask(ctx context.Context, query *dns.Msg, servers []string) (*dns.Msg, string, time.Duration, error) {
+	servers = slices.Clone(servers)
+	r.shuffle(len(servers), func(i, j int) { servers[i], servers[j] = servers[j], servers[i] })
+
+	start := time.Now()
+	err := errors.New("no name server addresses")
+	for _, server := range servers {
+		if ctx.Err() != nil {
+			break
+		}
+		result := r.socket.exchange(ctx, r.client, query, server)
+		if result.Err == nil && result.Response.Truncated && r.tcp != nil {
+			result = r.socket.exchange(ctx, r.tcp, query, server)
+		}
+		if err = result.Err; err != nil {
+			continue
+		}
+		resp := result.Response
+		if resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused {
+			// Lame or broken server, try the next one
+			err = fmt.Errorf("%s answered %s", server, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		return resp, server, time.Since(start), nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return nil, "", time.Since(start), err
+}
+
+// addresses returns the addresses of the named name servers, taken from the
+// glue records of resp or else looked up iteratively. Only IPv4 addresses
+// are used.
+func (r *IterativeResolver) addresses(ctx context.Context, resp *dns.Msg, names []string, depth int) ([]string, error) {
+	var addrs []string
+	for _, rr := range resp.Extra {
+		if a, ok := rr.(*dns.A); ok && slices.Contains(names, dns.CanonicalName(a.Hdr.Name)) {
+			addrs = append(addrs, net.JoinHostPort(a.A.String(), r.port))
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+
+	if depth >= maxGluelessDepth {
+		return nil, errors.New("no glue and too many nested lookups")
+	}
+	err := fmt.Errorf("no address for %s", strings.Join(names, ", "))
+	for _, name := range names {
+		var answer *dns.Msg
+		answer, _, err = r.iterate(ctx, NewQuery(name, dns.TypeA), depth+1)
+		if err != nil {
+			continue
+		}
+		for _, rr := range answer.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				addrs = append(addrs, net.JoinHostPort(a.A.String(), r.port))
+			}
+		}
+		if len(addrs) > 0 {
+			return addrs, nil
+		}
+	}
+	return nil, err
+}
+
+// referral returns the delegated zone and the names of its name servers if
+// resp refers qname to a zone below zone, or an empty zone if resp is a
+// final answer
+func referral(resp *dns.Msg, zone, qname string) (string, []string) {
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) > 0 {
+		return "", nil
+	}
+
+	child := ""
+	var names []string
+	for _, rr := range resp.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := dns.CanonicalName(ns.Hdr.Name)
+		if !dns.IsSubDomain(owner, qname) || owner == zone {
+			continue
+		}
+		if zone != "" && !dns.IsSubDomain(zone, owner) {
+			continue
+		}
+		if child != "" && owner != child {
+			continue
+		}
+		child = owner
+		names = append(names, dns.CanonicalName(ns.Ns))
+	}
+	return child, names
+}
+
+// NameServer is an authoritative name server of a zone, with its fully
+// qualified name
+type NameServer struct {
+	Name    string
+	Address string
+}
+
+// LookupNameServers resolves the NS set of zone iteratively from the root
+// servers. Only name servers with an IPv4 address in the response are
+// returned, in the order of the NS records.
+func LookupNameServers(ctx context.Context, zone string, timeouts Timeouts) ([]NameServer, error) {
+	result := NewIterativeResolver(Options{Timeouts: timeouts}, false).Query(ctx, zone, dns.TypeNS)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if result.Response.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("lookup answered %s", dns.RcodeToString[result.Response.Rcode])
+	}
+
+	addrs := make(map[string]string)
+	for _, rr := range result.Response.Extra {
+		if a, ok := rr.(*dns.A); ok {
+			addrs[dns.CanonicalName(a.Hdr.Name)] = a.A.String()
+		}
+	}
+	var servers []NameServer
+	for _, rr := range result.Response.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		name := dns.CanonicalName(ns.Ns)
+		if addr, ok := addrs[name]; ok {
+			servers = append(servers, NameServer{Name: name, Address: addr})
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no name servers with addresses for %s", dns.Fqdn(zone))
+	}
+	return servers, nil
+}
+
+// zoneName formats a zone for error messages
+func zoneName(zone string) string {
+	if zone == "" {
+		return "hint"
+	}
+	return zone
+}
+
+// Healthcheck verifies the hint servers answer the root NS query
+func (r *IterativeResolver) Healthcheck(ctx context.Context) error {
+	return healthcheck(ctx, r)
+}
+
+// Capabilities returns the transport features; a new socket is used per query
+func (r *IterativeResolver) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// Protocol returns the protocol identifier of the transport
+func (r *IterativeResolver) Protocol() string {
+	return r.protocol
+}
+
+// Close releases resources (no-op for iterative resolution)
+func (r *IterativeResolver) Close() error {
+	return nil
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startServer serves handler over UDP on addr until the test ends
+func startServer(t *testing.T, addr string, handler dns.HandlerFunc) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Skipf("Cannot listen on %s: %v", addr, err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: handler}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestIterativeResolver(t *testing.T) {
+	// The "root" on 127.0.0.1 delegates example. to 127.0.0.2, which answers
+	// authoritatively. Both listen on the same port, as the resolver uses
+	// one port for every server.
+	root := startServer(t, "127.0.0.1:0", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if req.RecursionDesired {
+			resp.Rcode = dns.RcodeRefused
+		}
+		resp.Ns = []dns.RR{&dns.NS{
+			Hdr: dns.RR_Header{Name: "example.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 300},
+			Ns:  "ns.example.",
+		}}
+		resp.Extra = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: "ns.example.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("127.0.0.2"),
+		}}
+		w.WriteMsg(resp)
+	})
+	_, port, _ := net.SplitHostPort(root)
+	startServer(t, net.JoinHostPort("127.0.0.2", port), func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Authoritative = true
+		resp.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("192.0.2.1"),
+		}}
+		w.WriteMsg(resp)
+	})
+
+	r := NewIterativeResolver(Options{Address: "127.0.0.1", Port: port, Timeouts: Timeouts{Total: 2 * time.Second}}, false)
+	result := r.Query(context.Background(), "www.example", dns.TypeA)
+	if result.Err != nil {
+		t.Fatalf("Query failed: %v", result.Err)
+	}
+	if !result.Response.Authoritative || len(result.Response.Answer) != 1 {
+		t.Errorf("Expected an authoritative answer, got %v", result.Response)
+	}
+	if len(result.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(result.Steps))
+	}
+	if result.Steps[0].Zone != "" || result.Steps[1].Zone != "example." {
+		t.Errorf("Expected steps for the hint and example., got %q and %q", result.Steps[0].Zone, result.Steps[1].Zone)
+	}
+	if result.Steps[1].Server != net.JoinHostPort("127.0.0.2", port) {
+		t.Errorf("Expected second step answered by 127.0.0.2, got %s", result.Steps[1].Server)
+	}
+}
+
+func TestReferral(t *testing.T) {
+	ns := func(owner, target string) dns.RR {
+		return &dns.NS{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeNS, Class: dns.ClassINET}, Ns: target}
+	}
+
+	tests := []struct {
+		name  string
+		zone  string
+		resp  *dns.Msg
+		child string
+		names int
+	}{
+		{"delegation", ".", &dns.Msg{Ns: []dns.RR{ns("com.", "a.gtld-servers.net."), ns("com.", "b.gtld-servers.net.")}}, "com.", 2},
+		{"answer", "com.", &dns.Msg{Answer: []dns.RR{ns("example.com.", "ns.example.com.")}}, "", 0},
+		{"upward referral", "com.", &dns.Msg{Ns: []dns.RR{ns(".", "a.root-servers.net.")}}, "", 0},
+		{"unrelated zone", ".", &dns.Msg{Ns: []dns.RR{ns("org.", "a0.org.afilias-nst.info.")}}, "", 0},
+		{"unknown hint zone", "", &dns.Msg{Ns: []dns.RR{ns("example.com.", "ns.example.com.")}}, "example.com.", 1},
+		{"nxdomain", ".", &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child, names := referral(tt.resp, tt.zone, "www.example.com.")
+			if child != tt.child || len(names) != tt.names {
+				t.Errorf("referral() = %q with %d names, want %q with %d", child, len(names), tt.child, tt.names)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build noquic
+
+package resolver
+
+// quicFeatures is empty, as the build has no QUIC support
+var quicFeatures []string
+
+// unsupported holds why the QUIC-based protocols are not registered
+var unsupported = map[string]string{
+	ProtocolDoH3: "this build was compiled without QUIC support (noquic build tag)",
+	ProtocolDoQ:  "this build was compiled without QUIC support (noquic build tag)",
+}
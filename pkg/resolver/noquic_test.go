@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build noquic
+
+package resolver
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNoQUIC(t *testing.T) {
+	if slices.Contains(Features(), "quic") {
+		t.Errorf("Expected no quic among the features, got %v", Features())
+	}
+	for _, protocol := range []string{ProtocolDoH3, ProtocolDoQ} {
+		if Registered(protocol) || slices.Contains(Protocols(), protocol) {
+			t.Errorf("Expected %s not to be registered", protocol)
+		}
+		if _, ok := Unsupported(protocol); !ok {
+			t.Errorf("Expected %s to be reported as unsupported", protocol)
+		}
+		_, err := New(protocol, Options{Address: "192.0.2.1"})
+		if err == nil || !strings.Contains(err.Error(), "noquic") {
+			t.Errorf("Expected New(%s) to fail naming the build tag, got %v", protocol, err)
+		}
+	}
+	if _, ok := Unsupported(ProtocolDoH); ok {
+		t.Error("Expected doh to be supported")
+	}
+	if _, ok := Unsupported("carrier-pigeon"); ok {
+		t.Error("Expected unknown protocols not to be reported as unsupported")
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// wireBufferSize fits the queries sent and most responses; larger
+// responses get a buffer of their own
+const wireBufferSize = 4096
+
+// maxPooledBody is the largest HTTP body buffer returned to the pool
+const maxPooledBody = 16 * 1024
+
+var (
+	// queryPool holds query messages, keeping their question slice
+	queryPool = sync.Pool{New: func() any { return new(dns.Msg) }}
+
+	// wirePool holds buffers for packing queries and reading DoQ responses
+	wirePool = sync.Pool{New: func() any {
+		buf := make([]byte, wireBufferSize)
+		return &buf
+	}}
+
+	// bodyPool holds buffers for reading DoH response bodies
+	bodyPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+// AcquireQuery is like NewQuery but takes the message from a pool. Pass
+// the message to ReleaseQuery once nothing uses it anymore; a message that
+// is never released is collected as usual.
+func AcquireQuery(hostname string, qtype uint16) *dns.Msg {
+	msg := queryPool.Get().(*dns.Msg)
+	question := msg.Question[:0]
+	*msg = dns.Msg{}
+	msg.Id = dns.Id()
+	msg.RecursionDesired = true
+	msg.Question = append(question, dns.Question{Name: dns.Fqdn(hostname), Qtype: qtype, Qclass: dns.ClassINET})
+	return msg
+}
+
+// ReleaseQuery returns a message from AcquireQuery to the pool. The message
+// must not be used afterwards.
+func ReleaseQuery(msg *dns.Msg) {
+	queryPool.Put(msg)
+}
+
+// acquireWire returns a buffer of wireBufferSize bytes from the pool
+func acquireWire() *[]byte {
+	return wirePool.Get().(*[]byte)
+}
+
+// releaseWire returns a buffer from acquireWire to the pool
+func releaseWire(buf *[]byte) {
+	wirePool.Put(buf)
+}
+
+// acquireBody returns an empty buffer from the pool
+func acquireBody() *bytes.Buffer {
+	buf := bodyPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// releaseBody returns a buffer from acquireBody to the pool unless a large
+// response made it grow
+func releaseBody(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBody {
+		bodyPool.Put(buf)
+	}
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestAcquireQuery(t *testing.T) {
+	msg := AcquireQuery("first.example", dns.TypeAAAA)
+	msg.RecursionDesired = false
+	msg.SetEdns0(dns.DefaultMsgSize, true)
+	ReleaseQuery(msg)
+
+	// Whether or not the pool hands back the same message, it must look
+	// like a fresh query
+	msg = AcquireQuery("second.example", dns.TypeA)
+	defer ReleaseQuery(msg)
+	want := NewQuery("second.example", dns.TypeA)
+	want.Id = msg.Id
+	if msg.String() != want.String() {
+		t.Errorf("Expected a fresh query:\n%s\ngot:\n%s", want, msg)
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !noquic
+
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/quic-go/quic-go"
+)
+
+// quicFeatures names QUIC support among the features of the build
+var quicFeatures = []string{"quic"}
+
+// unsupported is empty, as every built-in protocol is compiled in
+var unsupported map[string]string
+
+func init() {
+	Register(ProtocolDoH3, func(opts Options) (Resolver, error) { return NewDoH3Resolver(opts), nil })
+	Register(ProtocolDoQ, func(opts Options) (Resolver, error) { return NewDoQResolver(opts), nil })
+}
+
+// dialQUIC establishes a QUIC connection to addr from the namespace and VRF.
+// The connection is counted as open until it is closed.
+func (s socket) dialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config, conf *quic.Config) (*quic.Conn, error) {
+	if s.isDefault() {
+		conn, err := quic.DialAddr(ctx, addr, tlsConfig, conf)
+		if err != nil {
+			return nil, err
+		}
+		s.opened()
+		go func() {
+			<-conn.Context().Done()
+			s.closed()
+		}()
+		return conn, nil
+	}
+
+	udpAddr, err := s.resolveUDP(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := s.listenPacket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.Dial(ctx, pc, udpAddr, tlsConfig, conf)
+	if err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+	s.opened()
+	// quic.Dial leaves the socket open when the connection closes
+	go func() {
+		<-conn.Context().Done()
+		_ = pc.Close()
+		s.closed()
+	}()
+	return conn, nil
+}
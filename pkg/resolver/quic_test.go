@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !noquic
+
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestDoH3ResolverProtocol(t *testing.T) {
+	r := NewDoH3Resolver(Options{Address: "dns.google", Port: "443", ServerName: "dns.google", Timeouts: Timeouts{Total: 2 * time.Second}})
+	if r.Protocol() != "doh3" {
+		t.Errorf("Expected 'doh3', got '%s'", r.Protocol())
+	}
+}
+
+func TestDoQResolverProtocol(t *testing.T) {
+	r := NewDoQResolver(Options{Address: "dns.adguard-dns.com", Port: "853", ServerName: "dns.adguard-dns.com", Timeouts: Timeouts{Total: 2 * time.Second}})
+	if r.Protocol() != "doq" {
+		t.Errorf("Expected 'doq', got '%s'", r.Protocol())
+	}
+}
+
+func TestQUICResolverClose(t *testing.T) {
+	resolvers := []Resolver{
+		NewDoH3Resolver(Options{Address: "dns.google", Port: "443", ServerName: "dns.google", Timeouts: Timeouts{Total: 2 * time.Second}}),
+		NewDoQResolver(Options{Address: "dns.adguard-dns.com", Port: "853", ServerName: "dns.adguard-dns.com", Timeouts: Timeouts{Total: 2 * time.Second}}),
+	}
+
+	for _, r := range resolvers {
+		if err := r.Close(); err != nil {
+			t.Errorf("Close() returned error for %s: %v", r.Protocol(), err)
+		}
+	}
+}
+
+func TestDoQError(t *testing.T) {
+	reset := doqError(fmt.Errorf("read: %w", &quic.StreamError{ErrorCode: DoQProtocolError, Remote: true}))
+	var doqErr *DoQError
+	if !errors.As(reset, &doqErr) || !doqErr.StreamReset || doqErr.Code != DoQProtocolError {
+		t.Fatalf("Expected a stream reset with DOQ_PROTOCOL_ERROR, got %v", reset)
+	}
+	if reset.Error() != "server reset the stream with DOQ_PROTOCOL_ERROR" {
+		t.Errorf("Unexpected message: %s", reset)
+	}
+
+	closed := doqError(&quic.ApplicationError{ErrorCode: DoQExcessiveLoad, Remote: true})
+	if !errors.As(closed, &doqErr) || doqErr.StreamReset || doqErr.Code != DoQExcessiveLoad {
+		t.Fatalf("Expected a connection close with DOQ_EXCESSIVE_LOAD, got %v", closed)
+	}
+
+	// Our own closes and other errors are left alone
+	local := &quic.ApplicationError{ErrorCode: DoQNoError}
+	if err := doqError(local); err != error(local) {
+		t.Errorf("Expected local close to be unchanged, got %v", err)
+	}
+	if name := DoQErrorName(0x42); name != "0x42" {
+		t.Errorf("Expected unknown code as hex, got %s", name)
+	}
+}
+
+func TestQUICSupport(t *testing.T) {
+	if !slices.Contains(Features(), "quic") {
+		t.Errorf("Expected quic among the features, got %v", Features())
+	}
+	for _, protocol := range []string{ProtocolDoH3, ProtocolDoQ} {
+		if reason, ok := Unsupported(protocol); ok || !Registered(protocol) {
+			t.Errorf("Expected %s to be supported, got %q", protocol, reason)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// QueryResult contains the result of a DNS query
+type QueryResult struct {
+	Response *dns.Msg
+	Duration time.Duration
+	Err      error
+
+	// Steps holds the referrals followed by an iterative resolver, in
+	// order; it is empty for resolvers that send a single query
+	Steps []Step
+
+	// RemoteAddr is the address the final query was sent to, when known.
+	// For targets given by host name it tells which of their addresses
+	// answered.
+	RemoteAddr string
+
+	// FastOpen reports whether the server accepted the query sent in the
+	// SYN; it is nil unless TCP Fast Open was requested for a Do53 over
+	// TCP or DoT query that got a response
+	FastOpen *bool
+
+	// ALPN is the application protocol negotiated by a DoQ query
+	ALPN string
+
+	// AltSvc is the Alt-Svc header of a DoH response
+	AltSvc string
+
+	// Headers holds the captured response headers of a DoH or DoH3 query,
+	// keyed by canonical name
+	Headers map[string]string
+
+	// Conn describes the encrypted connection the query was sent on; it is
+	// nil for unencrypted queries and queries that failed to connect
+	Conn *ConnState
+}
+
+// ConnState tells how the connection of an encrypted query was set up
+type ConnState struct {
+	// Reused is true if the query was sent on an existing HTTP/2 or HTTP/3
+	// connection
+	Reused bool
+
+	// Resumed is true if a new connection resumed an earlier TLS session
+	// instead of a full handshake
+	Resumed bool
+}
+
+// Step is one exchange of an iterative resolution
+type Step struct {
+	// Zone is the zone whose name servers were asked
+	Zone string
+
+	// Server is the address that answered
+	Server string
+
+	// Duration includes failing over to other servers of the zone
+	Duration time.Duration
+}
+
+// Resolver is the interface that all DNS resolvers must implement
+type Resolver interface {
+	// Query performs a DNS query for the given hostname and record type
+	Query(ctx context.Context, hostname string, qtype uint16) QueryResult
+
+	// Exchange sends a prepared query message, e.g. one with EDNS options
+	// or flags set, and returns the response
+	Exchange(ctx context.Context, msg *dns.Msg) QueryResult
+
+	// Protocol returns the protocol identifier (e.g., "do53-udp", "dot", "doh")
+	Protocol() string
+
+	// Healthcheck reports whether the server answers queries at all
+	Healthcheck(ctx context.Context) error
+
+	// Capabilities describes the transport features the resolver uses
+	Capabilities() Capabilities
+
+	// Close releases any resources held by the resolver
+	Close() error
+}
+
+// ConnCounter is implemented by resolvers that keep track of the
+// connections and sockets they hold open
+type ConnCounter interface {
+	// OpenConns returns the number of connections and sockets open
+	OpenConns() int
+}
+
+// NewQuery builds a recursive query message for hostname and qtype
+func NewQuery(hostname string, qtype uint16) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(hostname), qtype)
+	return msg
+}
+
+// Capabilities describes the transport features of a resolver
+type Capabilities struct {
+	Encrypted       bool `json:"encrypted"`
+	ConnectionReuse bool `json:"connection_reuse"`
+	Padding         bool `json:"padding"`
+	ZeroRTT         bool `json:"zero_rtt"`
+}
+
+// healthcheck queries the root NS set, which any recursive resolver can
+// answer cheaply from cache
+func healthcheck(ctx context.Context, r Resolver) error {
+	return r.Query(ctx, ".", dns.TypeNS).Err
+}
+
+// Timeouts bounds the phases of a query. Connect limits establishing the
+// connection, including any TLS or QUIC handshake, and Query limits waiting
+// for the answer once connected. Total caps the query as a whole; a zero
+// Connect or Query falls back to Total.
+type Timeouts struct {
+	Total   time.Duration
+	Connect time.Duration
+	Query   time.Duration
+}
+
+// connect returns the connection establishment timeout
+func (t Timeouts) connect() time.Duration {
+	if t.Connect > 0 {
+		return t.Connect
+	}
+	return t.Total
+}
+
+// query returns the timeout for the answer on an established connection
+func (t Timeouts) query() time.Duration {
+	if t.Query > 0 {
+		return t.Query
+	}
+	return t.Total
+}
+
+// withTotal bounds ctx by the overall query timeout, if set
+func (t Timeouts) withTotal(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.Total > 0 {
+		return context.WithTimeout(ctx, t.Total)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestDo53ResolverProtocol(t *testing.T) {
+	t.Run("UDP protocol", func(t *testing.T) {
+		r := NewDo53Resolver(Options{Address: "8.8.8.8", Port: "53", Timeouts: Timeouts{Total: 2 * time.Second}}, false)
+		if r.Protocol() != "do53-udp" {
+			t.Errorf("Expected 'do53-udp', got '%s'", r.Protocol())
+		}
+	})
+
+	t.Run("TCP protocol", func(t *testing.T) {
+		r := NewDo53Resolver(Options{Address: "8.8.8.8", Port: "53", Timeouts: Timeouts{Total: 2 * time.Second}}, true)
+		if r.Protocol() != "do53-tcp" {
+			t.Errorf("Expected 'do53-tcp', got '%s'", r.Protocol())
+		}
+	})
+}
+
+func TestDoTResolverProtocol(t *testing.T) {
+	r := NewDoTResolver(Options{Address: "1.1.1.1", Port: "853", ServerName: "cloudflare-dns.com", Timeouts: Timeouts{Total: 2 * time.Second}})
+	if r.Protocol() != "dot" {
+		t.Errorf("Expected 'dot', got '%s'", r.Protocol())
+	}
+}
+
+func TestDoHResolverProtocol(t *testing.T) {
+	r := NewDoHResolver(Options{Address: "dns.google", Port: "443", ServerName: "dns.google", Timeouts: Timeouts{Total: 2 * time.Second}})
+	if r.Protocol() != "doh" {
+		t.Errorf("Expected 'doh', got '%s'", r.Protocol())
+	}
+}
+
+func TestDo53Query(t *testing.T) {
+	r := NewDo53Resolver(Options{Address: "8.8.8.8", Port: "53", Timeouts: Timeouts{Total: 5 * time.Second}}, false)
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	result := r.Query(ctx, "example.com", dns.TypeA)
+
+	if result.Duration < 0 {
+		t.Errorf("Expected non-negative duration, got %v", result.Duration)
+	}
+
+	if result.Err != nil {
+		t.Logf("DNS query failed (may be expected in some environments): %v", result.Err)
+		return
+	}
+
+	if result.Response == nil {
+		t.Error("Expected response, got nil")
+	}
+}
+
+func TestDo53QueryTimeout(t *testing.T) {
+	r := NewDo53Resolver(Options{Address: "192.0.2.1", Port: "53", Timeouts: Timeouts{Total: 100 * time.Millisecond}}, false)
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	result := r.Query(ctx, "example.com", dns.TypeA)
+
+	if result.Err == nil {
+		t.Error("Expected error for non-routable address")
+	}
+
+	if result.Duration < 0 {
+		t.Errorf("Expected non-negative duration, got %v", result.Duration)
+	}
+}
+
+func TestMissingNetns(t *testing.T) {
+	opts := Options{Address: "192.0.2.1", Timeouts: Timeouts{Total: time.Second}, Netns: "dnspulse-missing"}
+	for _, protocol := range []string{ProtocolDo53UDP, ProtocolDoT, ProtocolDoH, ProtocolDoQ} {
+		if _, ok := Unsupported(protocol); ok {
+			continue
+		}
+		r, err := New(protocol, opts)
+		if err != nil {
+			t.Fatalf("New(%s) failed: %v", protocol, err)
+		}
+		if result := r.Query(context.Background(), "example.com", dns.TypeA); result.Err == nil {
+			t.Errorf("Expected %s query through a missing namespace to fail", protocol)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestSourceAddress(t *testing.T) {
+	sources := make(chan string, 1)
+	addr := startServer(t, "127.0.0.1:0", func(w dns.ResponseWriter, req *dns.Msg) {
+		host, _, _ := net.SplitHostPort(w.RemoteAddr().String())
+		sources <- host
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		_ = w.WriteMsg(resp)
+	})
+	host, port, _ := net.SplitHostPort(addr)
+
+	r := NewDo53Resolver(Options{Address: host, Port: port, SourceAddress: "127.0.0.2", Timeouts: Timeouts{Total: time.Second}}, false)
+	defer func() { _ = r.Close() }()
+	if result := r.Query(context.Background(), "example.com", dns.TypeA); result.Err != nil {
+		t.Fatalf("Query failed: %v", result.Err)
+	}
+	if source := <-sources; source != "127.0.0.2" {
+		t.Errorf("Expected query from 127.0.0.2, got %s", source)
+	}
+}
+
+func TestReuseSocket(t *testing.T) {
+	sources := make(chan string, 3)
+	addr := startServer(t, "127.0.0.1:0", func(w dns.ResponseWriter, req *dns.Msg) {
+		sources <- w.RemoteAddr().String()
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		_ = w.WriteMsg(resp)
+	})
+	host, port, _ := net.SplitHostPort(addr)
+
+	r := NewDo53Resolver(Options{Address: host, Port: port, ReuseSocket: true, Timeouts: Timeouts{Total: time.Second}}, false)
+	defer func() { _ = r.Close() }()
+	for i := 0; i < 3; i++ {
+		if result := r.Query(context.Background(), "example.com", dns.TypeA); result.Err != nil {
+			t.Fatalf("Query %d failed: %v", i, result.Err)
+		}
+	}
+	first := <-sources
+	for i := 1; i < 3; i++ {
+		if source := <-sources; source != first {
+			t.Errorf("Expected every query from %s, got %s", first, source)
+		}
+	}
+	if !r.Capabilities().ConnectionReuse {
+		t.Error("Expected connection reuse capability")
+	}
+}
+
+func TestQueryCancellation(t *testing.T) {
+	addr := startServer(t, "127.0.0.1:0", func(w dns.ResponseWriter, req *dns.Msg) {})
+	host, port, _ := net.SplitHostPort(addr)
+
+	for _, reuse := range []bool{false, true} {
+		r := NewDo53Resolver(Options{Address: host, Port: port, ReuseSocket: reuse, Timeouts: Timeouts{Total: 10 * time.Second}}, false)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		result := r.Query(ctx, "example.com", dns.TypeA)
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("reuse=%v: expected context.Canceled, got %v", reuse, result.Err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("reuse=%v: query returned %v after cancellation", reuse, elapsed)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestResolverClose(t *testing.T) {
+	resolvers := []Resolver{
+		NewDo53Resolver(Options{Address: "8.8.8.8", Port: "53", Timeouts: Timeouts{Total: 2 * time.Second}}, false),
+		NewDo53Resolver(Options{Address: "8.8.8.8", Port: "53", Timeouts: Timeouts{Total: 2 * time.Second}}, true),
+		NewDoTResolver(Options{Address: "1.1.1.1", Port: "853", ServerName: "cloudflare-dns.com", Timeouts: Timeouts{Total: 2 * time.Second}}),
+		NewDoHResolver(Options{Address: "dns.google", Port: "443", ServerName: "dns.google", Timeouts: Timeouts{Total: 2 * time.Second}}),
+	}
+
+	for _, r := range resolvers {
+		if err := r.Close(); err != nil {
+			t.Errorf("Close() returned error for %s: %v", r.Protocol(), err)
+		}
+	}
+}
+
+func TestTimeoutsFallback(t *testing.T) {
+	timeouts := Timeouts{Total: 5 * time.Second}
+	if timeouts.connect() != 5*time.Second || timeouts.query() != 5*time.Second {
+		t.Errorf("Expected phases to fall back to total, got connect %s, query %s",
+			timeouts.connect(), timeouts.query())
+	}
+
+	timeouts = Timeouts{Total: 5 * time.Second, Connect: time.Second, Query: 3 * time.Second}
+	if timeouts.connect() != time.Second || timeouts.query() != 3*time.Second {
+		t.Errorf("Expected explicit phase timeouts, got connect %s, query %s",
+			timeouts.connect(), timeouts.query())
+	}
+}
+
+func TestHTTP3Port(t *testing.T) {
+	tests := []struct {
+		altSvc string
+		port   string
+		ok     bool
+	}{
+		{`h3=":443"; ma=86400`, "443", true},
+		{`h3-29=":443", h3=":8443"; ma=3600`, "8443", true},
+		{`h2="alt.example:443", h3="alt.example:443"`, "443", true},
+		{`h3-29=":443"`, "", false},
+		{`clear`, "", false},
+		{``, "", false},
+	}
+	for _, tt := range tests {
+		port, ok := HTTP3Port(tt.altSvc)
+		if port != tt.port || ok != tt.ok {
+			t.Errorf("HTTP3Port(%q) = %q, %v; expected %q, %v", tt.altSvc, port, ok, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestCaptureHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Server", "cloudflare")
+	header.Set("Cf-Ray", "8f1e2d3c4b5a6978-FRA")
+
+	captured := captureHeaders(header, []string{"server", "CF-Ray", "X-Provided-By"})
+	want := map[string]string{"Server": "cloudflare", "Cf-Ray": "8f1e2d3c4b5a6978-FRA"}
+	if len(captured) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, captured)
+	}
+	for name, value := range want {
+		if captured[name] != value {
+			t.Errorf("Expected %s: %s, got %q", name, value, captured[name])
+		}
+	}
+	if captured := captureHeaders(header, nil); captured != nil {
+		t.Errorf("Expected nothing captured without names, got %v", captured)
+	}
+}
+
+// dohHandler answers DoH queries with empty responses, storing the
+// request headers in header if not nil
+func dohHandler(header *http.Header) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if header != nil {
+			*header = req.Header.Clone()
+		}
+		msg := new(dns.Msg)
+		body, _ := io.ReadAll(req.Body)
+		if err := msg.Unpack(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		wire, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Header().Set("Server", "test-edge")
+		_, _ = w.Write(wire)
+	}
+}
+
+// newDoHServer starts an HTTP/2 DoH server answering every query with an
+// empty response, and returns its address. The request headers of the
+// latest query are stored in header if it is not nil.
+func newDoHServer(t *testing.T, header *http.Header) (host, port string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(dohHandler(header))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	host, port, _ = net.SplitHostPort(srv.Listener.Addr().String())
+	return host, port
+}
+
+func TestDoHHeaders(t *testing.T) {
+	var got http.Header
+	host, port := newDoHServer(t, &got)
+	r := NewDoHResolver(Options{
+		Address:            host,
+		Port:               port,
+		InsecureSkipVerify: true,
+		Timeouts:           Timeouts{Total: 5 * time.Second},
+		Headers:            map[string]string{"Authorization": "Bearer s3cret"},
+		UserAgent:          "dnspulse-test",
+		CaptureHeaders:     []string{"server"},
+	})
+	defer func() { _ = r.Close() }()
+
+	result := r.Query(context.Background(), "example.com", dns.TypeA)
+	if result.Err != nil {
+		t.Fatalf("Query failed: %v", result.Err)
+	}
+	if got.Get("Authorization") != "Bearer s3cret" || got.Get("User-Agent") != "dnspulse-test" {
+		t.Errorf("Expected custom headers, got %v", got)
+	}
+	if got.Get("Content-Type") != "application/dns-message" {
+		t.Errorf("Expected DoH content type, got %q", got.Get("Content-Type"))
+	}
+	if result.Headers["Server"] != "test-edge" {
+		t.Errorf("Expected captured Server header, got %v", result.Headers)
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	for _, tt := range []struct {
+		min  uint16
+		fail bool
+	}{{tls.VersionTLS12, false}, {tls.VersionTLS13, true}} {
+		r := NewDoHResolver(Options{
+			Address:            host,
+			Port:               port,
+			InsecureSkipVerify: true,
+			TLSMinVersion:      tt.min,
+			Timeouts:           Timeouts{Total: 5 * time.Second},
+		})
+		result := r.Query(context.Background(), "example.com", dns.TypeA)
+		_ = r.Close()
+
+		// The server answers every query with an HTTP error, so only a
+		// failed handshake leaves the request without a status
+		failed := result.Err != nil && !strings.Contains(result.Err.Error(), "HTTP status")
+		if failed != tt.fail {
+			t.Errorf("Minimum version %#x: expected handshake failure %v, got %v", tt.min, tt.fail, result.Err)
+		}
+	}
+}
+
+func TestDoHConnState(t *testing.T) {
+	host, port := newDoHServer(t, nil)
+	r := NewDoHResolver(Options{
+		Address:            host,
+		Port:               port,
+		InsecureSkipVerify: true,
+		ResumeSessions:     true,
+		Timeouts:           Timeouts{Total: 5 * time.Second},
+	})
+	defer func() { _ = r.Close() }()
+
+	query := func() *ConnState {
+		t.Helper()
+		result := r.Query(context.Background(), "example.com", dns.TypeA)
+		if result.Err != nil {
+			t.Fatalf("Query failed: %v", result.Err)
+		}
+		return result.Conn
+	}
+
+	if state := query(); state == nil || state.Reused || state.Resumed {
+		t.Errorf("Expected a new connection with a full handshake, got %+v", state)
+	}
+	if state := query(); state == nil || !state.Reused {
+		t.Errorf("Expected the connection to be reused, got %+v", state)
+	}
+	_ = r.Close()
+	if state := query(); state == nil || state.Reused || !state.Resumed {
+		t.Errorf("Expected a new connection resuming the session, got %+v", state)
+	}
+}
+
+func TestOpenConns(t *testing.T) {
+	addr := startServer(t, "127.0.0.1:0", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		_ = w.WriteMsg(resp)
+	})
+	udpHost, udpPort, _ := net.SplitHostPort(addr)
+	dohHost, dohPort := newDoHServer(t, nil)
+
+	for _, tt := range []struct {
+		name string
+		r    interface {
+			Resolver
+			ConnCounter
+		}
+		open int
+	}{
+		{"do53-udp", NewDo53Resolver(Options{Address: udpHost, Port: udpPort, Timeouts: Timeouts{Total: time.Second}}, false), 0},
+		{"reused", NewDo53Resolver(Options{Address: udpHost, Port: udpPort, ReuseSocket: true, Timeouts: Timeouts{Total: time.Second}}, false), 1},
+		{"doh", NewDoHResolver(Options{Address: dohHost, Port: dohPort, InsecureSkipVerify: true, Timeouts: Timeouts{Total: 5 * time.Second}}), 1},
+	} {
+		if result := tt.r.Query(context.Background(), "example.com", dns.TypeA); result.Err != nil {
+			t.Fatalf("%s: query failed: %v", tt.name, result.Err)
+		}
+		if got := tt.r.OpenConns(); got != tt.open {
+			t.Errorf("%s: expected %d open connections after a query, got %d", tt.name, tt.open, got)
+		}
+		_ = tt.r.Close()
+		if got := tt.r.OpenConns(); got != 0 {
+			t.Errorf("%s: expected no open connections after Close, got %d", tt.name, got)
+		}
+	}
+}
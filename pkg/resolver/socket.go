@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// socket describes where a resolver's sockets are created: in a network
+// namespace, bound to a VRF device and to a source address, or in the
+// exporter's own network stack with a system-chosen address if all are
+// empty. TCP sockets are tuned with the TCP options. Namespaces, VRFs and
+// TCP Fast Open are only supported on Linux.
+type socket struct {
+	netns  string
+	vrf    string
+	source net.IP
+	tcp    TCPOptions
+
+	// open counts the connections and sockets created that are not yet
+	// closed, and is shared by the copies of a resolver's socket
+	open *atomic.Int64
+}
+
+// opened counts a new connection or socket as open
+func (s socket) opened() {
+	if s.open != nil {
+		s.open.Add(1)
+	}
+}
+
+// closed counts a connection or socket counted by opened as closed
+func (s socket) closed() {
+	if s.open != nil {
+		s.open.Add(-1)
+	}
+}
+
+// openConns returns the number of connections and sockets open
+func (s socket) openConns() int {
+	if s.open == nil {
+		return 0
+	}
+	return int(s.open.Load())
+}
+
+// track counts conn as open until it is first closed
+func (s socket) track(conn net.Conn) net.Conn {
+	s.opened()
+	return &trackedConn{Conn: conn, socket: s}
+}
+
+// trackedConn is a connection counted as open until it is first closed
+type trackedConn struct {
+	net.Conn
+	socket socket
+	once   sync.Once
+}
+
+// Close closes the connection
+func (c *trackedConn) Close() error {
+	c.once.Do(c.socket.closed)
+	return c.Conn.Close()
+}
+
+// isDefault returns true if sockets are created as usual
+func (s socket) isDefault() bool {
+	return s.netns == "" && s.vrf == "" && s.source == nil && s.tcp == TCPOptions{}
+}
+
+// dialer returns a dialer for network binding its sockets to the VRF and
+// source address, if any, and applying the TCP options that must be set
+// before connecting
+func (s socket) dialer(network string, timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout}
+	isTCP := strings.HasPrefix(network, "tcp")
+	d.Control = func(network, address string, c syscall.RawConn) error {
+		if s.vrf != "" {
+			if err := bindToDevice(s.vrf)(network, address, c); err != nil {
+				return err
+			}
+		}
+		if isTCP && s.tcp.FastOpen {
+			return setFastOpen(c)
+		}
+		return nil
+	}
+	if isTCP && (s.tcp.KeepAlive != 0 || s.tcp.KeepAliveInterval != 0) {
+		d.KeepAliveConfig = net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     s.tcp.KeepAlive,
+			Interval: s.tcp.KeepAliveInterval,
+			Count:    -1,
+		}
+	}
+	if s.netns != "" {
+		// Dial and resolve on the calling goroutine's thread in the
+		// namespace, not racing address families on other threads
+		d.FallbackDelay = -1
+		d.Resolver = s.resolver()
+	}
+	if s.source != nil {
+		if strings.HasPrefix(network, "udp") {
+			d.LocalAddr = &net.UDPAddr{IP: s.source}
+		} else {
+			d.LocalAddr = &net.TCPAddr{IP: s.source}
+		}
+	}
+	return d
+}
+
+// resolver returns a resolver for server names that sends its queries from
+// the namespace, or nil for the default resolver outside of namespaces
+func (s socket) resolver() *net.Resolver {
+	if s.netns == "" {
+		return nil
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (conn net.Conn, err error) {
+			err = inNetns(s.netns, func() error {
+				var d net.Dialer
+				conn, err = d.DialContext(ctx, network, address)
+				return err
+			})
+			return conn, err
+		},
+	}
+}
+
+// resolveUDP resolves addr, a host and port, to a UDP address from the
+// namespace
+func (s socket) resolveUDP(ctx context.Context, addr string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	r := s.resolver()
+	portNum, err := r.LookupPort(ctx, "udp", port)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	// Prefer IPv4 like net.ResolveUDPAddr
+	ip := ips[0].Unmap()
+	if i := slices.IndexFunc(ips, func(ip netip.Addr) bool { return ip.Unmap().Is4() }); i >= 0 {
+		ip = ips[i].Unmap()
+	}
+	return net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(portNum))), nil
+}
+
+// dialContext connects to addr from the namespace, VRF and source address
+func (s socket) dialContext(ctx context.Context, network, addr string, timeout time.Duration) (net.Conn, error) {
+	var conn net.Conn
+	err := inNetns(s.netns, func() (err error) {
+		conn, err = s.dialer(network, timeout).DialContext(ctx, network, addr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.tune(conn)
+	return s.track(conn), nil
+}
+
+// tune applies the TCP options that can only be set once connected, as
+// the dialer enables TCP_NODELAY on every connection
+func (s socket) tune(conn net.Conn) {
+	if s.tcp.NoDelay == nil {
+		return
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetNoDelay(*s.tcp.NoDelay)
+	}
+}
+
+// fastOpen reports whether the server accepted the data sent in the SYN of
+// conn, or nil if TCP Fast Open was not requested
+func (s socket) fastOpen(conn net.Conn) *bool {
+	if !s.tcp.FastOpen {
+		return nil
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	accepted := fastOpenAccepted(tcpConn)
+	return &accepted
+}
+
+// listenPacket opens an unconnected UDP socket in the namespace and VRF,
+// bound to the source address
+func (s socket) listenPacket(ctx context.Context) (net.PacketConn, error) {
+	lc := net.ListenConfig{}
+	if s.vrf != "" {
+		lc.Control = bindToDevice(s.vrf)
+	}
+	local := ":0"
+	if s.source != nil {
+		local = net.JoinHostPort(s.source.String(), "0")
+	}
+	var pc net.PacketConn
+	err := inNetns(s.netns, func() (err error) {
+		pc, err = lc.ListenPacket(ctx, "udp", local)
+		return err
+	})
+	return pc, err
+}
+
+// exchange sends msg to addr using client and returns the response, the
+// address connected to and whether TCP Fast Open was accepted; the caller
+// sets the duration. The client's dialer must come from dialer, which
+// covers the VRF, source address and options set before connecting; the
+// connection is dialed here for the namespace and for the options set
+// afterwards.
+func (s socket) exchange(ctx context.Context, client *dns.Client, msg *dns.Msg, addr string) QueryResult {
+	var conn *dns.Conn
+	err := inNetns(s.netns, func() (err error) {
+		conn, err = client.DialContext(ctx, addr)
+		return err
+	})
+	if err != nil {
+		return QueryResult{Err: err}
+	}
+	s.opened()
+	defer func() {
+		_ = conn.Close()
+		s.closed()
+	}()
+	s.tune(conn.Conn)
+	remote := conn.RemoteAddr().String()
+	state := tlsConnState(conn.Conn)
+	resp, err := exchangeConn(ctx, client, msg, conn)
+	if err != nil {
+		return QueryResult{Err: err, RemoteAddr: remote, Conn: state}
+	}
+	return QueryResult{Response: resp, RemoteAddr: remote, FastOpen: s.fastOpen(conn.Conn), Conn: state}
+}
+
+// tlsConnState describes a new TLS connection, or returns nil if conn is
+// not encrypted
+func tlsConnState(conn net.Conn) *ConnState {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	return &ConnState{Resumed: tlsConn.ConnectionState().DidResume}
+}
+
+// exchangeConn sends msg over conn. The client only applies the deadline of
+// ctx, so the connection is closed to interrupt the exchange when ctx is
+// cancelled; ctx's error is returned in that case.
+func exchangeConn(ctx context.Context, client *dns.Client, msg *dns.Msg, conn *dns.Conn) (*dns.Msg, error) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	resp, _, err := client.ExchangeWithConnContext(ctx, msg, conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return resp, err
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// platformFeatures are the socket options only supported on Linux
+var platformFeatures = []string{"netns", "tcp_fast_open", "vrf"}
+
+// netnsDir holds the namespaces named by ip-netns(8)
+const netnsDir = "/var/run/netns"
+
+// tcpiOptSynData is set in tcpi_options when the server acknowledged the
+// data sent in the SYN
+const tcpiOptSynData = 0x20
+
+// setFastOpen enables TCP Fast Open on a socket before it connects, so
+// that the first write is sent in the SYN
+func setFastOpen(c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+	}); err != nil {
+		return err
+	}
+	if sockErr != nil {
+		return fmt.Errorf("failed to enable TCP Fast Open: %w", sockErr)
+	}
+	return nil
+}
+
+// fastOpenAccepted returns true if the server acknowledged the data sent
+// in the SYN of conn
+func fastOpenAccepted(conn *net.TCPConn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var info *unix.TCPInfo
+	if err := raw.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil || info == nil {
+		return false
+	}
+	return info.Options&tcpiOptSynData != 0
+}
+
+// bindToDevice returns a socket control function binding sockets to the
+// VRF device with SO_BINDTODEVICE
+func bindToDevice(vrf string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = unix.BindToDevice(int(fd), vrf)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("failed to bind to VRF %s: %w", vrf, sockErr)
+		}
+		return nil
+	}
+}
+
+// inNetns runs fn on an OS thread switched to the network namespace, so
+// that the sockets fn creates belong to it. The namespace is a name
+// created by ip-netns(8) or the path of a namespace file. The thread is
+// discarded afterwards instead of being switched back. Sockets keep their
+// namespace for their lifetime.
+func inNetns(netns string, fn func() error) error {
+	if netns == "" {
+		return fn()
+	}
+	path := netns
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(netnsDir, netns)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		// Exiting without UnlockOSThread terminates the thread
+		runtime.LockOSThread()
+
+		f, err := os.Open(path)
+		if err != nil {
+			errc <- fmt.Errorf("failed to open network namespace: %w", err)
+			return
+		}
+		defer f.Close()
+		if err := unix.Setns(int(f.Fd()), unix.CLONE_NEWNET); err != nil {
+			errc <- fmt.Errorf("failed to enter network namespace %s: %w", netns, err)
+			return
+		}
+		errc <- fn()
+	}()
+	return <-errc
+}
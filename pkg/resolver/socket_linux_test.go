@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/sys/unix"
+)
+
+// newTestNetns creates a network namespace with loopback up on a thread
+// kept for the test, and runs listen on that thread so that its sockets
+// belong to the namespace. It returns the path of the namespace.
+func newTestNetns(t *testing.T, listen func() error) string {
+	t.Helper()
+	type result struct {
+		path string
+		err  error
+	}
+	ready := make(chan result)
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		// Exiting without UnlockOSThread terminates the thread and so
+		// the namespace
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			ready <- result{err: err}
+			return
+		}
+		fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+		if err != nil {
+			ready <- result{err: err}
+			return
+		}
+		defer unix.Close(fd)
+		ifr, _ := unix.NewIfreq("lo")
+		ifr.SetUint16(unix.IFF_UP | unix.IFF_LOOPBACK | unix.IFF_RUNNING)
+		if err := unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr); err != nil {
+			ready <- result{err: err}
+			return
+		}
+		if err := listen(); err != nil {
+			ready <- result{err: err}
+			return
+		}
+		ready <- result{path: fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())}
+		<-done
+	}()
+	res := <-ready
+	if res.err != nil {
+		t.Skipf("Cannot create a network namespace: %v", res.err)
+	}
+	return res.path
+}
+
+func TestNetnsDoHHostname(t *testing.T) {
+	srv := httptest.NewUnstartedServer(dohHandler(nil))
+	netns := newTestNetns(t, func() (err error) {
+		srv.Listener, err = net.Listen("tcp", "127.0.0.1:0")
+		return err
+	})
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The server is only reachable inside the namespace, under a name
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	r := NewDoHResolver(Options{
+		Address:            "localhost",
+		Port:               port,
+		Netns:              netns,
+		InsecureSkipVerify: true,
+		Timeouts:           Timeouts{Total: 5 * time.Second},
+	})
+	defer func() { _ = r.Close() }()
+	if result := r.Query(context.Background(), "example.com", dns.TypeA); result.Err != nil {
+		t.Fatalf("Expected a DoH query by name inside the namespace to succeed, got %v", result.Err)
+	}
+}
+
+func TestNetnsResolver(t *testing.T) {
+	var conn net.PacketConn
+	netns := newTestNetns(t, func() (err error) {
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		return err
+	})
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		_ = w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	// Name server queries are sent from the namespace, where the server
+	// listens
+	s := socket{netns: netns}
+	c, err := s.resolver().Dial(context.Background(), "udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+	_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+	client := &dns.Client{}
+	if _, _, err := client.ExchangeWithConn(new(dns.Msg).SetQuestion("example.com.", dns.TypeA), &dns.Conn{Conn: c}); err != nil {
+		t.Errorf("Expected the name server inside the namespace to answer, got %v", err)
+	}
+
+	if d := s.dialer("tcp", time.Second); d.FallbackDelay >= 0 || d.Resolver == nil {
+		t.Errorf("Expected dialers in a namespace to resolve and dial on their own thread, got %+v", d)
+	}
+}
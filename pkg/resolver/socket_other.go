@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: BSD-2-Clause
+// Copyright (c) 2026 Babak Farrokhi
+
+//go:build !linux
+
+package resolver
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// platformFeatures is empty, as the optional socket options are only
+// supported on Linux
+var platformFeatures []string
+
+// bindToDevice fails every socket, as VRFs are only supported on Linux
+func bindToDevice(vrf string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return errors.New("VRFs are only supported on Linux")
+	}
+}
+
+// setFastOpen fails every socket, as TCP Fast Open is only supported on
+// Linux
+func setFastOpen(c syscall.RawConn) error {
+	return errors.New("TCP Fast Open is only supported on Linux")
+}
+
+// fastOpenAccepted always returns false
+func fastOpenAccepted(conn *net.TCPConn) bool {
+	return false
+}
+
+// inNetns fails unless netns is empty, as network namespaces are only
+// supported on Linux
+func inNetns(netns string, fn func() error) error {
+	if netns != "" {
+		return errors.New("network namespaces are only supported on Linux")
+	}
+	return fn()
+}